@@ -0,0 +1,600 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/internal/container/set"
+	"oras.land/oras-go/v2/internal/descriptor"
+	"oras.land/oras-go/v2/internal/docker"
+	"oras.land/oras-go/v2/internal/graph"
+	"oras.land/oras-go/v2/internal/resolver"
+)
+
+const (
+	// dockerArchiveManifestFile is the file listing the images in a docker
+	// archive.
+	dockerArchiveManifestFile = "manifest.json"
+	// dockerArchiveRepositoriesFile is the legacy file mapping the tags to the
+	// top layers of the images in a docker archive.
+	dockerArchiveRepositoriesFile = "repositories"
+)
+
+// dockerArchiveEntry is an image listed in the `manifest.json` file of a
+// docker archive.
+type dockerArchiveEntry struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+// magic numbers of the compressed layers.
+var (
+	gzipMagic = []byte{0x1f, 0x8b, 0x08}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// DockerArchiveStore implements `oras.ReadOnlyGraphTarget`, and represents a
+// read-only content store based on a docker archive, which is produced by
+// `docker save` and consumed by `docker load`.
+//
+// As docker archives do not preserve the image manifests, a Docker image
+// manifest (schema 2) is synthesized for each image listed in the
+// `manifest.json` file of the archive. The images are tagged with their
+// `RepoTags` (e.g. "example.com/foo:latest").
+//
+// Reference: https://github.com/moby/moby/blob/v25.0.0/image/spec/v1.2.md
+type DockerArchiveStore struct {
+	fsys        fs.FS
+	blobs       map[digest.Digest]dockerArchiveBlob
+	manifests   map[digest.Digest][]byte
+	tagResolver *resolver.Memory
+	graph       *graph.Memory
+	// closer releases the file system created by the store.
+	closer io.Closer
+}
+
+// dockerArchiveBlob records a config or layer blob in a docker archive.
+type dockerArchiveBlob struct {
+	desc ocispec.Descriptor
+	path string
+}
+
+// NewFromDockerArchive creates a new read-only store from a docker archive
+// located at path. Gzip compressed archives are extracted to a temporary file
+// of at most 32 GiB.
+//
+// The caller must Close the returned store to release the temporary file.
+func NewFromDockerArchive(ctx context.Context, path string) (*DockerArchiveStore, error) {
+	return NewFromDockerArchiveWithOptions(ctx, path, TarOptions{})
+}
+
+// NewFromDockerArchiveWithOptions creates a new read-only store from a docker
+// archive located at path, which is optionally compressed with gzip or zstd.
+//
+// The caller must Close the returned store to release the temporary
+// extraction cache of a compressed archive.
+func NewFromDockerArchiveWithOptions(ctx context.Context, path string, opts TarOptions) (*DockerArchiveStore, error) {
+	tfs, err := newTarFS(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	store, err := newFromDockerArchiveFS(ctx, tfs)
+	if err != nil {
+		tfs.Close()
+		return nil, err
+	}
+	store.closer = tfs
+	return store, nil
+}
+
+// newFromDockerArchiveFS creates a new read-only store from the content of a
+// docker archive in fsys.
+func newFromDockerArchiveFS(ctx context.Context, fsys fs.FS) (*DockerArchiveStore, error) {
+	store := &DockerArchiveStore{
+		fsys:        fsys,
+		blobs:       make(map[digest.Digest]dockerArchiveBlob),
+		manifests:   make(map[digest.Digest][]byte),
+		tagResolver: resolver.NewMemory(),
+		graph:       graph.NewMemory(),
+	}
+	if err := store.loadManifestFile(ctx); err != nil {
+		return nil, fmt.Errorf("invalid docker archive: %w", err)
+	}
+	return store, nil
+}
+
+// Close releases the resources held by the store, such as the temporary
+// extraction cache of a compressed archive.
+func (s *DockerArchiveStore) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// Fetch fetches the content identified by the descriptor.
+func (s *DockerArchiveStore) Fetch(_ context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	if manifest, ok := s.manifests[target.Digest]; ok {
+		return io.NopCloser(bytes.NewReader(manifest)), nil
+	}
+	blob, ok := s.blobs[target.Digest]
+	if !ok {
+		return nil, fmt.Errorf("%s: %s: %w", target.Digest, target.MediaType, errdef.ErrNotFound)
+	}
+	return s.fsys.Open(blob.path)
+}
+
+// Exists returns true if the described content exists.
+func (s *DockerArchiveStore) Exists(_ context.Context, target ocispec.Descriptor) (bool, error) {
+	if _, ok := s.manifests[target.Digest]; ok {
+		return true, nil
+	}
+	_, ok := s.blobs[target.Digest]
+	return ok, nil
+}
+
+// Resolve resolves a reference to a descriptor.
+//   - If the reference to be resolved is a tag, the returned descriptor will be
+//     a full descriptor declared by github.com/opencontainers/image-spec/specs-go/v1.
+//   - If the reference is a digest, the returned descriptor will be a
+//     plain descriptor (containing only the digest, media type and size).
+func (s *DockerArchiveStore) Resolve(ctx context.Context, reference string) (ocispec.Descriptor, error) {
+	if reference == "" {
+		return ocispec.Descriptor{}, errdef.ErrMissingReference
+	}
+
+	// attempt resolving manifest
+	desc, err := s.tagResolver.Resolve(ctx, reference)
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			// attempt resolving blob
+			if blob, ok := s.blobs[digest.Digest(reference)]; ok {
+				return blob.desc, nil
+			}
+		}
+		return ocispec.Descriptor{}, err
+	}
+
+	if reference == desc.Digest.String() {
+		return descriptor.Plain(desc), nil
+	}
+
+	return desc, nil
+}
+
+// Predecessors returns the nodes directly pointing to the current node.
+// Predecessors returns nil without error if the node does not exists in the
+// store.
+func (s *DockerArchiveStore) Predecessors(ctx context.Context, node ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	return s.graph.Predecessors(ctx, node)
+}
+
+// Tags lists the `RepoTags` presented in the `manifest.json` file of the
+// docker archive, returned in ascending order.
+// If `last` is NOT empty, the entries in the response start after the tag
+// specified by `last`. Otherwise, the response starts from the top of the tags
+// list.
+//
+// See also `Tags()` in the package `registry`.
+func (s *DockerArchiveStore) Tags(ctx context.Context, last string, fn func(tags []string) error) error {
+	return listTags(s.tagResolver, last, fn)
+}
+
+// loadManifestFile reads `manifest.json` from s.fsys, and synthesizes the
+// image manifests.
+func (s *DockerArchiveStore) loadManifestFile(ctx context.Context) error {
+	manifestFile, err := s.fsys.Open(dockerArchiveManifestFile)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest file: %w", err)
+	}
+	defer manifestFile.Close()
+
+	var entries []dockerArchiveEntry
+	if err := json.NewDecoder(manifestFile).Decode(&entries); err != nil {
+		return fmt.Errorf("failed to decode manifest file: %w", err)
+	}
+	for _, entry := range entries {
+		if err := isContextDone(ctx); err != nil {
+			return err
+		}
+		if err := s.loadEntry(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadEntry synthesizes the image manifest of entry, and tags it with the
+// `RepoTags` of entry.
+func (s *DockerArchiveStore) loadEntry(ctx context.Context, entry dockerArchiveEntry) error {
+	configPath := path.Clean(entry.Config)
+	configJSON, err := fs.ReadFile(s.fsys, configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", entry.Config, err)
+	}
+	var config ocispec.Image
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return fmt.Errorf("failed to decode config %s: %w", entry.Config, err)
+	}
+	manifest := ocispec.Manifest{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2, // historical value
+		},
+		MediaType: docker.MediaTypeManifest,
+		Config: ocispec.Descriptor{
+			MediaType: docker.MediaTypeConfig,
+			Digest:    digest.FromBytes(configJSON),
+			Size:      int64(len(configJSON)),
+		},
+		Layers: make([]ocispec.Descriptor, 0, len(entry.Layers)),
+	}
+	s.blobs[manifest.Config.Digest] = dockerArchiveBlob{
+		desc: manifest.Config,
+		path: configPath,
+	}
+
+	for i, layer := range entry.Layers {
+		var diffID digest.Digest
+		if i < len(config.RootFS.DiffIDs) {
+			diffID = config.RootFS.DiffIDs[i]
+		}
+		layerPath := path.Clean(layer)
+		desc, err := s.layerDescriptor(layerPath, diffID)
+		if err != nil {
+			return fmt.Errorf("failed to load layer %s: %w", layer, err)
+		}
+		s.blobs[desc.Digest] = dockerArchiveBlob{
+			desc: desc,
+			path: layerPath,
+		}
+		manifest.Layers = append(manifest.Layers, desc)
+	}
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	desc := ocispec.Descriptor{
+		MediaType: docker.MediaTypeManifest,
+		Digest:    digest.FromBytes(manifestJSON),
+		Size:      int64(len(manifestJSON)),
+	}
+	s.manifests[desc.Digest] = manifestJSON
+	if err := s.tagResolver.Tag(ctx, desc, desc.Digest.String()); err != nil {
+		return err
+	}
+	for _, repoTag := range entry.RepoTags {
+		if err := s.tagResolver.Tag(ctx, desc, repoTag); err != nil {
+			return err
+		}
+	}
+	return s.graph.IndexAll(ctx, s, desc)
+}
+
+// layerDescriptor returns the descriptor of the layer located at layerPath.
+// The digest of an uncompressed layer is its diff ID if known. Otherwise, the
+// layer is read to compute its digest.
+func (s *DockerArchiveStore) layerDescriptor(layerPath string, diffID digest.Digest) (ocispec.Descriptor, error) {
+	fp, err := s.fsys.Open(layerPath)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer fp.Close()
+	fi, err := fp.Stat()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType: docker.MediaTypeUncompressedLayer,
+		Size:      fi.Size(),
+	}
+	magic := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(fp, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return ocispec.Descriptor{}, err
+	}
+	magic = magic[:n]
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		desc.MediaType = docker.MediaTypeLayer
+	case bytes.HasPrefix(magic, zstdMagic):
+		desc.MediaType = ocispec.MediaTypeImageLayerZstd
+	case diffID.Validate() == nil:
+		desc.Digest = diffID
+		return desc, nil
+	}
+
+	digester := digest.Canonical.Digester()
+	if _, err := digester.Hash().Write(magic); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if _, err := io.Copy(digester.Hash(), fp); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc.Digest = digester.Digest()
+	return desc, nil
+}
+
+// DockerArchiveWriter implements `oras.Target`, and writes a docker archive
+// incrementally, which can be loaded by `docker load`.
+//
+// DockerArchiveWriter is based on TarWriter, and thus the archive written is
+// also an OCI image layout archive, which can be read by NewFromTar. Only
+// Docker image manifests (schema 2) and OCI image manifests can be tagged, and
+// the references are expected to be in the form of `RepoTags`
+// (e.g. "example.com/foo:latest"). The tagged images are listed in the
+// `manifest.json` file written on Close, along with the legacy `repositories`
+// file read by older versions of `docker load`.
+//
+// Reference: https://github.com/moby/moby/blob/v25.0.0/image/spec/v1.2.md
+type DockerArchiveWriter struct {
+	tw *TarWriter
+
+	// lock guards repoTags.
+	lock     sync.Mutex
+	repoTags map[digest.Digest]set.Set[string]
+}
+
+// NewDockerArchiveWriter creates a DockerArchiveWriter writing a docker
+// archive to w, applying the compression in opts. Export options other than
+// the compression are ignored. The caller is responsible for closing w after
+// closing the returned DockerArchiveWriter.
+func NewDockerArchiveWriter(w io.Writer, opts ExportOptions) (*DockerArchiveWriter, error) {
+	tw, err := NewTarWriter(w, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &DockerArchiveWriter{
+		tw:       tw,
+		repoTags: make(map[digest.Digest]set.Set[string]),
+	}, nil
+}
+
+// Push appends the content, matching the expected descriptor, to the archive.
+func (w *DockerArchiveWriter) Push(ctx context.Context, expected ocispec.Descriptor, reader io.Reader) error {
+	return w.tw.Push(ctx, expected, reader)
+}
+
+// Fetch fetches the content identified by the descriptor.
+// Blobs other than manifests can be fetched only if the archive is
+// uncompressed and the underlying writer implements io.ReaderAt.
+func (w *DockerArchiveWriter) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	return w.tw.Fetch(ctx, target)
+}
+
+// Exists returns true if the described content has been pushed.
+func (w *DockerArchiveWriter) Exists(ctx context.Context, target ocispec.Descriptor) (bool, error) {
+	return w.tw.Exists(ctx, target)
+}
+
+// Resolve resolves a reference to a descriptor.
+func (w *DockerArchiveWriter) Resolve(ctx context.Context, reference string) (ocispec.Descriptor, error) {
+	return w.tw.Resolve(ctx, reference)
+}
+
+// Tag associates a reference string (e.g. "example.com/foo:latest") with the
+// image manifest described by desc, which will be listed in both the
+// `manifest.json` and the `index.json` files written on Close.
+// Tagging a descriptor by its digest lists the image without `RepoTags`.
+//
+// Tag returns errdef.ErrUnsupported if desc is not an image manifest, or if
+// the config of the manifest is not an image config, such as the manifests of
+// artifacts, which cannot be loaded by `docker load`.
+func (w *DockerArchiveWriter) Tag(ctx context.Context, desc ocispec.Descriptor, reference string) error {
+	switch desc.MediaType {
+	case docker.MediaTypeManifest, ocispec.MediaTypeImageManifest:
+	default:
+		return fmt.Errorf("%s: %s: %w", desc.Digest, desc.MediaType, errdef.ErrUnsupported)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.checkImageConfig(desc); err != nil {
+		return err
+	}
+	if err := w.tw.Tag(ctx, desc, reference); err != nil {
+		return err
+	}
+	// the reference may be moved from another image
+	for dgst, repoTags := range w.repoTags {
+		repoTags.Delete(reference)
+		if len(repoTags) == 0 && !w.taggedByDigest(dgst) {
+			delete(w.repoTags, dgst)
+		}
+	}
+	repoTags, ok := w.repoTags[desc.Digest]
+	if !ok {
+		repoTags = set.New[string]()
+		w.repoTags[desc.Digest] = repoTags
+	}
+	if reference != desc.Digest.String() {
+		repoTags.Add(reference)
+	}
+	return nil
+}
+
+// checkImageConfig checks if the config of the image manifest described by
+// desc is an image config. The manifest must have been written to the archive.
+func (w *DockerArchiveWriter) checkImageConfig(desc ocispec.Descriptor) error {
+	t := w.tw
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	manifestJSON, ok := t.manifests[desc.Digest]
+	if !ok {
+		return fmt.Errorf("%s: %s: %w", desc.Digest, desc.MediaType, errdef.ErrNotFound)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return fmt.Errorf("failed to decode manifest %s: %w", desc.Digest, err)
+	}
+	switch manifest.Config.MediaType {
+	case docker.MediaTypeConfig, ocispec.MediaTypeImageConfig:
+		return nil
+	default:
+		return fmt.Errorf("%s: %s: %w", manifest.Config.Digest, manifest.Config.MediaType, errdef.ErrUnsupported)
+	}
+}
+
+// taggedByDigest returns true if the image manifest identified by dgst is
+// tagged by its digest.
+func (w *DockerArchiveWriter) taggedByDigest(dgst digest.Digest) bool {
+	w.tw.lock.Lock()
+	defer w.tw.lock.Unlock()
+	_, ok := w.tw.tags[dgst.String()]
+	return ok
+}
+
+// Close writes the `manifest.json`, the `repositories` and the `index.json`
+// files, and completes the archive. Close does not close the underlying writer.
+// The images are listed in `manifest.json` in the order of their manifest
+// digests.
+func (w *DockerArchiveWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	entries, err := w.manifestEntries()
+	if err != nil {
+		return err
+	}
+	manifestJSON, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest file: %w", err)
+	}
+	if err := w.tw.writeFile(dockerArchiveManifestFile, manifestJSON); err != nil {
+		return err
+	}
+	if repositories := legacyRepositories(entries); len(repositories) > 0 {
+		repositoriesJSON, err := json.Marshal(repositories)
+		if err != nil {
+			return fmt.Errorf("failed to marshal repositories file: %w", err)
+		}
+		if err := w.tw.writeFile(dockerArchiveRepositoriesFile, repositoriesJSON); err != nil {
+			return err
+		}
+	}
+	return w.tw.Close()
+}
+
+// legacyRepositories generates the content of the legacy `repositories` file,
+// which maps the repositories and the tags in the `RepoTags` of the entries to
+// the IDs of the top layers, i.e. the encoded digests of the layer blobs.
+// Images without layers are not listed.
+func legacyRepositories(entries []dockerArchiveEntry) map[string]map[string]string {
+	repositories := make(map[string]map[string]string)
+	for _, entry := range entries {
+		if len(entry.Layers) == 0 {
+			continue
+		}
+		topLayer := path.Base(entry.Layers[len(entry.Layers)-1])
+		for _, repoTag := range entry.RepoTags {
+			i := strings.LastIndexByte(repoTag, ':')
+			if i == -1 || i < strings.LastIndexByte(repoTag, '/') || strings.ContainsRune(repoTag, '@') {
+				// not in the form of "<repository>:<tag>"
+				continue
+			}
+			repo, tag := repoTag[:i], repoTag[i+1:]
+			if repositories[repo] == nil {
+				repositories[repo] = make(map[string]string)
+			}
+			repositories[repo][tag] = topLayer
+		}
+	}
+	return repositories
+}
+
+// manifestEntries generates the `manifest.json` entries from the tagged image
+// manifests.
+func (w *DockerArchiveWriter) manifestEntries() ([]dockerArchiveEntry, error) {
+	dgsts := make([]digest.Digest, 0, len(w.repoTags))
+	for dgst := range w.repoTags {
+		dgsts = append(dgsts, dgst)
+	}
+	slices.SortFunc(dgsts, func(a, b digest.Digest) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	entries := make([]dockerArchiveEntry, 0, len(dgsts))
+	for _, dgst := range dgsts {
+		entry, err := w.manifestEntry(dgst)
+		if err != nil {
+			return nil, err
+		}
+		for repoTag := range w.repoTags[dgst] {
+			entry.RepoTags = append(entry.RepoTags, repoTag)
+		}
+		slices.Sort(entry.RepoTags)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// manifestEntry returns the `manifest.json` entry of the image manifest
+// identified by dgst. The config and the layers must have been written to the
+// archive.
+func (w *DockerArchiveWriter) manifestEntry(dgst digest.Digest) (dockerArchiveEntry, error) {
+	t := w.tw
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	manifestJSON, ok := t.manifests[dgst]
+	if !ok {
+		return dockerArchiveEntry{}, fmt.Errorf("%s: %w", dgst, errdef.ErrNotFound)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return dockerArchiveEntry{}, fmt.Errorf("failed to decode manifest %s: %w", dgst, err)
+	}
+
+	blobPathOf := func(desc ocispec.Descriptor) (string, error) {
+		if _, ok := t.blobs[desc.Digest]; !ok {
+			return "", fmt.Errorf("%s: %s: %w", desc.Digest, desc.MediaType, errdef.ErrNotFound)
+		}
+		return blobPath(desc.Digest)
+	}
+	var entry dockerArchiveEntry
+	var err error
+	if entry.Config, err = blobPathOf(manifest.Config); err != nil {
+		return dockerArchiveEntry{}, err
+	}
+	entry.Layers = make([]string, 0, len(manifest.Layers))
+	for _, layer := range manifest.Layers {
+		layerPath, err := blobPathOf(layer)
+		if err != nil {
+			return dockerArchiveEntry{}, err
+		}
+		entry.Layers = append(entry.Layers, layerPath)
+	}
+	return entry, nil
+}
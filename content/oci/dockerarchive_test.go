@@ -0,0 +1,422 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/internal/docker"
+)
+
+func TestDockerArchiveStoreInterface(t *testing.T) {
+	var store interface{} = &DockerArchiveStore{}
+	if _, ok := store.(oras.ReadOnlyGraphTarget); !ok {
+		t.Error("&DockerArchiveStore{} does not conform oras.ReadOnlyGraphTarget")
+	}
+	var writer interface{} = &DockerArchiveWriter{}
+	if _, ok := writer.(oras.Target); !ok {
+		t.Error("&DockerArchiveWriter{} does not conform oras.Target")
+	}
+}
+
+// writeTestTar writes a tar archive with the files in order to path.
+func writeTestTar(t *testing.T, path string, files [][2]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, file := range files {
+		if err := writeTarFile(tw, file[0], []byte(file[1])); err != nil {
+			t.Fatal("writeTarFile() error =", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal("tar.Writer.Close() error =", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+}
+
+func TestDockerArchiveStore(t *testing.T) {
+	ctx := context.Background()
+
+	// build a docker archive in the legacy layout
+	layer := []byte("uncompressed layer")
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	if _, err := zw.Write([]byte("compressed layer")); err != nil {
+		t.Fatal("gzip.Writer.Write() error =", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal("gzip.Writer.Close() error =", err)
+	}
+	config := ocispec.Image{
+		RootFS: ocispec.RootFS{
+			Type: "layers",
+			DiffIDs: []digest.Digest{
+				digest.FromBytes(layer),
+				digest.FromString("compressed layer"),
+			},
+		},
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal("json.Marshal() error =", err)
+	}
+	configName := digest.FromBytes(configJSON).Encoded() + ".json"
+	manifestJSON, err := json.Marshal([]dockerArchiveEntry{{
+		Config:   configName,
+		RepoTags: []string{"example.com/foo:latest", "example.com/foo:v1"},
+		Layers:   []string{"aaa/layer.tar", "./bbb/layer.tar"},
+	}})
+	if err != nil {
+		t.Fatal("json.Marshal() error =", err)
+	}
+	tarPath := filepath.Join(t.TempDir(), "docker.tar")
+	writeTestTar(t, tarPath, [][2]string{
+		{"aaa/layer.tar", string(layer)},
+		{"bbb/layer.tar", gzipped.String()},
+		{configName, string(configJSON)},
+		{"manifest.json", string(manifestJSON)},
+		{"repositories", `{"example.com/foo":{"latest":"bbb"}}`},
+	})
+
+	s, err := NewFromDockerArchive(ctx, tarPath)
+	if err != nil {
+		t.Fatal("NewFromDockerArchive() error =", err)
+	}
+
+	// resolve and fetch the synthesized manifest
+	desc, err := s.Resolve(ctx, "example.com/foo:v1")
+	if err != nil {
+		t.Fatal("DockerArchiveStore.Resolve() error =", err)
+	}
+	if desc.MediaType != docker.MediaTypeManifest {
+		t.Errorf("DockerArchiveStore.Resolve().MediaType = %v, want %v", desc.MediaType, docker.MediaTypeManifest)
+	}
+	gotJSON, err := content.FetchAll(ctx, s, desc)
+	if err != nil {
+		t.Fatal("content.FetchAll() error =", err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(gotJSON, &manifest); err != nil {
+		t.Fatal("json.Unmarshal() error =", err)
+	}
+	wantConfig := ocispec.Descriptor{
+		MediaType: docker.MediaTypeConfig,
+		Digest:    digest.FromBytes(configJSON),
+		Size:      int64(len(configJSON)),
+	}
+	if !reflect.DeepEqual(manifest.Config, wantConfig) {
+		t.Errorf("manifest config = %v, want %v", manifest.Config, wantConfig)
+	}
+	wantLayers := []ocispec.Descriptor{
+		{
+			MediaType: docker.MediaTypeUncompressedLayer,
+			Digest:    digest.FromBytes(layer),
+			Size:      int64(len(layer)),
+		},
+		{
+			MediaType: docker.MediaTypeLayer,
+			Digest:    digest.FromBytes(gzipped.Bytes()),
+			Size:      int64(gzipped.Len()),
+		},
+	}
+	if !reflect.DeepEqual(manifest.Layers, wantLayers) {
+		t.Errorf("manifest layers = %v, want %v", manifest.Layers, wantLayers)
+	}
+
+	// tags and predecessors
+	var tags []string
+	if err := s.Tags(ctx, "", func(got []string) error {
+		tags = append(tags, got...)
+		return nil
+	}); err != nil {
+		t.Fatal("DockerArchiveStore.Tags() error =", err)
+	}
+	if want := []string{"example.com/foo:latest", "example.com/foo:v1"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("DockerArchiveStore.Tags() = %v, want %v", tags, want)
+	}
+	predecessors, err := s.Predecessors(ctx, wantLayers[1])
+	if err != nil {
+		t.Fatal("DockerArchiveStore.Predecessors() error =", err)
+	}
+	if want := []ocispec.Descriptor{desc}; !reflect.DeepEqual(predecessors, want) {
+		t.Errorf("DockerArchiveStore.Predecessors() = %v, want %v", predecessors, want)
+	}
+	if got, err := s.Resolve(ctx, wantConfig.Digest.String()); err != nil || !reflect.DeepEqual(got, wantConfig) {
+		t.Errorf("DockerArchiveStore.Resolve() = %v, %v, want %v", got, err, wantConfig)
+	}
+
+	// copy the image, verifying the blobs
+	dst := memory.New()
+	if _, err := oras.Copy(ctx, s, "example.com/foo:latest", dst, "latest", oras.DefaultCopyOptions); err != nil {
+		t.Fatal("oras.Copy() error =", err)
+	}
+
+	// errors
+	if _, err := s.Resolve(ctx, "missing"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("DockerArchiveStore.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	missing := content.NewDescriptorFromBytes("test/layer", []byte("missing"))
+	if _, err := s.Fetch(ctx, missing); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("DockerArchiveStore.Fetch() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if exists, err := s.Exists(ctx, missing); err != nil || exists {
+		t.Errorf("DockerArchiveStore.Exists() = %v, %v, want %v", exists, err, false)
+	}
+}
+
+func TestNewFromDockerArchive_Error(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+
+	// missing manifest file
+	tarPath := filepath.Join(tempDir, "empty.tar")
+	writeTestTar(t, tarPath, [][2]string{{"repositories", "{}"}})
+	if _, err := NewFromDockerArchive(ctx, tarPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("NewFromDockerArchive() error = %v, wantErr %v", err, os.ErrNotExist)
+	}
+
+	// missing layer
+	tarPath = filepath.Join(tempDir, "missing.tar")
+	writeTestTar(t, tarPath, [][2]string{
+		{"config.json", "{}"},
+		{"manifest.json", `[{"Config":"config.json","Layers":["layer.tar"]}]`},
+	})
+	if _, err := NewFromDockerArchive(ctx, tarPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("NewFromDockerArchive() error = %v, wantErr %v", err, os.ErrNotExist)
+	}
+}
+
+func TestDockerArchiveWriter(t *testing.T) {
+	ctx := context.Background()
+	src := memory.New()
+	config := pushToMemory(t, src, ocispec.MediaTypeImageConfig, []byte(`{"rootfs":{"type":"layers"}}`))
+	layer := pushToMemory(t, src, ocispec.MediaTypeImageLayer, []byte("layer"))
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+	if err != nil {
+		t.Fatal("json.Marshal() error =", err)
+	}
+	manifest := pushToMemory(t, src, ocispec.MediaTypeImageManifest, manifestJSON)
+	if err := src.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatal("Memory.Tag() error =", err)
+	}
+	indexJSON, err := json.Marshal(ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{manifest},
+	})
+	if err != nil {
+		t.Fatal("json.Marshal() error =", err)
+	}
+	index := pushToMemory(t, src, ocispec.MediaTypeImageIndex, indexJSON)
+
+	tarPath := filepath.Join(t.TempDir(), "docker.tar")
+	f, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatal("os.Create() error =", err)
+	}
+	defer f.Close()
+	dst, err := NewDockerArchiveWriter(f, ExportOptions{})
+	if err != nil {
+		t.Fatal("NewDockerArchiveWriter() error =", err)
+	}
+	if _, err := oras.Copy(ctx, src, "v1", dst, "example.com/foo:old", oras.DefaultCopyOptions); err != nil {
+		t.Fatal("oras.Copy() error =", err)
+	}
+	for _, ref := range []string{"example.com/foo:v1", "example.com/bar:v1"} {
+		if err := dst.Tag(ctx, manifest, ref); err != nil {
+			t.Fatalf("DockerArchiveWriter.Tag(%s) error = %v", ref, err)
+		}
+	}
+	if err := dst.Push(ctx, index, bytes.NewReader(indexJSON)); err != nil {
+		t.Fatal("DockerArchiveWriter.Push() error =", err)
+	}
+	if err := dst.Tag(ctx, index, "example.com/foo:index"); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("DockerArchiveWriter.Tag() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+	if err := dst.Close(); err != nil {
+		t.Fatal("DockerArchiveWriter.Close() error =", err)
+	}
+
+	// the legacy repositories file maps the tags to the top layer
+	archive, err := os.ReadFile(tarPath)
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+	var repositories map[string]map[string]string
+	if err := json.Unmarshal(tarEntry(t, archive, "repositories"), &repositories); err != nil {
+		t.Fatal("json.Unmarshal() error =", err)
+	}
+	wantRepositories := map[string]map[string]string{
+		"example.com/bar": {"v1": layer.Digest.Encoded()},
+		"example.com/foo": {"old": layer.Digest.Encoded(), "v1": layer.Digest.Encoded()},
+	}
+	if !reflect.DeepEqual(repositories, wantRepositories) {
+		t.Errorf("repositories = %v, want %v", repositories, wantRepositories)
+	}
+
+	// read the archive back as a docker archive
+	s, err := NewFromDockerArchive(ctx, tarPath)
+	if err != nil {
+		t.Fatal("NewFromDockerArchive() error =", err)
+	}
+	var tags []string
+	if err := s.Tags(ctx, "", func(got []string) error {
+		tags = append(tags, got...)
+		return nil
+	}); err != nil {
+		t.Fatal("DockerArchiveStore.Tags() error =", err)
+	}
+	if want := []string{"example.com/bar:v1", "example.com/foo:old", "example.com/foo:v1"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("DockerArchiveStore.Tags() = %v, want %v", tags, want)
+	}
+	desc, err := s.Resolve(ctx, "example.com/foo:v1")
+	if err != nil {
+		t.Fatal("DockerArchiveStore.Resolve() error =", err)
+	}
+	successors, err := content.Successors(ctx, s, desc)
+	if err != nil {
+		t.Fatal("content.Successors() error =", err)
+	}
+	if len(successors) != 2 || successors[0].Digest != config.Digest || successors[1].Digest != layer.Digest {
+		t.Errorf("content.Successors() = %v, want %v and %v", successors, config, layer)
+	}
+
+	// read the archive back as an OCI layout
+	rs, err := NewFromTar(ctx, tarPath)
+	if err != nil {
+		t.Fatal("NewFromTar() error =", err)
+	}
+	got, err := rs.Resolve(ctx, "example.com/bar:v1")
+	if err != nil {
+		t.Fatal("ReadOnlyStore.Resolve() error =", err)
+	}
+	if !content.Equal(got, manifest) {
+		t.Errorf("ReadOnlyStore.Resolve() = %v, want %v", got, manifest)
+	}
+}
+
+func TestDockerArchiveWriter_MissingBlob(t *testing.T) {
+	ctx := context.Background()
+	config := content.NewDescriptorFromBytes(docker.MediaTypeConfig, []byte("{}"))
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: docker.MediaTypeManifest,
+		Config:    config,
+	})
+	if err != nil {
+		t.Fatal("json.Marshal() error =", err)
+	}
+	manifest := content.NewDescriptorFromBytes(docker.MediaTypeManifest, manifestJSON)
+
+	var buf bytes.Buffer
+	w, err := NewDockerArchiveWriter(&buf, ExportOptions{})
+	if err != nil {
+		t.Fatal("NewDockerArchiveWriter() error =", err)
+	}
+	if err := w.Push(ctx, manifest, bytes.NewReader(manifestJSON)); err != nil {
+		t.Fatal("DockerArchiveWriter.Push() error =", err)
+	}
+	if err := w.Tag(ctx, manifest, "example.com/foo:latest"); err != nil {
+		t.Fatal("DockerArchiveWriter.Tag() error =", err)
+	}
+	if err := w.Close(); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("DockerArchiveWriter.Close() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+}
+
+func TestDockerArchiveWriter_Artifact(t *testing.T) {
+	ctx := context.Background()
+	config := content.NewDescriptorFromBytes("application/vnd.example.config", []byte("{}"))
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+	})
+	if err != nil {
+		t.Fatal("json.Marshal() error =", err)
+	}
+	manifest := content.NewDescriptorFromBytes(ocispec.MediaTypeImageManifest, manifestJSON)
+
+	var buf bytes.Buffer
+	w, err := NewDockerArchiveWriter(&buf, ExportOptions{})
+	if err != nil {
+		t.Fatal("NewDockerArchiveWriter() error =", err)
+	}
+	if err := w.Tag(ctx, manifest, "example.com/foo:latest"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("DockerArchiveWriter.Tag() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if err := w.Push(ctx, config, bytes.NewReader([]byte("{}"))); err != nil {
+		t.Fatal("DockerArchiveWriter.Push() error =", err)
+	}
+	if err := w.Push(ctx, manifest, bytes.NewReader(manifestJSON)); err != nil {
+		t.Fatal("DockerArchiveWriter.Push() error =", err)
+	}
+	if err := w.Tag(ctx, manifest, "example.com/foo:latest"); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("DockerArchiveWriter.Tag() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("DockerArchiveWriter.Close() error =", err)
+	}
+	names := tarEntryNames(t, buf.Bytes())
+	if !slices.Contains(names, "manifest.json") || slices.Contains(names, "repositories") {
+		t.Errorf("archive entries = %v, want manifest.json without repositories", names)
+	}
+}
+
+// tarEntry returns the content of the named entry in the tar archive.
+func tarEntry(t *testing.T, archive []byte, name string) []byte {
+	t.Helper()
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			t.Fatalf("tar entry %s is not found, error = %v", name, err)
+		}
+		if header.Name == name {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal("io.ReadAll() error =", err)
+			}
+			return data
+		}
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/internal/container/set"
+	"oras.land/oras-go/v2/internal/descriptor"
+)
+
+// Compression is the compression applied to a whole archive.
+type Compression string
+
+const (
+	// CompressionNone indicates an uncompressed archive.
+	CompressionNone Compression = ""
+	// CompressionGzip indicates a gzip compressed archive.
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd indicates a zstd compressed archive.
+	CompressionZstd Compression = "zstd"
+)
+
+// ExportOptions contains parameters for Export and ExportReferences.
+type ExportOptions struct {
+	// Compression is the compression applied to the whole archive.
+	//   - Default value: CompressionNone.
+	Compression Compression
+
+	// NewZstdWriter creates a zstd compressing writer writing to w. It is
+	// required by CompressionZstd as zstd is not supported by the standard
+	// library.
+	NewZstdWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+// ExportSource represents a read-only source of content with references, such
+// as Store, ReadOnlyStore, and the repositories of remote registries.
+type ExportSource interface {
+	content.ReadOnlyStorage
+	content.Resolver
+}
+
+// ExportReferences resolves the references in src and streams an OCI image
+// layout archive containing the resolved descriptors as its roots to w.
+// The tags among the references are recorded in the
+// "org.opencontainers.image.ref.name" annotations in `index.json`.
+//
+// See also Export.
+func ExportReferences(ctx context.Context, w io.Writer, src ExportSource, references []string, opts ExportOptions) error {
+	roots := make([]ocispec.Descriptor, 0, len(references))
+	for _, reference := range references {
+		desc, err := src.Resolve(ctx, reference)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", reference, err)
+		}
+		desc = deleteAnnotationRefName(desc)
+		if reference != desc.Digest.String() {
+			desc.Annotations = withRefName(desc.Annotations, reference)
+		}
+		roots = append(roots, desc)
+	}
+	return Export(ctx, w, src, roots, opts)
+}
+
+// Export streams an OCI image layout archive to w, which consists of the
+// `oci-layout` file, the `index.json` file listing the roots in order, and
+// the blobs reachable from the roots fetched from src.
+//
+// The archive is deterministic: the blobs are written in the order of their
+// digests, and the metadata of the entries, such as the modification times
+// and the owners, are fixed. Thus identical inputs produce identical archives.
+// The blobs are verified against the descriptors while being written, and the
+// archive written so far is incomplete on error.
+//
+// Reference: https://github.com/opencontainers/image-spec/blob/v1.1.1/image-layout.md
+func Export(ctx context.Context, w io.Writer, src content.ReadOnlyStorage, roots []ocispec.Descriptor, opts ExportOptions) (exportErr error) {
+	blobs, err := reachableBlobs(ctx, src, roots)
+	if err != nil {
+		return err
+	}
+
+	cw, err := compressWriter(w, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cw.Close(); err != nil && exportErr == nil {
+			exportErr = fmt.Errorf("failed to close compressor: %w", err)
+		}
+	}()
+	tw := tar.NewWriter(cw)
+	defer func() {
+		if err := tw.Close(); err != nil && exportErr == nil {
+			exportErr = fmt.Errorf("failed to close tar writer: %w", err)
+		}
+	}()
+
+	layoutJSON, err := json.Marshal(ocispec.ImageLayout{
+		Version: ocispec.ImageLayoutVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal OCI layout file: %w", err)
+	}
+	if err := writeTarFile(tw, ocispec.ImageLayoutFile, layoutJSON); err != nil {
+		return err
+	}
+	indexJSON, err := json.Marshal(ocispec.Index{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2, // historical value
+		},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: roots,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal index file: %w", err)
+	}
+	if err := writeTarFile(tw, ocispec.ImageIndexFile, indexJSON); err != nil {
+		return err
+	}
+
+	writtenDirs := set.New[string]()
+	for _, desc := range blobs {
+		if err := isContextDone(ctx); err != nil {
+			return err
+		}
+		blobPath, err := blobPath(desc.Digest)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", desc.Digest, desc.MediaType, errdef.ErrInvalidDigest)
+		}
+		if err := writeTarDirs(tw, path.Dir(blobPath), writtenDirs); err != nil {
+			return err
+		}
+		if err := writeTarBlob(ctx, tw, src, blobPath, desc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reachableBlobs returns the descriptors of the blobs reachable from the
+// roots in src, including the roots, sorted by digest.
+func reachableBlobs(ctx context.Context, src content.ReadOnlyStorage, roots []ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	var blobs []ocispec.Descriptor
+	visited := set.New[digest.Digest]()
+	queue := make([]ocispec.Descriptor, 0, len(roots))
+	for _, root := range roots {
+		queue = append(queue, descriptor.Plain(root))
+	}
+	for len(queue) > 0 {
+		if err := isContextDone(ctx); err != nil {
+			return nil, err
+		}
+		node := queue[0]
+		queue = queue[1:]
+		if visited.Contains(node.Digest) {
+			continue
+		}
+		visited.Add(node.Digest)
+		blobs = append(blobs, node)
+
+		successors, err := content.Successors(ctx, src, node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get successors of %s: %w", node.Digest, err)
+		}
+		for _, successor := range successors {
+			queue = append(queue, descriptor.Plain(successor))
+		}
+	}
+	slices.SortFunc(blobs, func(a, b ocispec.Descriptor) int {
+		return strings.Compare(a.Digest.String(), b.Digest.String())
+	})
+	return blobs, nil
+}
+
+// compressWriter returns a writer applying the compression in opts to w.
+func compressWriter(w io.Writer, opts ExportOptions) (io.WriteCloser, error) {
+	switch opts.Compression {
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		if opts.NewZstdWriter == nil {
+			return nil, fmt.Errorf("%w: zstd compression requires NewZstdWriter", errdef.ErrUnsupported)
+		}
+		return opts.NewZstdWriter(w)
+	default:
+		return nil, fmt.Errorf("%w: compression %q", errdef.ErrUnsupported, opts.Compression)
+	}
+}
+
+// nopWriteCloser wraps an io.Writer with a no-op Close method.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// tarHeader returns a tar header with the fixed metadata for determinism.
+func tarHeader(name string, typeflag byte, mode int64, size int64) *tar.Header {
+	return &tar.Header{
+		Typeflag: typeflag,
+		Name:     name,
+		Mode:     mode,
+		Size:     size,
+		ModTime:  time.Unix(0, 0),
+	}
+}
+
+// writeTarFile writes a regular file with the given content to tw.
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(tarHeader(name, tar.TypeReg, 0644, int64(len(data)))); err != nil {
+		return fmt.Errorf("failed to write tar header of %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeTarDirs writes the directory and its parents to tw if not written.
+func writeTarDirs(tw *tar.Writer, dir string, written set.Set[string]) error {
+	if dir == "." || written.Contains(dir) {
+		return nil
+	}
+	if err := writeTarDirs(tw, path.Dir(dir), written); err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(tarHeader(dir+"/", tar.TypeDir, 0755, 0)); err != nil {
+		return fmt.Errorf("failed to write tar header of %s: %w", dir, err)
+	}
+	written.Add(dir)
+	return nil
+}
+
+// writeTarBlob writes the blob described by desc fetched from src to tw.
+func writeTarBlob(ctx context.Context, tw *tar.Writer, src content.Fetcher, name string, desc ocispec.Descriptor) error {
+	rc, err := src.Fetch(ctx, desc)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", desc.Digest, err)
+	}
+	defer rc.Close()
+
+	if err := tw.WriteHeader(tarHeader(name, tar.TypeReg, 0444, desc.Size)); err != nil {
+		return fmt.Errorf("failed to write tar header of %s: %w", name, err)
+	}
+	vr := content.NewVerifyReader(rc, desc)
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	if _, err := io.CopyBuffer(tw, vr, *buf); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := vr.Verify(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// withRefName returns a copy of annotations with the ref name set.
+func withRefName(annotations map[string]string, ref string) map[string]string {
+	result := make(map[string]string, len(annotations)+1)
+	maps.Copy(result, annotations)
+	result[ocispec.AnnotationRefName] = ref
+	return result
+}
@@ -0,0 +1,262 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// tarEntryNames returns the names of the entries in the tar archive.
+func tarEntryNames(t *testing.T, archive []byte) []string {
+	t.Helper()
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatal("tar.Reader.Next() error =", err)
+		}
+		names = append(names, header.Name)
+	}
+}
+
+func TestExportReferences(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	manifest := pushManifest(t, s, nil, config, layer)
+	if err := s.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	other := pushManifest(t, s, nil, config)
+	unexported := pushManifest(t, s, nil, pushBlob(t, s, "test/config", []byte("unexported")))
+
+	var buf bytes.Buffer
+	if err := ExportReferences(ctx, &buf, s, []string{"v1", other.Digest.String()}, ExportOptions{}); err != nil {
+		t.Fatal("ExportReferences() error =", err)
+	}
+
+	// check the entries
+	blobPaths := []string{
+		"blobs/sha256/" + config.Digest.Encoded(),
+		"blobs/sha256/" + layer.Digest.Encoded(),
+		"blobs/sha256/" + manifest.Digest.Encoded(),
+		"blobs/sha256/" + other.Digest.Encoded(),
+	}
+	slices.Sort(blobPaths)
+	want := append([]string{"oci-layout", "index.json", "blobs/", "blobs/sha256/"}, blobPaths...)
+	if got := tarEntryNames(t, buf.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("archive entries = %v, want %v", got, want)
+	}
+
+	// read the archive back
+	tarPath := filepath.Join(t.TempDir(), "layout.tar")
+	if err := os.WriteFile(tarPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+	rs, err := NewFromTar(ctx, tarPath)
+	if err != nil {
+		t.Fatal("NewFromTar() error =", err)
+	}
+	got, err := rs.Resolve(ctx, "v1")
+	if err != nil {
+		t.Fatal("ReadOnlyStore.Resolve() error =", err)
+	}
+	if !content.Equal(got, manifest) {
+		t.Errorf("ReadOnlyStore.Resolve() = %v, want %v", got, manifest)
+	}
+	if _, err := rs.Resolve(ctx, other.Digest.String()); err != nil {
+		t.Errorf("ReadOnlyStore.Resolve() error = %v", err)
+	}
+	if exists, err := rs.Exists(ctx, unexported); err != nil || exists {
+		t.Errorf("ReadOnlyStore.Exists() = %v, %v, want %v", exists, err, false)
+	}
+	report, err := rs.Verify(ctx)
+	if err != nil {
+		t.Fatal("ReadOnlyStore.Verify() error =", err)
+	}
+	if !report.OK() {
+		t.Errorf("ReadOnlyStore.Verify().Issues = %v, want none", report.Issues)
+	}
+}
+
+func TestExport_Deterministic(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	var layers []ocispec.Descriptor
+	for _, data := range []string{"foo", "bar", "baz", "qux"} {
+		layers = append(layers, pushBlob(t, s, "test/layer", []byte(data)))
+	}
+	manifest := pushManifest(t, s, nil, config, layers...)
+
+	// copy the content into another storage in a different order
+	m := memory.New()
+	for _, desc := range append([]ocispec.Descriptor{manifest, config}, layers...) {
+		data, err := content.FetchAll(ctx, s, desc)
+		if err != nil {
+			t.Fatal("content.FetchAll() error =", err)
+		}
+		if err := m.Push(ctx, desc, bytes.NewReader(data)); err != nil {
+			t.Fatal("Memory.Push() error =", err)
+		}
+	}
+
+	for _, compression := range []Compression{CompressionNone, CompressionGzip} {
+		t.Run(string(compression), func(t *testing.T) {
+			opts := ExportOptions{Compression: compression}
+			var want bytes.Buffer
+			if err := Export(ctx, &want, s, []ocispec.Descriptor{manifest}, opts); err != nil {
+				t.Fatal("Export() error =", err)
+			}
+			for i := 0; i < 2; i++ {
+				var got bytes.Buffer
+				if err := Export(ctx, &got, m, []ocispec.Descriptor{manifest}, opts); err != nil {
+					t.Fatal("Export() error =", err)
+				}
+				if !bytes.Equal(got.Bytes(), want.Bytes()) {
+					t.Errorf("Export() is not deterministic")
+				}
+			}
+		})
+	}
+}
+
+func TestExport_Compression(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	manifest := pushManifest(t, s, nil, pushBlob(t, s, "test/config", []byte("config")))
+	roots := []ocispec.Descriptor{manifest}
+
+	var plain bytes.Buffer
+	if err := Export(ctx, &plain, s, roots, ExportOptions{}); err != nil {
+		t.Fatal("Export() error =", err)
+	}
+
+	// gzip
+	var compressed bytes.Buffer
+	if err := Export(ctx, &compressed, s, roots, ExportOptions{Compression: CompressionGzip}); err != nil {
+		t.Fatal("Export() error =", err)
+	}
+	zr, err := gzip.NewReader(&compressed)
+	if err != nil {
+		t.Fatal("gzip.NewReader() error =", err)
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal("io.ReadAll() error =", err)
+	}
+	if !bytes.Equal(decompressed, plain.Bytes()) {
+		t.Errorf("decompressed archive does not match the uncompressed archive")
+	}
+
+	// zstd without a compressor
+	err = Export(ctx, io.Discard, s, roots, ExportOptions{Compression: CompressionZstd})
+	if !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("Export() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+
+	// zstd with a compressor
+	var closed bool
+	var zstd bytes.Buffer
+	err = Export(ctx, &zstd, s, roots, ExportOptions{
+		Compression: CompressionZstd,
+		NewZstdWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return &testWriteCloser{Writer: w, closed: &closed}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal("Export() error =", err)
+	}
+	if !closed {
+		t.Errorf("zstd writer is not closed")
+	}
+	if !bytes.Equal(zstd.Bytes(), plain.Bytes()) {
+		t.Errorf("archive written via the zstd writer does not match the uncompressed archive")
+	}
+
+	// unknown compression
+	err = Export(ctx, io.Discard, s, roots, ExportOptions{Compression: "unknown"})
+	if !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("Export() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+}
+
+func TestExport_Error(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	manifest := pushManifest(t, s, nil, pushBlob(t, s, "test/config", []byte("config")), layer)
+
+	// missing blob
+	if err := s.storage.Delete(ctx, layer); err != nil {
+		t.Fatal("Storage.Delete() error =", err)
+	}
+	err = Export(ctx, io.Discard, s, []ocispec.Descriptor{manifest}, ExportOptions{})
+	if !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Export() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+
+	// missing reference
+	err = ExportReferences(ctx, io.Discard, s, []string{"missing"}, ExportOptions{})
+	if !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("ExportReferences() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+}
+
+// testWriteCloser records whether it is closed.
+type testWriteCloser struct {
+	io.Writer
+	closed *bool
+}
+
+func (w *testWriteCloser) Close() error {
+	*w.closed = true
+	return nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/internal/descriptor"
+	"oras.land/oras-go/v2/internal/graph"
+)
+
+const (
+	// graphIndexFile is the name of the file persisting the graph of the
+	// manifests. It is not part of the OCI image layout.
+	graphIndexFile = "index.graph.json"
+	// graphIndexVersion is the version of the graph index file format.
+	graphIndexVersion = 1
+)
+
+// graphIndex is the content of the graph index file.
+type graphIndex struct {
+	// Version is the version of the file format.
+	Version int `json:"version"`
+	// Index is the digest of the `index.json` file which the graph is built
+	// for. The graph index is valid only if `index.json` is not changed.
+	Index digest.Digest `json:"index"`
+	// Nodes are the nodes of the graph along with their direct successors.
+	Nodes []graph.Node `json:"nodes"`
+}
+
+// ensureGraph loads the graph if it is not loaded.
+// The graph is loaded from the graph index file if the file is valid for
+// `index.json` as of the last load or save. Otherwise, the graph is rebuilt
+// from the manifests referenced by the index, and the file is rewritten.
+func (s *Store) ensureGraph(ctx context.Context) error {
+	s.graphLock.Lock()
+	defer s.graphLock.Unlock()
+
+	if s.graphLoaded {
+		return nil
+	}
+
+	s.indexLock.Lock()
+	indexDigest := s.indexDigest
+	s.indexLock.Unlock()
+	if nodes, ok := s.readGraphIndexFile(indexDigest); ok {
+		for _, node := range nodes {
+			s.graph.Add(node.Descriptor, node.Successors)
+		}
+		s.graphLoaded = true
+		return nil
+	}
+
+	// rebuild the graph
+	for ref, desc := range s.tagResolver.Map() {
+		if ref != desc.Digest.String() {
+			continue
+		}
+		if err := s.graph.IndexAll(ctx, s.storage, descriptor.Plain(desc)); err != nil {
+			return err
+		}
+	}
+	s.graphLoaded = true
+	return s.saveGraphIndex()
+}
+
+// successors returns the direct successors of node. The successors are taken
+// from the graph if node is indexed. Otherwise, node is fetched.
+func (s *Store) successors(ctx context.Context, node ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	if successors, ok := s.graph.Successors(node); ok {
+		return successors, nil
+	}
+	return content.Successors(ctx, s.storage, node)
+}
+
+// readGraphIndexFile reads the nodes in the graph index file if the file is
+// valid for the `index.json` file identified by indexDigest.
+func (s *Store) readGraphIndexFile(indexDigest digest.Digest) ([]graph.Node, bool) {
+	graphIndexJSON, err := os.ReadFile(s.graphIndexPath)
+	if err != nil {
+		return nil, false
+	}
+	var index graphIndex
+	if err := json.Unmarshal(graphIndexJSON, &index); err != nil {
+		// the corrupted file will be rewritten
+		return nil, false
+	}
+	if index.Version != graphIndexVersion || index.Index != indexDigest {
+		return nil, false
+	}
+	return index.Nodes, true
+}
+
+// saveGraphIndex writes the graph index file for `index.json` as of the last
+// load or save.
+func (s *Store) saveGraphIndex() error {
+	s.indexLock.Lock()
+	defer s.indexLock.Unlock()
+
+	unlock, err := s.lockIndexFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.writeGraphIndexFile()
+}
+
+// writeGraphIndexFile writes the graph index file atomically.
+func (s *Store) writeGraphIndexFile() error {
+	graphIndexJSON, err := json.Marshal(graphIndex{
+		Version: graphIndexVersion,
+		Index:   s.indexDigest,
+		Nodes:   s.graph.Nodes(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal graph index file: %w", err)
+	}
+	if err := writeFileAtomic(s.graphIndexPath, graphIndexJSON); err != nil {
+		return fmt.Errorf("failed to write graph index file: %w", err)
+	}
+	return nil
+}
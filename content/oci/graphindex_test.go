@@ -0,0 +1,242 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// readGraphIndex reads the graph index file under root.
+func readGraphIndex(t *testing.T, root string) graphIndex {
+	t.Helper()
+	graphIndexJSON, err := os.ReadFile(filepath.Join(root, graphIndexFile))
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+	var index graphIndex
+	if err := json.Unmarshal(graphIndexJSON, &index); err != nil {
+		t.Fatal("json.Unmarshal() error =", err)
+	}
+	return index
+}
+
+// indexFileDigest returns the digest of the `index.json` file under root.
+func indexFileDigest(t *testing.T, root string) digest.Digest {
+	t.Helper()
+	indexJSON, err := os.ReadFile(filepath.Join(root, ocispec.ImageIndexFile))
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+	return digest.FromBytes(indexJSON)
+}
+
+func TestStore_PersistGraphIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	opts := StoreOptions{PersistGraphIndex: true}
+	s, err := NewWithOptions(ctx, tempDir, opts)
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	manifest := pushManifest(t, s, nil, config, layer)
+	if err := s.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	referrer := pushManifest(t, s, &manifest, config)
+
+	// the graph index is saved along with the index
+	if got, want := readGraphIndex(t, tempDir).Index, indexFileDigest(t, tempDir); got != want {
+		t.Errorf("graphIndex.Index = %v, want %v", got, want)
+	}
+
+	// remove the manifests so that the graph can only be loaded from the
+	// graph index
+	for _, desc := range []ocispec.Descriptor{manifest, referrer} {
+		if err := os.Remove(filepath.Join(tempDir, "blobs", "sha256", desc.Digest.Encoded())); err != nil {
+			t.Fatal("os.Remove() error =", err)
+		}
+	}
+	s, err = NewWithOptions(ctx, tempDir, opts)
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	if s.graphLoaded {
+		t.Errorf("Store.graphLoaded = %v, want %v", s.graphLoaded, false)
+	}
+	predecessors, err := s.Predecessors(ctx, layer)
+	if err != nil {
+		t.Fatal("Store.Predecessors() error =", err)
+	}
+	if want := []ocispec.Descriptor{manifest}; !reflect.DeepEqual(predecessors, want) {
+		t.Errorf("Store.Predecessors() = %v, want %v", predecessors, want)
+	}
+	predecessors, err = s.Predecessors(ctx, manifest)
+	if err != nil {
+		t.Fatal("Store.Predecessors() error =", err)
+	}
+	if want := []ocispec.Descriptor{referrer}; !reflect.DeepEqual(predecessors, want) {
+		t.Errorf("Store.Predecessors() = %v, want %v", predecessors, want)
+	}
+
+	// the graph index is invalidated by changes made without it
+	plain, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	if err := plain.Untag(ctx, "v1"); err != nil {
+		t.Fatal("Store.Untag() error =", err)
+	}
+	s, err = NewWithOptions(ctx, tempDir, opts)
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	predecessors, err = s.Predecessors(ctx, layer)
+	if err != nil {
+		t.Fatal("Store.Predecessors() error =", err)
+	}
+	if len(predecessors) != 0 {
+		t.Errorf("Store.Predecessors() = %v, want none", predecessors)
+	}
+	if got, want := readGraphIndex(t, tempDir).Index, indexFileDigest(t, tempDir); got != want {
+		t.Errorf("graphIndex.Index = %v, want %v", got, want)
+	}
+}
+
+func TestStore_PersistGraphIndex_Corrupted(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	opts := StoreOptions{PersistGraphIndex: true}
+	s, err := NewWithOptions(ctx, tempDir, opts)
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	manifest := pushManifest(t, s, nil, pushBlob(t, s, "test/config", []byte("config")), layer)
+
+	if err := os.WriteFile(filepath.Join(tempDir, graphIndexFile), []byte("corrupted"), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+	s, err = NewWithOptions(ctx, tempDir, opts)
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	predecessors, err := s.Predecessors(ctx, layer)
+	if err != nil {
+		t.Fatal("Store.Predecessors() error =", err)
+	}
+	if want := []ocispec.Descriptor{manifest}; !reflect.DeepEqual(predecessors, want) {
+		t.Errorf("Store.Predecessors() = %v, want %v", predecessors, want)
+	}
+	if got, want := readGraphIndex(t, tempDir).Index, indexFileDigest(t, tempDir); got != want {
+		t.Errorf("graphIndex.Index = %v, want %v", got, want)
+	}
+}
+
+func TestStore_PersistGraphIndex_GC(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	opts := StoreOptions{PersistGraphIndex: true}
+	s, err := NewWithOptions(ctx, tempDir, opts)
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	manifest := pushManifest(t, s, nil, config, layer)
+	if err := s.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	untagged := pushManifest(t, s, nil, config, pushBlob(t, s, "test/layer", []byte("untagged")))
+	garbage := pushBlob(t, s, "test/layer", []byte("garbage"))
+
+	s, err = NewWithOptions(ctx, tempDir, opts)
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	report, err := s.GCWithOptions(ctx, GCOptions{PruneIndex: true})
+	if err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	if got, want := len(report.Blobs), 3; got != want {
+		t.Errorf("len(GCReport.Blobs) = %v, want %v: %v", got, want, report.Blobs)
+	}
+	for _, desc := range []ocispec.Descriptor{config, layer, manifest} {
+		if exists, err := s.Exists(ctx, desc); err != nil || !exists {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", desc.Digest, exists, err, true)
+		}
+	}
+	for _, desc := range []ocispec.Descriptor{untagged, garbage} {
+		if exists, err := s.Exists(ctx, desc); err != nil || exists {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", desc.Digest, exists, err, false)
+		}
+	}
+
+	// the graph index reflects the collected graph
+	index := readGraphIndex(t, tempDir)
+	if got, want := index.Index, indexFileDigest(t, tempDir); got != want {
+		t.Errorf("graphIndex.Index = %v, want %v", got, want)
+	}
+	for _, node := range index.Nodes {
+		if node.Descriptor.Digest == untagged.Digest {
+			t.Errorf("graphIndex.Nodes contains the collected manifest %v", untagged)
+		}
+	}
+}
+
+func TestStore_PersistGraphIndex_GC_IndexNotPruned(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	opts := StoreOptions{PersistGraphIndex: true}
+	s, err := NewWithOptions(ctx, tempDir, opts)
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	manifest := pushManifest(t, s, nil, config, pushBlob(t, s, "test/layer", []byte("layer")))
+	if err := s.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	untagged := pushManifest(t, s, nil, config, pushBlob(t, s, "test/layer", []byte("untagged")))
+
+	s, err = NewWithOptions(ctx, tempDir, opts)
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	if _, err := s.GCWithOptions(ctx, GCOptions{}); err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+
+	// the graph index reflects the collected graph while `index.json` is kept
+	index := readGraphIndex(t, tempDir)
+	if got, want := index.Index, indexFileDigest(t, tempDir); got != want {
+		t.Errorf("graphIndex.Index = %v, want %v", got, want)
+	}
+	for _, node := range index.Nodes {
+		if node.Descriptor.Digest == untagged.Digest {
+			t.Errorf("graphIndex.Nodes contains the collected manifest %v", untagged)
+		}
+	}
+}
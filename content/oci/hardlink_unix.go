@@ -0,0 +1,32 @@
+//go:build unix
+
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// isHardLinked returns true if the file described by info has other hard
+// links.
+func isHardLinked(info fs.FileInfo) bool {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return st.Nlink > 1
+	}
+	return false
+}
@@ -0,0 +1,378 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/internal/container/set"
+	"oras.land/oras-go/v2/internal/descriptor"
+	"oras.land/oras-go/v2/registry"
+)
+
+// MultiStore implements `registry.Registry`, and represents a content store
+// based on file system with the OCI-Image layout, which holds multiple
+// repositories sharing a single pool of blobs.
+//
+// The tags of the repositories are recorded in the
+// "org.opencontainers.image.ref.name" annotations in `index.json` in the form
+// of "<repository>:<tag>" (e.g. "library/hello-world:latest"). References not
+// in this form, such as the ones tagged by Store, are not listed in any
+// repository.
+//
+// As the blobs are shared, content pushed to a repository exists in all the
+// repositories, and resolving a digest is not scoped to a repository.
+type MultiStore struct {
+	store *Store
+
+	// lock serializes the deletes with the tagging of the repositories, so
+	// that the content is not deleted while being tagged in other
+	// repositories.
+	lock sync.Mutex
+}
+
+// NewMultiStore creates a new multi-repository OCI store.
+func NewMultiStore(ctx context.Context, root string) (*MultiStore, error) {
+	return NewMultiStoreWithOptions(ctx, root, StoreOptions{})
+}
+
+// NewMultiStoreWithOptions creates a new multi-repository OCI store with the
+// given options.
+func NewMultiStoreWithOptions(ctx context.Context, root string, opts StoreOptions) (*MultiStore, error) {
+	store, err := NewWithOptions(ctx, root, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &MultiStore{store: store}, nil
+}
+
+// Store returns the underlying OCI store holding the blobs and the tags of all
+// the repositories, which can be used to save the index or to collect garbage.
+func (m *MultiStore) Store() *Store {
+	return m.store
+}
+
+// Repositories lists the names of the repositories with at least one tag,
+// returned in ascending order.
+// If `last` is NOT empty, the entries in the response start after the
+// repository specified by `last`. Otherwise, the response starts from the top
+// of the repositories list.
+//
+// See also `Repositories()` in the package `registry`.
+func (m *MultiStore) Repositories(ctx context.Context, last string, fn func(repos []string) error) error {
+	repoSet := set.New[string]()
+	for _, ref := range m.store.references() {
+		if repo, _, ok := splitRepositoryTag(ref); ok && (last == "" || repo > last) {
+			repoSet.Add(repo)
+		}
+	}
+	repos := make([]string, 0, len(repoSet))
+	for repo := range repoSet {
+		repos = append(repos, repo)
+	}
+	slices.Sort(repos)
+	return fn(repos)
+}
+
+// Repository returns the repository of the given name, which does not need to
+// exist. The returned repository implements `registry.Repository`.
+func (m *MultiStore) Repository(_ context.Context, name string) (registry.Repository, error) {
+	ref := registry.Reference{Repository: name}
+	if err := ref.ValidateRepository(); err != nil {
+		return nil, err
+	}
+	return &multiStoreRepository{
+		store: m.store,
+		lock:  &m.lock,
+		name:  name,
+	}, nil
+}
+
+// references returns the references tagged in the store, excluding the
+// digests.
+func (s *Store) references() []string {
+	s.sync.RLock()
+	defer s.sync.RUnlock()
+
+	var refs []string
+	for ref, desc := range s.tagResolver.Map() {
+		if ref != desc.Digest.String() {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// referencesOf returns the references associated with desc, excluding the
+// digest.
+func (s *Store) referencesOf(desc ocispec.Descriptor) []string {
+	s.sync.RLock()
+	defer s.sync.RUnlock()
+
+	var refs []string
+	for ref := range s.tagResolver.TagSet(desc) {
+		if ref != desc.Digest.String() {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// splitRepositoryTag splits ref in the form of "<repository>:<tag>".
+func splitRepositoryTag(ref string) (string, string, bool) {
+	i := strings.LastIndexByte(ref, ':')
+	if i == -1 {
+		return "", "", false
+	}
+	r := registry.Reference{
+		Repository: ref[:i],
+		Reference:  ref[i+1:],
+	}
+	if r.ValidateRepository() != nil || r.ValidateReferenceAsTag() != nil {
+		return "", "", false
+	}
+	return r.Repository, r.Reference, true
+}
+
+// multiStoreRepository is a repository of MultiStore.
+type multiStoreRepository struct {
+	store *Store
+	// lock is MultiStore.lock.
+	lock *sync.Mutex
+	name string
+}
+
+// Fetch fetches the content identified by the descriptor.
+func (r *multiStoreRepository) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	return r.store.Fetch(ctx, target)
+}
+
+// Push pushes the content, matching the expected descriptor, to the shared
+// pool of blobs.
+func (r *multiStoreRepository) Push(ctx context.Context, expected ocispec.Descriptor, content io.Reader) error {
+	return r.store.Push(ctx, expected, content)
+}
+
+// Exists returns true if the described content exists.
+func (r *multiStoreRepository) Exists(ctx context.Context, target ocispec.Descriptor) (bool, error) {
+	return r.store.Exists(ctx, target)
+}
+
+// Delete removes the tags of the repository associated with the descriptor,
+// and deletes the content from the shared pool of blobs if it is neither
+// tagged in other repositories nor referenced by other manifests.
+//
+// If the content is still tagged in other repositories or referenced by other
+// manifests, Delete only removes the tags of the repository and returns nil,
+// and the content remains in the shared pool of blobs, which can be checked by
+// Exists.
+func (r *multiStoreRepository) Delete(ctx context.Context, target ocispec.Descriptor) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	exists, err := r.store.Exists(ctx, target)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s: %s: %w", target.Digest, target.MediaType, errdef.ErrNotFound)
+	}
+
+	for _, ref := range r.store.referencesOf(target) {
+		if repo, _, ok := splitRepositoryTag(ref); ok && repo == r.name {
+			if err := r.store.Untag(ctx, ref); err != nil {
+				return err
+			}
+		}
+	}
+
+	inUse, err := r.inUse(ctx, target)
+	if err != nil || inUse {
+		return err
+	}
+	return r.store.Delete(ctx, target)
+}
+
+// inUse returns true if desc is tagged or referenced by other manifests other
+// than its referrers, which are deleted along with desc.
+func (r *multiStoreRepository) inUse(ctx context.Context, desc ocispec.Descriptor) (bool, error) {
+	if len(r.store.referencesOf(desc)) > 0 {
+		return true, nil
+	}
+	predecessors, err := r.store.Predecessors(ctx, desc)
+	if err != nil || len(predecessors) == 0 {
+		return false, err
+	}
+	if !descriptor.IsManifest(desc) {
+		return true, nil
+	}
+	referrers, err := registry.Referrers(ctx, r.store, desc, "")
+	if err != nil {
+		return false, err
+	}
+	referrerSet := set.New[descriptor.Descriptor]()
+	for _, referrer := range referrers {
+		referrerSet.Add(descriptor.FromOCI(referrer))
+	}
+	for _, predecessor := range predecessors {
+		if !referrerSet.Contains(descriptor.FromOCI(predecessor)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Tag tags a manifest descriptor with a tag of the repository.
+func (r *multiStoreRepository) Tag(ctx context.Context, desc ocispec.Descriptor, reference string) error {
+	ref, err := r.parseTag(reference)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.store.Tag(ctx, desc, ref)
+}
+
+// Resolve resolves a tag of the repository or a digest to a descriptor.
+func (r *multiStoreRepository) Resolve(ctx context.Context, reference string) (ocispec.Descriptor, error) {
+	ref, err := r.parseReference(reference)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return r.store.Resolve(ctx, ref)
+}
+
+// FetchReference fetches the content identified by a tag of the repository
+// or a digest.
+func (r *multiStoreRepository) FetchReference(ctx context.Context, reference string) (ocispec.Descriptor, io.ReadCloser, error) {
+	desc, err := r.Resolve(ctx, reference)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	rc, err := r.store.Fetch(ctx, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	return desc, rc, nil
+}
+
+// PushReference pushes the manifest, and tags it with a tag of the
+// repository. The manifest is tagged if it already exists.
+func (r *multiStoreRepository) PushReference(ctx context.Context, expected ocispec.Descriptor, content io.Reader, reference string) error {
+	ref, err := r.parseTag(reference)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.store.Push(ctx, expected, content); err != nil && !errors.Is(err, errdef.ErrAlreadyExists) {
+		return err
+	}
+	return r.store.Tag(ctx, expected, ref)
+}
+
+// Referrers lists the descriptors of the image or artifact manifests directly
+// referencing the given manifest descriptor in the shared pool of blobs.
+// If artifactType is not empty, only the referrers of the artifact type are
+// listed.
+//
+// Reference: https://github.com/opencontainers/image-spec/blob/v1.1.1/manifest.md#guidelines-for-artifact-usage
+func (r *multiStoreRepository) Referrers(ctx context.Context, desc ocispec.Descriptor, artifactType string, fn func(referrers []ocispec.Descriptor) error) error {
+	if !descriptor.IsManifest(desc) {
+		return fmt.Errorf("%s: %s: %w", desc.Digest, desc.MediaType, errdef.ErrUnsupported)
+	}
+	referrers, err := registry.Referrers(ctx, r.store, desc, artifactType)
+	if err != nil {
+		return err
+	}
+	return fn(referrers)
+}
+
+// Tags lists the tags of the repository, returned in ascending order.
+// If `last` is NOT empty, the entries in the response start after the tag
+// specified by `last`. Otherwise, the response starts from the top of the tags
+// list.
+//
+// See also `Tags()` in the package `registry`.
+func (r *multiStoreRepository) Tags(ctx context.Context, last string, fn func(tags []string) error) error {
+	var tags []string
+	for _, ref := range r.store.references() {
+		if repo, tag, ok := splitRepositoryTag(ref); ok && repo == r.name && (last == "" || tag > last) {
+			tags = append(tags, tag)
+		}
+	}
+	slices.Sort(tags)
+	return fn(tags)
+}
+
+// Blobs provides access to the blobs, which are shared among the
+// repositories.
+func (r *multiStoreRepository) Blobs() registry.BlobStore {
+	return r
+}
+
+// Manifests provides access to the manifests, which are shared among the
+// repositories.
+func (r *multiStoreRepository) Manifests() registry.ManifestStore {
+	return r
+}
+
+// parseTag validates reference as a tag, and returns the reference in the
+// store.
+func (r *multiStoreRepository) parseTag(reference string) (string, error) {
+	if reference == "" {
+		return "", errdef.ErrMissingReference
+	}
+	ref := registry.Reference{
+		Repository: r.name,
+		Reference:  reference,
+	}
+	if err := ref.ValidateReferenceAsTag(); err != nil {
+		return "", err
+	}
+	return r.name + ":" + reference, nil
+}
+
+// parseReference validates reference as a tag or a digest, and returns the
+// reference in the store.
+func (r *multiStoreRepository) parseReference(reference string) (string, error) {
+	if reference == "" {
+		return "", errdef.ErrMissingReference
+	}
+	ref := registry.Reference{
+		Repository: r.name,
+		Reference:  reference,
+	}
+	if err := ref.ValidateReference(); err != nil {
+		return "", err
+	}
+	if _, err := ref.Digest(); err == nil {
+		return reference, nil
+	}
+	return r.name + ":" + reference, nil
+}
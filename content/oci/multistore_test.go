@@ -0,0 +1,272 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
+)
+
+func TestMultiStoreInterface(t *testing.T) {
+	var store interface{} = &MultiStore{}
+	if _, ok := store.(registry.Registry); !ok {
+		t.Error("&MultiStore{} does not conform registry.Registry")
+	}
+	var repo interface{} = &multiStoreRepository{}
+	if _, ok := repo.(registry.Repository); !ok {
+		t.Error("&multiStoreRepository{} does not conform registry.Repository")
+	}
+}
+
+func TestMultiStore(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	m, err := NewMultiStore(ctx, tempDir)
+	if err != nil {
+		t.Fatal("NewMultiStore() error =", err)
+	}
+	s := m.Store()
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	manifest := pushManifest(t, s, nil, config, layer)
+	other := pushManifest(t, s, nil, config)
+	referrer := pushManifest(t, s, &manifest, config)
+	// plain tags are not listed in any repository
+	if err := s.Tag(ctx, manifest, "latest"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+
+	foo, err := m.Repository(ctx, "library/foo")
+	if err != nil {
+		t.Fatal("MultiStore.Repository() error =", err)
+	}
+	bar, err := m.Repository(ctx, "bar")
+	if err != nil {
+		t.Fatal("MultiStore.Repository() error =", err)
+	}
+	if err := foo.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatal("Repository.Tag() error =", err)
+	}
+	if err := foo.Tag(ctx, other, "v2"); err != nil {
+		t.Fatal("Repository.Tag() error =", err)
+	}
+	if _, err := oras.Copy(ctx, foo, "v1", bar, "v1", oras.DefaultCopyOptions); err != nil {
+		t.Fatal("oras.Copy() error =", err)
+	}
+
+	// list repositories and tags
+	repos, err := registry.Repositories(ctx, m)
+	if err != nil {
+		t.Fatal("registry.Repositories() error =", err)
+	}
+	if want := []string{"bar", "library/foo"}; !reflect.DeepEqual(repos, want) {
+		t.Errorf("registry.Repositories() = %v, want %v", repos, want)
+	}
+	if err := m.Repositories(ctx, "bar", func(got []string) error {
+		if want := []string{"library/foo"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("MultiStore.Repositories() = %v, want %v", got, want)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("MultiStore.Repositories() error =", err)
+	}
+	tags, err := registry.Tags(ctx, foo)
+	if err != nil {
+		t.Fatal("registry.Tags() error =", err)
+	}
+	if want := []string{"v1", "v2"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("registry.Tags() = %v, want %v", tags, want)
+	}
+
+	// resolve per repository
+	got, err := bar.Resolve(ctx, "v1")
+	if err != nil {
+		t.Fatal("Repository.Resolve() error =", err)
+	}
+	if !content.Equal(got, manifest) {
+		t.Errorf("Repository.Resolve() = %v, want %v", got, manifest)
+	}
+	if _, err := bar.Resolve(ctx, "v2"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Repository.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if _, err := bar.Resolve(ctx, "latest"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Repository.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	desc, rc, err := bar.FetchReference(ctx, other.Digest.String())
+	if err != nil {
+		t.Fatal("Repository.FetchReference() error =", err)
+	}
+	rc.Close()
+	if !content.Equal(desc, other) {
+		t.Errorf("Repository.FetchReference() = %v, want %v", desc, other)
+	}
+
+	// referrers are shared
+	var referrers []ocispec.Descriptor
+	if err := bar.Referrers(ctx, manifest, "", func(got []ocispec.Descriptor) error {
+		referrers = append(referrers, got...)
+		return nil
+	}); err != nil {
+		t.Fatal("Repository.Referrers() error =", err)
+	}
+	if len(referrers) != 1 || referrers[0].Digest != referrer.Digest {
+		t.Errorf("Repository.Referrers() = %v, want %v", referrers, referrer)
+	}
+
+	// the manifest is kept while tagged in other repositories
+	if err := foo.Delete(ctx, manifest); err != nil {
+		t.Fatal("Repository.Delete() error =", err)
+	}
+	if _, err := foo.Resolve(ctx, "v1"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Repository.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if _, err := bar.Resolve(ctx, "v1"); err != nil {
+		t.Errorf("Repository.Resolve() error = %v", err)
+	}
+	if err := s.Untag(ctx, "latest"); err != nil {
+		t.Fatal("Store.Untag() error =", err)
+	}
+	if err := bar.Delete(ctx, manifest); err != nil {
+		t.Fatal("Repository.Delete() error =", err)
+	}
+	for _, desc := range []ocispec.Descriptor{manifest, layer, referrer} {
+		if exists, err := s.Exists(ctx, desc); err != nil || exists {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", desc.Digest, exists, err, false)
+		}
+	}
+	// the config is referenced by the other manifest
+	if exists, err := s.Exists(ctx, config); err != nil || !exists {
+		t.Errorf("Store.Exists(%s) = %v, %v, want %v", config.Digest, exists, err, true)
+	}
+	repos, err = registry.Repositories(ctx, m)
+	if err != nil {
+		t.Fatal("registry.Repositories() error =", err)
+	}
+	if want := []string{"library/foo"}; !reflect.DeepEqual(repos, want) {
+		t.Errorf("registry.Repositories() = %v, want %v", repos, want)
+	}
+
+	// the tags are persisted
+	m, err = NewMultiStore(ctx, tempDir)
+	if err != nil {
+		t.Fatal("NewMultiStore() error =", err)
+	}
+	foo, err = m.Repository(ctx, "library/foo")
+	if err != nil {
+		t.Fatal("MultiStore.Repository() error =", err)
+	}
+	if got, err := foo.Resolve(ctx, "v2"); err != nil || !content.Equal(got, other) {
+		t.Errorf("Repository.Resolve() = %v, %v, want %v", got, err, other)
+	}
+}
+
+func TestMultiStore_ConcurrentDeleteAndTag(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewMultiStore(ctx, t.TempDir())
+	if err != nil {
+		t.Fatal("NewMultiStore() error =", err)
+	}
+	s := m.Store()
+	foo, err := m.Repository(ctx, "foo")
+	if err != nil {
+		t.Fatal("MultiStore.Repository() error =", err)
+	}
+	bar, err := m.Repository(ctx, "bar")
+	if err != nil {
+		t.Fatal("MultiStore.Repository() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	if err := s.Tag(ctx, config, "config"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		manifest := pushManifest(t, s, nil, config, pushBlob(t, s, "test/layer", []byte(fmt.Sprintf("layer%d", i))))
+		if err := foo.Tag(ctx, manifest, "v1"); err != nil {
+			t.Fatal("Repository.Tag() error =", err)
+		}
+		var deleteErr, tagErr error
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			deleteErr = foo.Delete(ctx, manifest)
+		}()
+		go func() {
+			defer wg.Done()
+			tagErr = bar.Tag(ctx, manifest, "v1")
+		}()
+		wg.Wait()
+		if deleteErr != nil {
+			t.Fatal("Repository.Delete() error =", deleteErr)
+		}
+		if tagErr != nil {
+			if !errors.Is(tagErr, errdef.ErrNotFound) {
+				t.Fatalf("Repository.Tag() error = %v, wantErr %v", tagErr, errdef.ErrNotFound)
+			}
+			continue
+		}
+		// the manifest tagged is never deleted
+		if exists, err := s.Exists(ctx, manifest); err != nil || !exists {
+			t.Fatalf("Store.Exists(%s) = %v, %v, want %v", manifest.Digest, exists, err, true)
+		}
+		if err := bar.Delete(ctx, manifest); err != nil {
+			t.Fatal("Repository.Delete() error =", err)
+		}
+	}
+}
+
+func TestMultiStore_Error(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewMultiStore(ctx, t.TempDir())
+	if err != nil {
+		t.Fatal("NewMultiStore() error =", err)
+	}
+	if _, err := m.Repository(ctx, "Invalid:Name"); !errors.Is(err, errdef.ErrInvalidReference) {
+		t.Errorf("MultiStore.Repository() error = %v, wantErr %v", err, errdef.ErrInvalidReference)
+	}
+	repo, err := m.Repository(ctx, "foo")
+	if err != nil {
+		t.Fatal("MultiStore.Repository() error =", err)
+	}
+	manifest := pushManifest(t, m.Store(), nil, pushBlob(t, m.Store(), "test/config", []byte("config")))
+	if err := repo.Tag(ctx, manifest, "invalid:tag"); !errors.Is(err, errdef.ErrInvalidReference) {
+		t.Errorf("Repository.Tag() error = %v, wantErr %v", err, errdef.ErrInvalidReference)
+	}
+	if err := repo.Tag(ctx, manifest, ""); !errors.Is(err, errdef.ErrMissingReference) {
+		t.Errorf("Repository.Tag() error = %v, wantErr %v", err, errdef.ErrMissingReference)
+	}
+	if _, err := repo.Resolve(ctx, "missing"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Repository.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	missing := content.NewDescriptorFromBytes("test/layer", []byte("missing"))
+	if err := repo.Delete(ctx, missing); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Repository.Delete() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if err := repo.Referrers(ctx, missing, "", func([]ocispec.Descriptor) error { return nil }); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("Repository.Referrers() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+}
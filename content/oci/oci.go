@@ -23,11 +23,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"maps"
+	"math/rand/v2"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/opencontainers/go-digest"
 	specs "github.com/opencontainers/image-spec/specs-go"
@@ -36,6 +42,7 @@ import (
 	"oras.land/oras-go/v2/errdef"
 	"oras.land/oras-go/v2/internal/container/set"
 	"oras.land/oras-go/v2/internal/descriptor"
+	"oras.land/oras-go/v2/internal/fs/filelock"
 	"oras.land/oras-go/v2/internal/graph"
 	"oras.land/oras-go/v2/internal/manifestutil"
 	"oras.land/oras-go/v2/internal/resolver"
@@ -64,22 +71,87 @@ type Store struct {
 	//   - Default value: true.
 	AutoGC bool
 
-	root        string
-	indexPath   string
-	index       *ocispec.Index
+	// MergeIndexOnSave controls if the OCI store will reconcile the changes
+	// made to `index.json` by other processes when saving the index.
+	//   - If MergeIndexOnSave is set to true, the OCI store re-reads
+	//     `index.json` before saving, and only applies the references tagged,
+	//     untagged or deleted by itself since the last load or save. References
+	//     changed by others are kept and loaded into the store.
+	//   - If MergeIndexOnSave is set to false, the OCI store overwrites
+	//     `index.json` with its own view of the references.
+	//   - Merging takes the exclusive lock of the store, blocking the
+	//     concurrent operations such as Fetch and Resolve.
+	//   - Default value: false.
+	MergeIndexOnSave bool
+
+	root          string
+	indexPath     string
+	indexLockPath string
+	index         *ocispec.Index
+	// savedRefs is the snapshot of the references as of the last load or save
+	// of the index, which is used as the base when merging the index.
+	savedRefs map[string]ocispec.Descriptor
+	// indexDigest is the digest of `index.json` as of the last load or save.
+	indexDigest digest.Digest
 	storage     *Storage
 	tagResolver *resolver.Memory
 	graph       *graph.Memory
 
+	// persistGraph indicates whether the graph is persisted in the graph
+	// index file at graphIndexPath.
+	persistGraph   bool
+	graphIndexPath string
+	// graphLock guards graphLoaded, and ensures that the graph is loaded once.
+	graphLock   sync.Mutex
+	graphLoaded bool
+
 	// sync ensures that most operations can be done concurrently, while Delete
 	// has the exclusive access to Store if a delete operation is underway.
 	// Operations such as Fetch, Push use sync.RLock(), while Delete uses
 	// sync.Lock().
 	sync sync.RWMutex
 	// indexLock ensures that only one go-routine is writing to the index.
+	// Across processes, the index is protected by the advisory lock of the
+	// file at indexLockPath.
 	indexLock sync.Mutex
 }
 
+// indexLockFile is the name of the advisory lock file guarding the updates of
+// `index.json` among processes.
+//
+// The lock file, as well as the temporary files named `index.json.*.tmp` while
+// `index.json` is being saved, are created under the root of the layout. They
+// are not part of the OCI image layout, and are ignored by Verify and by the
+// OCI stores reading the layout.
+const indexLockFile = ocispec.ImageIndexFile + ".lock"
+
+// StoreOptions contains parameters for NewWithOptions.
+type StoreOptions struct {
+	// PersistGraphIndex controls if the OCI store persists the graph of the
+	// manifests, which is used by Predecessors, Delete and GC, in the
+	// `index.graph.json` file under the root of the layout. This file is not
+	// part of the OCI image layout.
+	//   - If PersistGraphIndex is set to true, only `index.json` is read on
+	//     opening the store, and the graph is loaded lazily on the first use
+	//     from the graph index file, if the file is built for the current
+	//     `index.json`. Otherwise, the graph is rebuilt by parsing all the
+	//     manifests reachable from `index.json`, and the file is rewritten.
+	//     The file is updated whenever `index.json` is saved.
+	//   - If PersistGraphIndex is set to false, the graph is built by parsing
+	//     all the manifests reachable from `index.json` on opening the store.
+	//   - Default value: false.
+	PersistGraphIndex bool
+
+	// PeerRoots are the root directories of other OCI layouts, typically on
+	// the same file system, from which the pushed blobs are hard linked or
+	// cloned instead of being written again.
+	// Hard linked blobs keep the modification time of the blobs in the peer
+	// layouts, which affects GCOptions.KeepNewerThan.
+	// See also `StorageOptions.PeerRoots`.
+	//   - Default value: nil.
+	PeerRoots []string
+}
+
 // New creates a new OCI store with context.Background().
 func New(root string) (*Store, error) {
 	return NewWithContext(context.Background(), root)
@@ -87,23 +159,33 @@ func New(root string) (*Store, error) {
 
 // NewWithContext creates a new OCI store.
 func NewWithContext(ctx context.Context, root string) (*Store, error) {
+	return NewWithOptions(ctx, root, StoreOptions{})
+}
+
+// NewWithOptions creates a new OCI store with the given options.
+func NewWithOptions(ctx context.Context, root string, opts StoreOptions) (*Store, error) {
 	rootAbs, err := filepath.Abs(root)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve absolute path for %s: %w", root, err)
 	}
-	storage, err := NewStorage(rootAbs)
+	storage, err := NewStorageWithOptions(rootAbs, StorageOptions{
+		PeerRoots: opts.PeerRoots,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create storage: %w", err)
 	}
 
 	store := &Store{
-		AutoSaveIndex: true,
-		AutoGC:        true,
-		root:          rootAbs,
-		indexPath:     filepath.Join(rootAbs, ocispec.ImageIndexFile),
-		storage:       storage,
-		tagResolver:   resolver.NewMemory(),
-		graph:         graph.NewMemory(),
+		AutoSaveIndex:  true,
+		AutoGC:         true,
+		root:           rootAbs,
+		indexPath:      filepath.Join(rootAbs, ocispec.ImageIndexFile),
+		indexLockPath:  filepath.Join(rootAbs, indexLockFile),
+		storage:        storage,
+		tagResolver:    resolver.NewMemory(),
+		graph:          graph.NewMemory(),
+		persistGraph:   opts.PersistGraphIndex,
+		graphIndexPath: filepath.Join(rootAbs, graphIndexFile),
 	}
 
 	if err := ensureDir(filepath.Join(rootAbs, ocispec.ImageBlobsDir)); err != nil {
@@ -130,7 +212,20 @@ func (s *Store) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadCl
 }
 
 // Push pushes the content, matching the expected descriptor.
+// If the content exists in the peer layouts (see StoreOptions.PeerRoots), it is
+// taken from the peer layouts and reader is not read at all.
 func (s *Store) Push(ctx context.Context, expected ocispec.Descriptor, reader io.Reader) error {
+	if err := s.push(ctx, expected, reader); err != nil {
+		return err
+	}
+	if descriptor.IsManifest(expected) {
+		return s.autoSaveIndex(ctx)
+	}
+	return nil
+}
+
+// push pushes the content, and tags the manifests by digest.
+func (s *Store) push(ctx context.Context, expected ocispec.Descriptor, reader io.Reader) error {
 	s.sync.RLock()
 	defer s.sync.RUnlock()
 
@@ -166,6 +261,9 @@ func (s *Store) Delete(ctx context.Context, target ocispec.Descriptor) error {
 	s.sync.Lock()
 	defer s.sync.Unlock()
 
+	if err := s.ensureGraph(ctx); err != nil {
+		return err
+	}
 	deleteQueue := []ocispec.Descriptor{target}
 	for len(deleteQueue) > 0 {
 		head := deleteQueue[0]
@@ -210,7 +308,7 @@ func (s *Store) delete(ctx context.Context, target ocispec.Descriptor) ([]ocispe
 	}
 	danglings := s.graph.Remove(target)
 	if untagged && s.AutoSaveIndex {
-		err := s.saveIndex()
+		err := s.saveIndex(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -234,12 +332,20 @@ func (s *Store) delete(ctx context.Context, target ocispec.Descriptor) ([]ocispe
 //
 // Reference: https://github.com/opencontainers/image-spec/blob/v1.1.1/image-layout.md#indexjson-file
 func (s *Store) Tag(ctx context.Context, desc ocispec.Descriptor, reference string) error {
-	s.sync.RLock()
-	defer s.sync.RUnlock()
-
 	if err := validateReference(reference); err != nil {
 		return err
 	}
+	if err := s.tagExisting(ctx, desc, reference); err != nil {
+		return err
+	}
+	return s.autoSaveIndex(ctx)
+}
+
+// tagExisting tags a descriptor with a reference string if the described
+// content exists.
+func (s *Store) tagExisting(ctx context.Context, desc ocispec.Descriptor, reference string) error {
+	s.sync.RLock()
+	defer s.sync.RUnlock()
 
 	exists, err := s.storage.Exists(ctx, desc)
 	if err != nil {
@@ -261,13 +367,7 @@ func (s *Store) tag(ctx context.Context, desc ocispec.Descriptor, reference stri
 			return err
 		}
 	}
-	if err := s.tagResolver.Tag(ctx, desc, reference); err != nil {
-		return err
-	}
-	if s.AutoSaveIndex {
-		return s.saveIndex()
-	}
-	return nil
+	return s.tagResolver.Tag(ctx, desc, reference)
 }
 
 // Resolve resolves a reference to a descriptor.
@@ -311,7 +411,15 @@ func (s *Store) Untag(ctx context.Context, reference string) error {
 	if reference == "" {
 		return errdef.ErrMissingReference
 	}
+	if err := s.untag(ctx, reference); err != nil {
+		return err
+	}
+	return s.autoSaveIndex(ctx)
+}
 
+// untag disassociates a reference string, which must not be a digest, from its
+// descriptor.
+func (s *Store) untag(ctx context.Context, reference string) error {
 	s.sync.RLock()
 	defer s.sync.RUnlock()
 
@@ -324,9 +432,6 @@ func (s *Store) Untag(ctx context.Context, reference string) error {
 	}
 
 	s.tagResolver.Untag(reference)
-	if s.AutoSaveIndex {
-		return s.saveIndex()
-	}
 	return nil
 }
 
@@ -337,6 +442,9 @@ func (s *Store) Predecessors(ctx context.Context, node ocispec.Descriptor) ([]oc
 	s.sync.RLock()
 	defer s.sync.RUnlock()
 
+	if err := s.ensureGraph(ctx); err != nil {
+		return nil, err
+	}
 	return s.graph.Predecessors(ctx, node)
 }
 
@@ -385,30 +493,75 @@ func (s *Store) ensureOCILayoutFile() error {
 // loadIndexFile reads index.json from the file system.
 // Create index.json if it does not exist.
 func (s *Store) loadIndexFile(ctx context.Context) error {
-	indexFile, err := os.Open(s.indexPath)
+	index, dgst, err := readIndexFile(s.indexPath)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("failed to open index file: %w", err)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if index, err = s.createIndexFile(); err != nil {
+			return err
 		}
+	} else {
+		s.indexDigest = dgst
+	}
+	s.index = index
+	graph := s.graph
+	if s.persistGraph {
+		// the graph is loaded lazily
+		graph = nil
+	}
+	if err := loadIndex(ctx, s.index, s.storage, s.tagResolver, graph); err != nil {
+		return err
+	}
+	s.graphLoaded = !s.persistGraph
+	s.savedRefs = s.tagResolver.Map()
+	return nil
+}
+
+// createIndexFile writes an empty index.json if it does not exist, and returns
+// the index in the file.
+func (s *Store) createIndexFile() (*ocispec.Index, error) {
+	unlock, err := s.lockIndexFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// index.json may have been created by another process
+	index, dgst, err := readIndexFile(s.indexPath)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		s.indexDigest = dgst
+		return index, err
+	}
+	index = &ocispec.Index{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2, // historical value
+		},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{},
+	}
+	if err := s.writeIndexFile(index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
 
-		// write index.json if it does not exist
-		s.index = &ocispec.Index{
-			Versioned: specs.Versioned{
-				SchemaVersion: 2, // historical value
-			},
-			MediaType: ocispec.MediaTypeImageIndex,
-			Manifests: []ocispec.Descriptor{},
+// readIndexFile reads and decodes the index file at the given path, and
+// returns the digest of the file.
+func readIndexFile(path string) (*ocispec.Index, digest.Digest, error) {
+	indexJSON, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, "", err
 		}
-		return s.writeIndexFile()
+		return nil, "", fmt.Errorf("failed to read index file: %w", err)
 	}
-	defer indexFile.Close()
 
 	var index ocispec.Index
-	if err := json.NewDecoder(indexFile).Decode(&index); err != nil {
-		return fmt.Errorf("failed to decode index file: %w", err)
+	if err := json.Unmarshal(indexJSON, &index); err != nil {
+		return nil, "", fmt.Errorf("failed to decode index file: %w", err)
 	}
-	s.index = &index
-	return loadIndex(ctx, s.index, s.storage, s.tagResolver, s.graph)
+	return &index, digest.FromBytes(indexJSON), nil
 }
 
 // SaveIndex writes the `index.json` file to the file system.
@@ -417,20 +570,63 @@ func (s *Store) loadIndexFile(ctx context.Context) error {
 //     on Tag() and Delete() calls, and when pushing a manifest.
 //   - If AutoSaveIndex is set to false, it's the caller's responsibility
 //     to manually call this method when needed.
+//   - If MergeIndexOnSave is set to true, the changes made to `index.json` by
+//     other processes are merged instead of being overwritten.
 func (s *Store) SaveIndex() error {
-	s.sync.RLock()
-	defer s.sync.RUnlock()
+	return s.saveIndexWithLock(context.Background())
+}
 
-	return s.saveIndex()
+// autoSaveIndex saves the index if AutoSaveIndex is set to true.
+// The caller must not hold s.sync.
+func (s *Store) autoSaveIndex(ctx context.Context) error {
+	if !s.AutoSaveIndex {
+		return nil
+	}
+	return s.saveIndexWithLock(ctx)
 }
 
-func (s *Store) saveIndex() error {
+// saveIndexWithLock saves the index while holding s.sync. As merging the index
+// modifies the references and the graph of the store, the exclusive lock is
+// taken if MergeIndexOnSave is set to true, so that the readers never observe
+// a partially merged index.
+// The caller must not hold s.sync.
+func (s *Store) saveIndexWithLock(ctx context.Context) error {
+	if s.MergeIndexOnSave {
+		s.sync.Lock()
+		defer s.sync.Unlock()
+	} else {
+		s.sync.RLock()
+		defer s.sync.RUnlock()
+	}
+	return s.saveIndex(ctx)
+}
+
+// saveIndex writes the `index.json` file. The caller must hold s.sync, and
+// must hold it exclusively if MergeIndexOnSave is set to true.
+func (s *Store) saveIndex(ctx context.Context) error {
+	// the graph is persisted along with the index
+	if err := s.ensureGraph(ctx); err != nil {
+		return err
+	}
+
 	s.indexLock.Lock()
 	defer s.indexLock.Unlock()
 
+	unlock, err := s.lockIndexFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	refMap := s.tagResolver.Map()
+	if s.MergeIndexOnSave {
+		if refMap, err = s.mergeIndex(ctx, refMap); err != nil {
+			return err
+		}
+	}
+
 	var manifests []ocispec.Descriptor
 	tagged := set.New[digest.Digest]()
-	refMap := s.tagResolver.Map()
 
 	// 1. Add descriptors that are associated with tags
 	// Note: One descriptor can be associated with multiple tags.
@@ -454,16 +650,200 @@ func (s *Store) saveIndex() error {
 	}
 
 	s.index.Manifests = manifests
-	return s.writeIndexFile()
+	if err := s.writeIndexFile(s.index); err != nil {
+		return err
+	}
+	s.savedRefs = refMap
+	if s.persistGraph {
+		return s.writeGraphIndexFile()
+	}
+	return nil
+}
+
+// mergeIndex merges the references in local with the ones in the `index.json`
+// file on the file system, and returns the merged references.
+// The references tagged, untagged or deleted locally since the last load or
+// save take precedence, while the others are taken from the file. The merged
+// references are loaded into the store.
+func (s *Store) mergeIndex(ctx context.Context, local map[string]ocispec.Descriptor) (map[string]ocispec.Descriptor, error) {
+	merged := make(map[string]ocispec.Descriptor)
+	index, _, err := readIndexFile(s.indexPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	} else {
+		for _, desc := range index.Manifests {
+			merged[desc.Digest.String()] = deleteAnnotationRefName(desc)
+			if ref := desc.Annotations[ocispec.AnnotationRefName]; ref != "" {
+				merged[ref] = desc
+			}
+		}
+	}
+
+	// apply local changes
+	for ref, desc := range local {
+		if base, ok := s.savedRefs[ref]; !ok || !content.Equal(base, desc) {
+			merged[ref] = desc
+		}
+	}
+	for ref := range s.savedRefs {
+		if _, ok := local[ref]; !ok {
+			delete(merged, ref)
+		}
+	}
+	// ensure that tagged manifests are also referenced by digest
+	for ref, desc := range merged {
+		if dgst := desc.Digest.String(); ref != dgst {
+			if _, ok := merged[dgst]; !ok {
+				merged[dgst] = deleteAnnotationRefName(desc)
+			}
+		}
+	}
+
+	// load changes made by others into the store
+	for ref, desc := range local {
+		if _, ok := merged[ref]; !ok {
+			s.tagResolver.Untag(ref)
+			if ref == desc.Digest.String() {
+				// the manifest is deleted by others
+				s.graph.Remove(desc)
+			}
+		}
+	}
+	for ref, desc := range merged {
+		if current, ok := local[ref]; ok && content.Equal(current, desc) {
+			continue
+		}
+		s.tagResolver.Untag(ref)
+		if err := s.tagResolver.Tag(ctx, desc, ref); err != nil {
+			return nil, err
+		}
+		if err := s.graph.IndexAll(ctx, s.storage, descriptor.Plain(desc)); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
 }
 
-// writeIndexFile writes the `index.json` file.
-func (s *Store) writeIndexFile() error {
-	indexJSON, err := json.Marshal(s.index)
+// writeIndexFile writes the `index.json` file atomically.
+func (s *Store) writeIndexFile(index *ocispec.Index) error {
+	indexJSON, err := json.Marshal(index)
 	if err != nil {
 		return fmt.Errorf("failed to marshal index file: %w", err)
 	}
-	return os.WriteFile(s.indexPath, indexJSON, 0666)
+	if err := writeFileAtomic(s.indexPath, indexJSON); err != nil {
+		return fmt.Errorf("failed to write index file: %w", err)
+	}
+	s.indexDigest = digest.FromBytes(indexJSON)
+	return nil
+}
+
+// writeFileAtomic writes data to the file at path atomically by writing to a
+// temporary file in the same directory and renaming it.
+// The permissions of the existing file are preserved. Otherwise, the file is
+// created with the mode 0666 before umask, like os.WriteFile.
+func writeFileAtomic(path string, data []byte) (writeErr error) {
+	fp, err := createTempFile(path)
+	if err != nil {
+		return err
+	}
+	tempPath := fp.Name()
+	defer func() {
+		if writeErr != nil {
+			os.Remove(tempPath)
+		}
+	}()
+	if _, err := fp.Write(data); err != nil {
+		fp.Close()
+		return err
+	}
+	if err := fp.Close(); err != nil {
+		return err
+	}
+	if fi, err := os.Stat(path); err == nil {
+		if err := os.Chmod(tempPath, fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tempPath, path)
+}
+
+// createTempFile creates a new temporary file named "<path>.<random>.tmp" with
+// the mode 0666 before umask. Unlike os.CreateTemp, which always uses the mode
+// 0600, the file is accessible as if created by os.WriteFile.
+func createTempFile(path string) (*os.File, error) {
+	for try := 0; ; try++ {
+		name := path + "." + strconv.FormatUint(rand.Uint64(), 36) + ".tmp"
+		fp, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if err == nil || !errors.Is(err, fs.ErrExist) || try >= 10000 {
+			return fp, err
+		}
+	}
+}
+
+// lockIndexFile acquires the advisory lock guarding the updates of
+// `index.json` among processes. The returned function releases the lock.
+// If file locking is not supported on the platform, only the in-process
+// locks are effective.
+func (s *Store) lockIndexFile() (func() error, error) {
+	unlock, err := filelock.Lock(s.indexLockPath)
+	if err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return func() error { return nil }, nil
+		}
+		return nil, fmt.Errorf("failed to lock index file: %w", err)
+	}
+	return unlock, nil
+}
+
+// GCOptions contains parameters for Store.GCWithOptions.
+type GCOptions struct {
+	// DryRun, if set to true, reports the garbage without removing it.
+	DryRun bool
+
+	// KeepUntagged is the number of the most recent untagged manifests in the
+	// index to be kept along with their successors. The recency is determined
+	// by the modification time of the manifest blobs.
+	KeepUntagged int
+
+	// KeepNewerThan, if positive, keeps the blobs modified within the
+	// duration. Untagged manifests in the index modified within the duration
+	// are kept along with their successors.
+	//
+	// Blobs hard linked from peer layouts (see StoreOptions.PeerRoots) share
+	// the modification time of the blobs in the peer layouts, and may be
+	// collected even if they are pushed within the duration.
+	KeepNewerThan time.Duration
+
+	// Pinned are the digests of the content to be kept. Pinned manifests are
+	// kept along with their successors.
+	Pinned []digest.Digest
+
+	// PruneIndex, if set to true, saves `index.json` without the untagged
+	// manifests collected. Otherwise, the collected manifests are only removed
+	// from the index in memory, as GC does, and are removed from `index.json`
+	// on the next save.
+	PruneIndex bool
+
+	// StaleIngestAge, if positive, is the age after which the temporary files
+	// in the ingest directory, typically left by crashed pushes, are removed.
+	// If zero, the ingest files are not removed.
+	StaleIngestAge time.Duration
+}
+
+// GCReport is the result of Store.GCWithOptions.
+type GCReport struct {
+	// Blobs are the blobs removed, or to be removed on dry-run.
+	Blobs []ocispec.Descriptor
+	// IngestFiles are the slash-separated paths, relative to the root of the
+	// store, of the stale ingest files removed, or to be removed on dry-run.
+	IngestFiles []string
+	// ReclaimedBytes is the total size of the blobs and the ingest files
+	// removed, or to be removed on dry-run. On Unix, blobs hard linked
+	// elsewhere, such as the ones linked from peer layouts, are not counted
+	// as their space is not reclaimed.
+	ReclaimedBytes int64
 }
 
 // GC removes garbage from Store. Unsaved index will be lost. To prevent unexpected
@@ -471,22 +851,90 @@ func (s *Store) writeIndexFile() error {
 // The garbage to be cleaned are:
 //   - unreferenced (dangling) blobs in Store which have no predecessors
 //   - garbage blobs in the storage whose metadata is not stored in Store
+//
+// The manifests tagged in `index.json` by other processes since the last load
+// or save are kept.
+//
+// See also GCWithOptions.
 func (s *Store) GC(ctx context.Context) error {
+	_, err := s.GCWithOptions(ctx, GCOptions{})
+	return err
+}
+
+// GCWithOptions removes garbage from Store as GC does, subject to the
+// retention policies in opts, and reports the garbage removed. If
+// opts.DryRun is set to true, the garbage is reported without being removed.
+//
+// Tagged manifests, manifests retained by the policies, and the referrers of
+// the retained manifests are kept along with their successors. Untagged
+// manifests not retained are removed from the index in memory, and from
+// `index.json` if opts.PruneIndex is set to true.
+func (s *Store) GCWithOptions(ctx context.Context, opts GCOptions) (*GCReport, error) {
 	s.sync.Lock()
 	defer s.sync.Unlock()
 
+	if err := s.ensureGraph(ctx); err != nil {
+		return nil, err
+	}
+	refMap := s.tagResolver.Map()
+	report, tagResolver, graph, err := s.gc(ctx, &opts, refMap)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	s.tagResolver = tagResolver
+	s.graph = graph
+	if opts.PruneIndex && len(tagResolver.Map()) != len(refMap) {
+		if err := s.saveIndex(ctx); err != nil {
+			return nil, err
+		}
+	} else if s.persistGraph {
+		// keep the graph index file in sync with the collected graph, even if
+		// `index.json` is not pruned
+		if err := s.saveGraphIndex(); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// gc collects the garbage blobs and the stale ingest files, and returns the
+// updated metadata. The updates of `index.json` by other processes are blocked
+// until the collection completes.
+func (s *Store) gc(ctx context.Context, opts *GCOptions, refMap map[string]ocispec.Descriptor) (*GCReport, *resolver.Memory, *graph.Memory, error) {
+	s.indexLock.Lock()
+	defer s.indexLock.Unlock()
+
+	unlock, err := s.lockIndexFile()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer unlock()
+
 	// get reachable nodes by reloading the index
-	err := s.gcIndex(ctx)
+	external, err := s.externalManifests()
 	if err != nil {
-		return fmt.Errorf("unable to reload index: %w", err)
+		return nil, nil, nil, fmt.Errorf("unable to reload index: %w", err)
+	}
+	now := time.Now()
+	tagResolver, graph, err := s.gcIndex(ctx, opts, now, refMap, external)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to reload index: %w", err)
+	}
+	reachableNodes := graph.DigestSet()
+	for _, dgst := range opts.Pinned {
+		reachableNodes.Add(dgst)
 	}
-	reachableNodes := s.graph.DigestSet()
 
 	// clean up garbage blobs in the storage
+	report := &GCReport{}
 	rootpath := filepath.Join(s.root, ocispec.ImageBlobsDir)
 	algDirs, err := os.ReadDir(rootpath)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 	for _, algDir := range algDirs {
 		if !algDir.IsDir() {
@@ -500,11 +948,11 @@ func (s *Store) GC(ctx context.Context) error {
 		algPath := path.Join(rootpath, alg)
 		digestEntries, err := os.ReadDir(algPath)
 		if err != nil {
-			return err
+			return nil, nil, nil, err
 		}
 		for _, digestEntry := range digestEntries {
 			if err := isContextDone(ctx); err != nil {
-				return err
+				return nil, nil, nil, err
 			}
 			dgst := digestEntry.Name()
 			blobDigest := digest.NewDigestFromEncoded(digest.Algorithm(alg), dgst)
@@ -512,74 +960,288 @@ func (s *Store) GC(ctx context.Context) error {
 				// skip irrelevant content
 				continue
 			}
-			if !reachableNodes.Contains(blobDigest) {
+			if reachableNodes.Contains(blobDigest) {
+				continue
+			}
+			info, err := digestEntry.Info()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if opts.KeepNewerThan > 0 && now.Sub(info.ModTime()) < opts.KeepNewerThan {
+				continue
+			}
+			desc, ok := refMap[blobDigest.String()]
+			if ok {
+				desc = descriptor.Plain(desc)
+			} else {
+				desc = ocispec.Descriptor{
+					MediaType: descriptor.DefaultMediaType,
+					Digest:    blobDigest,
+					Size:      info.Size(),
+				}
+			}
+			report.Blobs = append(report.Blobs, desc)
+			if !isHardLinked(info) {
+				report.ReclaimedBytes += info.Size()
+			}
+			if !opts.DryRun {
 				// remove the blob from storage if it does not exist in Store
 				err = os.Remove(path.Join(algPath, dgst))
 				if err != nil {
-					return err
+					return nil, nil, nil, err
 				}
 			}
 		}
 	}
+
+	// clean up stale ingest files
+	if opts.StaleIngestAge > 0 {
+		if err := s.gcIngest(opts, now, report); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return report, tagResolver, graph, nil
+}
+
+// externalManifests returns the manifests in the `index.json` file on the file
+// system which are added or re-tagged by other processes since the last load or
+// save. The caller must hold the lock of the index file.
+func (s *Store) externalManifests() ([]ocispec.Descriptor, error) {
+	index, dgst, err := readIndexFile(s.indexPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if dgst == s.indexDigest {
+		return nil, nil
+	}
+	var manifests []ocispec.Descriptor
+	for _, desc := range index.Manifests {
+		ref := desc.Annotations[ocispec.AnnotationRefName]
+		if ref == "" {
+			ref = desc.Digest.String()
+		}
+		if base, ok := s.savedRefs[ref]; !ok || !content.Equal(base, desc) {
+			manifests = append(manifests, descriptor.Plain(desc))
+		}
+	}
+	return manifests, nil
+}
+
+// gcIngest removes the stale files in the ingest directory.
+func (s *Store) gcIngest(opts *GCOptions, now time.Time, report *GCReport) error {
+	entries, err := os.ReadDir(s.storage.ingestRoot)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// the ingest file is moved into place
+				continue
+			}
+			return err
+		}
+		if now.Sub(info.ModTime()) < opts.StaleIngestAge {
+			continue
+		}
+		report.IngestFiles = append(report.IngestFiles, path.Join(ingestDir, entry.Name()))
+		report.ReclaimedBytes += info.Size()
+		if !opts.DryRun {
+			if err := os.Remove(filepath.Join(s.storage.ingestRoot, entry.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-// gcIndex reloads the index and updates metadata. Information of untagged blobs
-// are cleaned and only tagged blobs remain.
-func (s *Store) gcIndex(ctx context.Context) error {
+// gcIndex reloads the index and returns the updated metadata, where only the
+// tagged manifests, the manifests retained by the policies in opts and their
+// referrers remain. The external manifests, added to `index.json` by other
+// processes, are indexed in the returned graph only, so that they are kept
+// without being loaded into the store.
+func (s *Store) gcIndex(ctx context.Context, opts *GCOptions, now time.Time, refMap map[string]ocispec.Descriptor, external []ocispec.Descriptor) (*resolver.Memory, *graph.Memory, error) {
 	tagResolver := resolver.NewMemory()
 	graph := graph.NewMemory()
 	tagged := set.New[digest.Digest]()
 
+	// index external manifests
+	for _, desc := range external {
+		exists, err := s.storage.Exists(ctx, desc)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !exists {
+			continue
+		}
+		if err := graph.IndexAllFunc(ctx, s.successors, desc); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// index tagged manifests
-	refMap := s.tagResolver.Map()
 	for ref, desc := range refMap {
 		if ref == desc.Digest.String() {
 			continue
 		}
 		if err := tagResolver.Tag(ctx, deleteAnnotationRefName(desc), desc.Digest.String()); err != nil {
-			return err
+			return nil, nil, err
 		}
 		if err := tagResolver.Tag(ctx, desc, ref); err != nil {
-			return err
+			return nil, nil, err
 		}
 		plain := descriptor.Plain(desc)
-		if err := graph.IndexAll(ctx, s.storage, plain); err != nil {
-			return err
+		if err := graph.IndexAllFunc(ctx, s.successors, plain); err != nil {
+			return nil, nil, err
 		}
 		tagged.Add(desc.Digest)
 	}
 
-	// index referrer manifests
+	// index untagged manifests retained by the policies
+	retain := func(desc ocispec.Descriptor) error {
+		if err := tagResolver.Tag(ctx, deleteAnnotationRefName(desc), desc.Digest.String()); err != nil {
+			return err
+		}
+		return graph.IndexAllFunc(ctx, s.successors, descriptor.Plain(desc))
+	}
+	pinned := set.New[digest.Digest]()
+	for _, dgst := range opts.Pinned {
+		pinned.Add(dgst)
+	}
+	var untagged []ocispec.Descriptor
+	modTimes := make(map[digest.Digest]time.Time)
 	for ref, desc := range refMap {
 		if ref != desc.Digest.String() || tagged.Contains(desc.Digest) {
 			continue
 		}
-		// check if the referrers manifest can traverse to the existing graph
-		subject := &desc
-		for {
-			subject, err := manifestutil.Subject(ctx, s.storage, *subject)
-			if err != nil {
-				return err
+		modTime, err := s.blobModTime(desc)
+		if err != nil {
+			return nil, nil, err
+		}
+		modTimes[desc.Digest] = modTime
+		untagged = append(untagged, desc)
+	}
+	slices.SortFunc(untagged, func(a, b ocispec.Descriptor) int {
+		if c := modTimes[b.Digest].Compare(modTimes[a.Digest]); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Digest.String(), b.Digest.String())
+	})
+	for i, desc := range untagged {
+		if i < opts.KeepUntagged || pinned.Contains(desc.Digest) ||
+			(opts.KeepNewerThan > 0 && now.Sub(modTimes[desc.Digest]) < opts.KeepNewerThan) {
+			if err := retain(desc); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	// index pinned manifests not in the index
+	for dgst := range pinned {
+		if graph.Exists(ocispec.Descriptor{Digest: dgst}) {
+			continue
+		}
+		desc, err := s.pinnedManifest(ctx, dgst)
+		if err != nil {
+			return nil, nil, err
+		}
+		if desc != nil {
+			if err := graph.IndexAllFunc(ctx, s.successors, *desc); err != nil {
+				return nil, nil, err
 			}
-			if subject == nil {
-				break
+		}
+	}
+
+	// index referrer manifests of the retained manifests, repeating until no
+	// more referrers are found as referrers can be chained
+	for found := true; found; {
+		found = false
+		for ref, desc := range refMap {
+			if ref != desc.Digest.String() || tagResolver.TagSet(desc).Contains(ref) {
+				continue
 			}
-			if graph.Exists(*subject) {
-				if err := tagResolver.Tag(ctx, deleteAnnotationRefName(desc), desc.Digest.String()); err != nil {
-					return err
-				}
-				plain := descriptor.Plain(desc)
-				if err := graph.IndexAll(ctx, s.storage, plain); err != nil {
-					return err
+			subject, err := manifestutil.Subject(ctx, s.storage, desc)
+			if err != nil {
+				return nil, nil, err
+			}
+			if subject != nil && graph.Exists(*subject) {
+				if err := retain(desc); err != nil {
+					return nil, nil, err
 				}
-				break
+				found = true
 			}
 		}
 	}
-	s.tagResolver = tagResolver
-	s.graph = graph
-	return nil
+	return tagResolver, graph, nil
+}
+
+// blobModTime returns the modification time of the blob described by desc.
+func (s *Store) blobModTime(desc ocispec.Descriptor) (time.Time, error) {
+	blobPath, err := blobPath(desc.Digest)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(filepath.Join(s.root, blobPath))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// pinnedManifest returns the descriptor of the pinned blob identified by dgst
+// if it is a manifest. Otherwise, it returns nil.
+// As "mediaType" is optional in OCI image manifests and indexes, a blob
+// without it is detected by its "config" and "layers" fields, or by its
+// "manifests" field.
+func (s *Store) pinnedManifest(ctx context.Context, dgst digest.Digest) (*ocispec.Descriptor, error) {
+	desc, err := resolveBlob(os.DirFS(s.root), dgst.String())
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	rc, err := s.storage.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var manifest struct {
+		MediaType string          `json:"mediaType"`
+		Config    json.RawMessage `json:"config"`
+		Layers    json.RawMessage `json:"layers"`
+		Manifests json.RawMessage `json:"manifests"`
+	}
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		// not a manifest
+		return nil, nil
+	}
+	desc.MediaType = manifest.MediaType
+	if desc.MediaType == "" {
+		switch {
+		case manifest.Config != nil && manifest.Layers != nil:
+			desc.MediaType = ocispec.MediaTypeImageManifest
+		case manifest.Manifests != nil:
+			desc.MediaType = ocispec.MediaTypeImageIndex
+		}
+	}
+	if !descriptor.IsManifest(desc) {
+		return nil, nil
+	}
+	return &desc, nil
 }
 
 // isTagged checks if the blob given by the descriptor is tagged.
@@ -32,6 +32,7 @@ import (
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -654,7 +655,7 @@ func TestStore_DisableAutoSaveIndex(t *testing.T) {
 	if got, want := len(s.index.Manifests), 0; got != want {
 		t.Errorf("len(index.Manifests) = %v, want %v", got, want)
 	}
-	if err := s.saveIndex(); err != nil {
+	if err := s.saveIndex(ctx); err != nil {
 		t.Fatal("Store.SaveIndex() error =", err)
 	}
 	// test index file again
@@ -676,7 +677,7 @@ func TestStore_DisableAutoSaveIndex(t *testing.T) {
 	if got, want := len(s.index.Manifests), 2; got != want {
 		t.Errorf("len(index.Manifests) = %v, want %v", got, want)
 	}
-	if err := s.saveIndex(); err != nil {
+	if err := s.saveIndex(ctx); err != nil {
 		t.Fatal("Store.SaveIndex() error =", err)
 	}
 	// test index file again
@@ -2538,6 +2539,287 @@ func TestStore_UntagErrorPath(t *testing.T) {
 	}
 }
 
+func TestStore_MergeIndexOnSave(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s1, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	s1.MergeIndexOnSave = true
+	s2, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	s2.MergeIndexOnSave = true
+
+	var descs []ocispec.Descriptor
+	for i := 0; i < 3; i++ {
+		blob := []byte(fmt.Sprintf(`{"layers":[],"i":%d}`, i))
+		desc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageManifest, blob)
+		if err := s1.Push(ctx, desc, bytes.NewReader(blob)); err != nil {
+			t.Fatal("Store.Push() error =", err)
+		}
+		descs = append(descs, desc)
+	}
+	if err := s1.Tag(ctx, descs[0], "foo"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	// s2 has not seen the changes made by s1
+	if err := s2.Tag(ctx, descs[1], "bar"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	// the tag made by s1 is merged into s2
+	if got, err := s2.Resolve(ctx, "foo"); err != nil || !content.Equal(got, descs[0]) {
+		t.Errorf("Store.Resolve(foo) = %v, %v, want %v", got, err, descs[0])
+	}
+	// s1 moves "foo" while s2 untags "bar"
+	if err := s1.Tag(ctx, descs[2], "foo"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	if err := s2.Untag(ctx, "bar"); err != nil {
+		t.Fatal("Store.Untag() error =", err)
+	}
+	if got, err := s2.Resolve(ctx, "foo"); err != nil || !content.Equal(got, descs[2]) {
+		t.Errorf("Store.Resolve(foo) = %v, %v, want %v", got, err, descs[2])
+	}
+
+	s3, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	var tags []string
+	if err := s3.Tags(ctx, "", func(got []string) error {
+		tags = got
+		return nil
+	}); err != nil {
+		t.Fatal("Store.Tags() error =", err)
+	}
+	if want := []string{"foo"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Store.Tags() = %v, want %v", tags, want)
+	}
+	for _, desc := range descs {
+		if _, err := s3.Resolve(ctx, desc.Digest.String()); err != nil {
+			t.Errorf("Store.Resolve(%s) error = %v", desc.Digest, err)
+		}
+	}
+}
+
+func TestStore_MergeIndexOnSave_Delete(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s1, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	s1.MergeIndexOnSave = true
+
+	blob := []byte(`{"layers":[]}`)
+	desc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageManifest, blob)
+	if err := s1.Push(ctx, desc, bytes.NewReader(blob)); err != nil {
+		t.Fatal("Store.Push() error =", err)
+	}
+	s2, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	s2.MergeIndexOnSave = true
+
+	// s1 deletes the manifest, which is then dropped from s2 on save
+	if err := s1.Delete(ctx, desc); err != nil {
+		t.Fatal("Store.Delete() error =", err)
+	}
+	if err := s2.SaveIndex(); err != nil {
+		t.Fatal("Store.SaveIndex() error =", err)
+	}
+	if _, err := s2.Resolve(ctx, desc.Digest.String()); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Store.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	index, _, err := readIndexFile(filepath.Join(tempDir, ocispec.ImageIndexFile))
+	if err != nil {
+		t.Fatal("readIndexFile() error =", err)
+	}
+	if got := len(index.Manifests); got != 0 {
+		t.Errorf("len(index.Manifests) = %v, want %v", got, 0)
+	}
+}
+
+func TestStore_OverwriteIndexOnSave(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s1, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	s2, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+
+	blob := []byte(`{"layers":[]}`)
+	desc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageManifest, blob)
+	if err := s1.Push(ctx, desc, bytes.NewReader(blob)); err != nil {
+		t.Fatal("Store.Push() error =", err)
+	}
+	if err := s1.Tag(ctx, desc, "foo"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	if err := s2.Tag(ctx, desc, "bar"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+
+	s3, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	if _, err := s3.Resolve(ctx, "foo"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Store.Resolve(foo) error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if _, err := s3.Resolve(ctx, "bar"); err != nil {
+		t.Errorf("Store.Resolve(bar) error = %v", err)
+	}
+}
+
+func TestStore_MergeIndexOnSave_Concurrent(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	blob := []byte(`{"layers":[]}`)
+	desc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageManifest, blob)
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	if err := s.Push(ctx, desc, bytes.NewReader(blob)); err != nil {
+		t.Fatal("Store.Push() error =", err)
+	}
+
+	const concurrency = 8
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i := 0; i < concurrency; i++ {
+		eg.Go(func() error {
+			// each store simulates a process sharing the layout
+			s, err := New(tempDir)
+			if err != nil {
+				return err
+			}
+			s.MergeIndexOnSave = true
+			for j := 0; j < 5; j++ {
+				if err := s.Tag(egCtx, desc, fmt.Sprintf("tag-%d-%d", i, j)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		t.Fatal("concurrent Store.Tag() error =", err)
+	}
+
+	s, err = New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	var tags []string
+	if err := s.Tags(ctx, "", func(got []string) error {
+		tags = got
+		return nil
+	}); err != nil {
+		t.Fatal("Store.Tags() error =", err)
+	}
+	if got, want := len(tags), concurrency*5; got != want {
+		t.Errorf("len(Store.Tags()) = %v, want %v", got, want)
+	}
+
+	// no temporary index files are left behind
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal("os.ReadDir() error =", err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("unexpected temporary file %s", entry.Name())
+		}
+	}
+}
+
+func TestStore_MergeIndexOnSave_ConcurrentReaders(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	s.MergeIndexOnSave = true
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	manifest := pushManifest(t, s, nil, config)
+	if err := s.Tag(ctx, manifest, "latest"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+
+	// another process tags a new manifest, which is merged on saving
+	other, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	otherManifest := pushManifest(t, other, nil, config, pushBlob(t, other, "test/layer", []byte("layer")))
+	if err := other.Tag(ctx, otherManifest, "other"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		for i := 0; i < 20; i++ {
+			if err := s.Tag(egCtx, manifest, fmt.Sprintf("tag-%d", i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	for i := 0; i < 4; i++ {
+		eg.Go(func() error {
+			for j := 0; j < 20; j++ {
+				if _, err := s.Resolve(egCtx, "latest"); err != nil {
+					return err
+				}
+				if _, err := s.Predecessors(egCtx, config); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		t.Fatal("concurrent Store.Tag() and Store.Resolve() error =", err)
+	}
+
+	got, err := s.Resolve(ctx, "other")
+	if err != nil {
+		t.Fatal("Store.Resolve() error =", err)
+	}
+	if !content.Equal(got, otherManifest) {
+		t.Errorf("Store.Resolve() = %v, want %v", got, otherManifest)
+	}
+	predecessors, err := s.Predecessors(ctx, config)
+	if err != nil {
+		t.Fatal("Store.Predecessors() error =", err)
+	}
+	if len(predecessors) != 2 {
+		t.Errorf("len(Store.Predecessors()) = %v, want %v", len(predecessors), 2)
+	}
+
+	// the lock file of index.json is not a part of the layout
+	if _, err := os.Stat(filepath.Join(tempDir, indexLockFile)); err != nil {
+		t.Fatal("os.Stat() error =", err)
+	}
+	report, err := s.Verify(ctx, VerifyOptions{})
+	if err != nil {
+		t.Fatal("Store.Verify() error =", err)
+	}
+	if !report.OK() {
+		t.Errorf("Store.Verify() issues = %v, want none", report.Issues)
+	}
+}
+
 func TestStore_GC(t *testing.T) {
 	tempDir := t.TempDir()
 	s, err := New(tempDir)
@@ -2861,6 +3143,376 @@ func equalDescriptorSet(actual []ocispec.Descriptor, expected []ocispec.Descript
 	return true
 }
 
+// setBlobModTime sets the modification time of the blob described by desc.
+func setBlobModTime(t *testing.T, root string, desc ocispec.Descriptor, modTime time.Time) {
+	t.Helper()
+	path := filepath.Join(root, "blobs", desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal("os.Chtimes() error =", err)
+	}
+}
+
+func TestStore_GCWithOptions_DryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	layer1 := pushBlob(t, s, "test/layer", []byte("layer1"))
+	layer2 := pushBlob(t, s, "test/layer", []byte("layer2"))
+	tagged := pushManifest(t, s, nil, config, layer1)
+	if err := s.Tag(ctx, tagged, "latest"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	untagged := pushManifest(t, s, nil, config, layer2)
+	garbage := pushBlob(t, s, "test/garbage", []byte("garbage"))
+
+	wantBlobs := map[digest.Digest]ocispec.Descriptor{
+		layer2.Digest: {
+			MediaType: descriptor.DefaultMediaType,
+			Digest:    layer2.Digest,
+			Size:      layer2.Size,
+		},
+		untagged.Digest: untagged,
+		garbage.Digest: {
+			MediaType: descriptor.DefaultMediaType,
+			Digest:    garbage.Digest,
+			Size:      garbage.Size,
+		},
+	}
+	wantBytes := layer2.Size + untagged.Size + garbage.Size
+	checkReport := func(report *GCReport) {
+		t.Helper()
+		if got, want := len(report.Blobs), len(wantBlobs); got != want {
+			t.Fatalf("len(GCReport.Blobs) = %v, want %v", got, want)
+		}
+		for _, desc := range report.Blobs {
+			if want := wantBlobs[desc.Digest]; !reflect.DeepEqual(desc, want) {
+				t.Errorf("GCReport.Blobs contains %v, want %v", desc, want)
+			}
+		}
+		if got := report.ReclaimedBytes; got != wantBytes {
+			t.Errorf("GCReport.ReclaimedBytes = %v, want %v", got, wantBytes)
+		}
+	}
+
+	report, err := s.GCWithOptions(ctx, GCOptions{DryRun: true})
+	if err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	checkReport(report)
+	for _, desc := range []ocispec.Descriptor{untagged, layer2, garbage} {
+		if exists, err := s.Exists(ctx, desc); err != nil || !exists {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", desc.Digest, exists, err, true)
+		}
+	}
+	if _, err := s.Resolve(ctx, untagged.Digest.String()); err != nil {
+		t.Errorf("Store.Resolve() error = %v", err)
+	}
+
+	report, err = s.GCWithOptions(ctx, GCOptions{PruneIndex: true})
+	if err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	checkReport(report)
+	for _, desc := range []ocispec.Descriptor{untagged, layer2, garbage} {
+		if exists, err := s.Exists(ctx, desc); err != nil || exists {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", desc.Digest, exists, err, false)
+		}
+	}
+
+	// the index is saved without the untagged manifest
+	s, err = New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	if _, err := s.Resolve(ctx, untagged.Digest.String()); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Store.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if _, err := s.Resolve(ctx, "latest"); err != nil {
+		t.Errorf("Store.Resolve() error = %v", err)
+	}
+}
+
+func TestStore_GC_IndexNotSaved(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	untagged := pushManifest(t, s, nil, pushBlob(t, s, "test/config", []byte("config")))
+	indexPath := filepath.Join(tempDir, ocispec.ImageIndexFile)
+	want, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+
+	if err := s.GC(ctx); err != nil {
+		t.Fatal("Store.GC() error =", err)
+	}
+	if _, err := s.Resolve(ctx, untagged.Digest.String()); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("Store.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	got, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("index file = %s, want %s", got, want)
+	}
+}
+
+func TestStore_GC_ExternalTags(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+
+	// another process sharing the layout pushes and tags a manifest
+	other, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, other, "test/config", []byte("config"))
+	layer := pushBlob(t, other, "test/layer", []byte("layer"))
+	manifest := pushManifest(t, other, nil, config, layer)
+	if err := other.Tag(ctx, manifest, "latest"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	garbage := pushBlob(t, other, "test/garbage", []byte("garbage"))
+
+	report, err := s.GCWithOptions(ctx, GCOptions{})
+	if err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	if len(report.Blobs) != 1 || report.Blobs[0].Digest != garbage.Digest {
+		t.Errorf("GCReport.Blobs = %v, want %v", report.Blobs, garbage)
+	}
+	for _, desc := range []ocispec.Descriptor{config, layer, manifest} {
+		if exists, err := s.Exists(ctx, desc); err != nil || !exists {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", desc.Digest, exists, err, true)
+		}
+	}
+}
+
+func TestStore_GCWithOptions_KeepUntagged(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	now := time.Now()
+	var layers, manifests []ocispec.Descriptor
+	for i := 0; i < 3; i++ {
+		layer := pushBlob(t, s, "test/layer", []byte(fmt.Sprintf("layer%d", i)))
+		manifest := pushManifest(t, s, nil, config, layer)
+		setBlobModTime(t, tempDir, manifest, now.Add(time.Duration(i-3)*time.Hour))
+		layers = append(layers, layer)
+		manifests = append(manifests, manifest)
+	}
+
+	report, err := s.GCWithOptions(ctx, GCOptions{KeepUntagged: 2})
+	if err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	if got, want := len(report.Blobs), 2; got != want {
+		t.Errorf("len(GCReport.Blobs) = %v, want %v", got, want)
+	}
+	for i, want := range []bool{false, true, true} {
+		for _, desc := range []ocispec.Descriptor{manifests[i], layers[i]} {
+			if exists, err := s.Exists(ctx, desc); err != nil || exists != want {
+				t.Errorf("Store.Exists(%s) = %v, %v, want %v", desc.Digest, exists, err, want)
+			}
+		}
+	}
+}
+
+func TestStore_GCWithOptions_KeepNewerThan(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	oldGarbage := pushBlob(t, s, "test/garbage", []byte("old garbage"))
+	newGarbage := pushBlob(t, s, "test/garbage", []byte("new garbage"))
+	manifest := pushManifest(t, s, nil, config, layer)
+	for _, desc := range []ocispec.Descriptor{config, layer, oldGarbage} {
+		setBlobModTime(t, tempDir, desc, old)
+	}
+
+	if _, err := s.GCWithOptions(ctx, GCOptions{KeepNewerThan: time.Hour}); err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	for _, tt := range []struct {
+		desc ocispec.Descriptor
+		want bool
+	}{
+		{config, true},
+		{layer, true},
+		{manifest, true},
+		{oldGarbage, false},
+		{newGarbage, true},
+	} {
+		if exists, err := s.Exists(ctx, tt.desc); err != nil || exists != tt.want {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", tt.desc.Digest, exists, err, tt.want)
+		}
+	}
+}
+
+func TestStore_GCWithOptions_Pinned(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	pinned := pushManifest(t, s, nil, config, layer)
+	referrer := pushManifest(t, s, &pinned, config)
+	referrerOfReferrer := pushManifest(t, s, &referrer, config)
+	unpinned := pushManifest(t, s, nil, config)
+	pinnedBlob := pushBlob(t, s, "test/garbage", []byte("pinned garbage"))
+
+	// the child manifest of an index not in the index.json
+	childLayer := pushBlob(t, s, "test/layer", []byte("child layer"))
+	child := pushManifest(t, s, nil, config, childLayer)
+	s.tagResolver.Untag(child.Digest.String())
+
+	if _, err := s.GCWithOptions(ctx, GCOptions{
+		Pinned: []digest.Digest{pinned.Digest, pinnedBlob.Digest, child.Digest},
+	}); err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	for _, tt := range []struct {
+		desc ocispec.Descriptor
+		want bool
+	}{
+		{config, true},
+		{layer, true},
+		{pinned, true},
+		{referrer, true},
+		{referrerOfReferrer, true},
+		{unpinned, false},
+		{pinnedBlob, true},
+		{child, true},
+		{childLayer, true},
+	} {
+		if exists, err := s.Exists(ctx, tt.desc); err != nil || exists != tt.want {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", tt.desc.Digest, exists, err, tt.want)
+		}
+	}
+}
+
+func TestStore_GCWithOptions_PinnedWithoutMediaType(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	manifestJSON := []byte(`{"schemaVersion":2,"config":` + descriptorJSON(t, config) + `,"layers":[` + descriptorJSON(t, layer) + `]}`)
+	manifest := pushBlob(t, s, ocispec.MediaTypeImageManifest, manifestJSON)
+	childLayer := pushBlob(t, s, "test/layer", []byte("child layer"))
+	child := pushManifest(t, s, nil, config, childLayer)
+	indexJSON := []byte(`{"schemaVersion":2,"manifests":[` + descriptorJSON(t, child) + `]}`)
+	index := pushBlob(t, s, ocispec.MediaTypeImageIndex, indexJSON)
+	for _, desc := range []ocispec.Descriptor{manifest, child, index} {
+		s.tagResolver.Untag(desc.Digest.String())
+	}
+
+	if _, err := s.GCWithOptions(ctx, GCOptions{
+		Pinned: []digest.Digest{manifest.Digest, index.Digest},
+	}); err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	for _, desc := range []ocispec.Descriptor{config, layer, manifest, childLayer, child, index} {
+		if exists, err := s.Exists(ctx, desc); err != nil || !exists {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", desc.Digest, exists, err, true)
+		}
+	}
+}
+
+// descriptorJSON returns the JSON encoding of desc.
+func descriptorJSON(t *testing.T, desc ocispec.Descriptor) string {
+	t.Helper()
+	descJSON, err := json.Marshal(desc)
+	if err != nil {
+		t.Fatal("json.Marshal() error =", err)
+	}
+	return string(descJSON)
+}
+
+func TestStore_GCWithOptions_StaleIngest(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	ingestRoot := filepath.Join(tempDir, "ingest")
+	if err := os.MkdirAll(ingestRoot, 0777); err != nil {
+		t.Fatal("os.MkdirAll() error =", err)
+	}
+	stalePath := filepath.Join(ingestRoot, "stale_1")
+	freshPath := filepath.Join(ingestRoot, "fresh_1")
+	if err := os.WriteFile(stalePath, []byte("stale"), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+	if err := os.WriteFile(freshPath, []byte("fresh"), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+	old := time.Now().Add(-25 * time.Hour)
+	if err := os.Chtimes(stalePath, old, old); err != nil {
+		t.Fatal("os.Chtimes() error =", err)
+	}
+
+	report, err := s.GCWithOptions(ctx, GCOptions{DryRun: true, StaleIngestAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	if want := []string{"ingest/stale_1"}; !reflect.DeepEqual(report.IngestFiles, want) {
+		t.Errorf("GCReport.IngestFiles = %v, want %v", report.IngestFiles, want)
+	}
+	if got, want := report.ReclaimedBytes, int64(5); got != want {
+		t.Errorf("GCReport.ReclaimedBytes = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(stalePath); err != nil {
+		t.Errorf("stale ingest file is removed on dry-run, error = %v", err)
+	}
+
+	// ingest files are not removed unless requested
+	if err := s.GC(ctx); err != nil {
+		t.Fatal("Store.GC() error =", err)
+	}
+	if _, err := os.Stat(stalePath); err != nil {
+		t.Errorf("stale ingest file is removed by GC(), error = %v", err)
+	}
+
+	if _, err := s.GCWithOptions(ctx, GCOptions{StaleIngestAge: 24 * time.Hour}); err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	if _, err := os.Stat(stalePath); !os.IsNotExist(err) {
+		t.Errorf("stale ingest file is not removed, error = %v", err)
+	}
+	if _, err := os.Stat(freshPath); err != nil {
+		t.Errorf("fresh ingest file is removed, error = %v", err)
+	}
+}
+
 func Test_isContextDone(t *testing.T) {
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
@@ -2947,3 +3599,63 @@ func TestStore_BadDigest(t *testing.T) {
 		}
 	})
 }
+
+func TestStore_PeerRoots(t *testing.T) {
+	ctx := context.Background()
+	srcDir := t.TempDir()
+	src, err := New(srcDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, src, "test/config", []byte("config"))
+	layer := pushBlob(t, src, "test/layer", []byte("layer"))
+	manifest := pushManifest(t, src, nil, config, layer)
+	if err := src.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+
+	dstDir := t.TempDir()
+	dst, err := NewWithOptions(ctx, dstDir, StoreOptions{
+		PeerRoots: []string{srcDir},
+	})
+	if err != nil {
+		t.Fatal("NewWithOptions() error =", err)
+	}
+	if _, err := oras.Copy(ctx, src, "v1", dst, "v1", oras.DefaultCopyOptions); err != nil {
+		t.Fatal("oras.Copy() error =", err)
+	}
+	for _, desc := range []ocispec.Descriptor{config, layer, manifest} {
+		path := filepath.Join("blobs", "sha256", desc.Digest.Encoded())
+		srcInfo, err := os.Stat(filepath.Join(srcDir, path))
+		if err != nil {
+			t.Fatal("os.Stat() error =", err)
+		}
+		dstInfo, err := os.Stat(filepath.Join(dstDir, path))
+		if err != nil {
+			t.Fatal("os.Stat() error =", err)
+		}
+		if !os.SameFile(srcInfo, dstInfo) {
+			t.Errorf("os.SameFile(%s) = %v, want %v", desc.Digest, false, true)
+		}
+	}
+	if got, err := dst.Resolve(ctx, "v1"); err != nil || !content.Equal(got, manifest) {
+		t.Errorf("Store.Resolve() = %v, %v, want %v", got, err, manifest)
+	}
+	predecessors, err := dst.Predecessors(ctx, layer)
+	if err != nil {
+		t.Fatal("Store.Predecessors() error =", err)
+	}
+	if want := []ocispec.Descriptor{manifest}; !reflect.DeepEqual(predecessors, want) {
+		t.Errorf("Store.Predecessors() = %v, want %v", predecessors, want)
+	}
+
+	// deleting from one layout does not affect the other
+	if err := dst.Delete(ctx, manifest); err != nil {
+		t.Fatal("Store.Delete() error =", err)
+	}
+	for _, desc := range []ocispec.Descriptor{config, layer, manifest} {
+		if exists, err := src.Exists(ctx, desc); err != nil || !exists {
+			t.Errorf("Store.Exists(%s) = %v, %v, want %v", desc.Digest, exists, err, true)
+		}
+	}
+}
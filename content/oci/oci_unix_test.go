@@ -0,0 +1,92 @@
+//go:build !windows
+
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestStore_SaveIndex_FileMode(t *testing.T) {
+	oldMask := syscall.Umask(0002)
+	defer syscall.Umask(oldMask)
+
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	indexPath := filepath.Join(tempDir, ocispec.ImageIndexFile)
+	checkMode := func(want fs.FileMode) {
+		t.Helper()
+		fi, err := os.Stat(indexPath)
+		if err != nil {
+			t.Fatal("os.Stat() error =", err)
+		}
+		if got := fi.Mode().Perm(); got != want {
+			t.Errorf("index file mode = %v, want %v", got, want)
+		}
+	}
+
+	// new index files respect umask
+	checkMode(0664)
+
+	// the mode of the existing index file is preserved
+	if err := os.Chmod(indexPath, 0640); err != nil {
+		t.Fatal("os.Chmod() error =", err)
+	}
+	manifest := pushManifest(t, s, nil, pushBlob(t, s, "test/config", []byte("config")))
+	if err := s.Tag(ctx, manifest, "latest"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	checkMode(0640)
+}
+
+func TestStore_GCWithOptions_HardLinked(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	linked := pushBlob(t, s, "test/garbage", []byte("linked garbage"))
+	garbage := pushBlob(t, s, "test/garbage", []byte("garbage"))
+	blobPath := filepath.Join(tempDir, "blobs", "sha256", linked.Digest.Encoded())
+	if err := os.Link(blobPath, filepath.Join(t.TempDir(), "linked")); err != nil {
+		t.Fatal("os.Link() error =", err)
+	}
+
+	report, err := s.GCWithOptions(ctx, GCOptions{DryRun: true})
+	if err != nil {
+		t.Fatal("Store.GCWithOptions() error =", err)
+	}
+	if got, want := len(report.Blobs), 2; got != want {
+		t.Errorf("len(GCReport.Blobs) = %v, want %v", got, want)
+	}
+	// the space of the hard linked blob is not reclaimed
+	if got, want := report.ReclaimedBytes, garbage.Size; got != want {
+		t.Errorf("GCReport.ReclaimedBytes = %v, want %v", got, want)
+	}
+}
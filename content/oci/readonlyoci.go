@@ -42,6 +42,33 @@ type ReadOnlyStore struct {
 	storage     content.ReadOnlyStorage
 	tagResolver *resolver.Memory
 	graph       *graph.Memory
+	// closer releases the file system created by the store.
+	closer io.Closer
+}
+
+// TarOptions contains parameters for NewFromTarWithOptions.
+type TarOptions struct {
+	// CacheDir is the directory where compressed archives are extracted to.
+	// The extracted archives are reused by subsequent opens of the same
+	// compressed archive, as identified by its path, size and modification
+	// time, and they are left for the caller to clean up.
+	// If empty, compressed archives are extracted to temporary files, which
+	// are removed right after the extraction while kept open, and are freed on
+	// Close. On platforms not supporting the removal of open files, such as
+	// Windows, the temporary files are removed on Close.
+	CacheDir string
+
+	// MaxExtractedSize is the maximum size of the uncompressed archive
+	// extracted from a compressed archive, limiting the disk space used by
+	// the extraction, for example, of a decompression bomb. Opening the
+	// archive fails with errdef.ErrSizeExceedsLimit if the limit is exceeded.
+	// If zero, 32 GiB is used. If negative, the size is not limited.
+	MaxExtractedSize int64
+
+	// NewZstdReader creates a zstd decompressing reader reading from r. It is
+	// required by zstd compressed archives as zstd is not supported by the
+	// standard library.
+	NewZstdReader func(r io.Reader) (io.ReadCloser, error)
 }
 
 // NewFromFS creates a new read-only OCI store from fsys.
@@ -64,13 +91,50 @@ func NewFromFS(ctx context.Context, fsys fs.FS) (*ReadOnlyStore, error) {
 }
 
 // NewFromTar creates a new read-only OCI store from a tar archive located at
-// path.
+// path. Gzip compressed archives are extracted to a temporary file of at most
+// 32 GiB.
+//
+// The caller must Close the returned store to release the temporary file.
 func NewFromTar(ctx context.Context, path string) (*ReadOnlyStore, error) {
-	tfs, err := tarfs.New(path)
+	return NewFromTarWithOptions(ctx, path, TarOptions{})
+}
+
+// NewFromTarWithOptions creates a new read-only OCI store from a tar archive
+// located at path, which is optionally compressed with gzip or zstd.
+// Compressed archives are extracted once to an uncompressed cache, which
+// serves the random reads of the blobs.
+//
+// The caller must Close the returned store to release the temporary cache.
+func NewFromTarWithOptions(ctx context.Context, path string, opts TarOptions) (*ReadOnlyStore, error) {
+	tfs, err := newTarFS(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewFromFS(ctx, tfs)
 	if err != nil {
+		tfs.Close()
 		return nil, err
 	}
-	return NewFromFS(ctx, tfs)
+	store.closer = tfs
+	return store, nil
+}
+
+// newTarFS returns a file system for a tar archive located at path.
+func newTarFS(path string, opts TarOptions) (*tarfs.TarFS, error) {
+	return tarfs.NewWithOptions(path, tarfs.Options{
+		CacheDir:         opts.CacheDir,
+		MaxExtractedSize: opts.MaxExtractedSize,
+		NewZstdReader:    opts.NewZstdReader,
+	})
+}
+
+// Close releases the resources held by the store, such as the temporary
+// extraction cache of a compressed archive.
+func (s *ReadOnlyStore) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
 }
 
 // Fetch fetches the content identified by the descriptor.
@@ -167,7 +231,8 @@ func (s *ReadOnlyStore) loadIndexFile(ctx context.Context) error {
 	return loadIndex(ctx, &index, s.storage, s.tagResolver, s.graph)
 }
 
-// loadIndex loads index into memory.
+// loadIndex loads index into memory. If graph is nil, only the references are
+// loaded.
 func loadIndex(ctx context.Context, index *ocispec.Index, fetcher content.Fetcher, tagger content.Tagger, graph *graph.Memory) error {
 	for _, desc := range index.Manifests {
 		if err := tagger.Tag(ctx, deleteAnnotationRefName(desc), desc.Digest.String()); err != nil {
@@ -178,6 +243,9 @@ func loadIndex(ctx context.Context, index *ocispec.Index, fetcher content.Fetche
 				return err
 			}
 		}
+		if graph == nil {
+			continue
+		}
 		plain := descriptor.Plain(desc)
 		if err := graph.IndexAll(ctx, fetcher, plain); err != nil {
 			return err
@@ -17,12 +17,14 @@ package oci
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -36,6 +38,7 @@ import (
 	"oras.land/oras-go/v2"
 	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/errdef"
 	"oras.land/oras-go/v2/internal/docker"
 	"oras.land/oras-go/v2/internal/spec"
 	"oras.land/oras-go/v2/registry"
@@ -530,6 +533,69 @@ func TestReadOnlyStore_TarFS(t *testing.T) {
 	}
 }
 
+func TestReadOnlyStore_TarGzip(t *testing.T) {
+	ctx := context.Background()
+	data, err := os.ReadFile("testdata/hello-world.tar")
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal("gzip.Writer.Write() error =", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal("gzip.Writer.Close() error =", err)
+	}
+	tempDir := t.TempDir()
+	gzPath := filepath.Join(tempDir, "hello-world.tar.gz")
+	if err := os.WriteFile(gzPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+
+	for _, opts := range []TarOptions{{}, {CacheDir: filepath.Join(tempDir, "cache")}} {
+		s, err := NewFromTarWithOptions(ctx, gzPath, opts)
+		if err != nil {
+			t.Fatal("NewFromTarWithOptions() error =", err)
+		}
+		desc, err := s.Resolve(ctx, "latest")
+		if err != nil {
+			t.Fatal("ReadOnlyStore.Resolve() error =", err)
+		}
+		if want := digest.Digest("sha256:faa03e786c97f07ef34423fccceeec2398ec8a5759259f94d99078f264e9d7af"); desc.Digest != want {
+			t.Errorf("ReadOnlyStore.Resolve() = %v, want %v", desc.Digest, want)
+		}
+		layer := ocispec.Descriptor{
+			MediaType: "application/vnd.docker.image.rootfs.diff.tar.gzip",
+			Size:      2479,
+			Digest:    "sha256:2db29710123e3e53a794f2694094b9b4338aa9ee5c40b930cb8063a1be392c54",
+		}
+		// fetch more than once from the extraction cache
+		for i := 0; i < 2; i++ {
+			if _, err := content.FetchAll(ctx, s, layer); err != nil {
+				t.Fatal("content.FetchAll() error =", err)
+			}
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal("ReadOnlyStore.Close() error =", err)
+		}
+	}
+
+	// the extraction is limited
+	if _, err := NewFromTarWithOptions(ctx, gzPath, TarOptions{MaxExtractedSize: 1024}); !errors.Is(err, errdef.ErrSizeExceedsLimit) {
+		t.Errorf("NewFromTarWithOptions() error = %v, wantErr %v", err, errdef.ErrSizeExceedsLimit)
+	}
+
+	// zstd without a decompressor
+	zstdPath := filepath.Join(tempDir, "hello-world.tar.zst")
+	if err := os.WriteFile(zstdPath, append([]byte{0x28, 0xb5, 0x2f, 0xfd}, data...), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+	if _, err := NewFromTar(ctx, zstdPath); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("NewFromTar() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+}
+
 func TestReadOnlyStore_BadIndex(t *testing.T) {
 	content := []byte("whatever")
 	fsys := fstest.MapFS{
@@ -34,6 +34,8 @@ import (
 // Reference: https://github.com/opencontainers/image-spec/blob/v1.1.1/image-layout.md
 type ReadOnlyStorage struct {
 	fsys fs.FS
+	// closer releases the file system created by the storage.
+	closer io.Closer
 }
 
 // NewStorageFromFS creates a new read-only CAS from fsys.
@@ -44,13 +46,27 @@ func NewStorageFromFS(fsys fs.FS) *ReadOnlyStorage {
 }
 
 // NewStorageFromTar creates a new read-only CAS from a tar archive located at
-// path.
+// path. Gzip compressed archives are extracted to a temporary file of at most
+// 32 GiB.
+//
+// The caller must Close the returned storage to release the temporary file.
 func NewStorageFromTar(path string) (*ReadOnlyStorage, error) {
 	tfs, err := tarfs.New(path)
 	if err != nil {
 		return nil, err
 	}
-	return NewStorageFromFS(tfs), nil
+	storage := NewStorageFromFS(tfs)
+	storage.closer = tfs
+	return storage, nil
+}
+
+// Close releases the resources held by the storage, such as the temporary
+// extraction cache of a compressed archive.
+func (s *ReadOnlyStorage) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
 }
 
 // Fetch fetches the content identified by the descriptor.
@@ -0,0 +1,46 @@
+//go:build linux
+
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl request code, which is defined as
+// _IOW(0x94, 9, int) in <linux/fs.h>.
+const ficlone = 0x40049409
+
+// reflink clones the content of src to dst, sharing the data blocks on the
+// file systems supporting copy-on-write, such as Btrfs and XFS.
+func reflink(dst, src *os.File) error {
+	rawConn, err := dst.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := rawConn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, ficlone, src.Fd())
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return &os.SyscallError{Syscall: "ioctl FICLONE", Err: errno}
+	}
+	return nil
+}
@@ -50,23 +50,65 @@ type Storage struct {
 	root string
 	// ingestRoot is the root directory of the temporary ingest files.
 	ingestRoot string
+	// peerRoots are the root directories of the peer OCI layouts.
+	peerRoots []string
+}
+
+// StorageOptions contains parameters for NewStorageWithOptions.
+type StorageOptions struct {
+	// PeerRoots are the root directories of other OCI layouts, which are
+	// looked up for the blobs being pushed.
+	// If a pushed blob exists in a peer layout and its content matches the
+	// expected size and digest, the blob is hard linked from the peer layout
+	// instead of being read from the given content. If hard linking fails,
+	// for instance, when the layouts are on different file systems, the blob
+	// is cloned by reflink where supported, or copied from the peer layout.
+	//   - Blobs hard linked from peer layouts share the same file, so that
+	//     the blobs must not be modified in place.
+	//   - Blobs hard linked from peer layouts also share the modification
+	//     time of the blobs in the peer layouts, which may be long before the
+	//     push. Retention policies based on the modification time, such as
+	//     GCOptions.KeepNewerThan, may collect such blobs right after the
+	//     push.
+	//   - Default value: nil.
+	PeerRoots []string
 }
 
 // NewStorage creates a new CAS based on file system with the OCI-Image layout.
 func NewStorage(root string) (*Storage, error) {
+	return NewStorageWithOptions(root, StorageOptions{})
+}
+
+// NewStorageWithOptions creates a new CAS based on file system with the
+// OCI-Image layout with the given options.
+func NewStorageWithOptions(root string, opts StorageOptions) (*Storage, error) {
 	rootAbs, err := filepath.Abs(root)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve absolute path for %s: %w", root, err)
 	}
+	var peerRoots []string
+	for _, peerRoot := range opts.PeerRoots {
+		peerRootAbs, err := filepath.Abs(peerRoot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve absolute path for %s: %w", peerRoot, err)
+		}
+		if peerRootAbs != rootAbs {
+			peerRoots = append(peerRoots, peerRootAbs)
+		}
+	}
 
 	return &Storage{
 		ReadOnlyStorage: NewStorageFromFS(os.DirFS(rootAbs)),
 		root:            rootAbs,
-		ingestRoot:      filepath.Join(rootAbs, "ingest"),
+		ingestRoot:      filepath.Join(rootAbs, ingestDir),
+		peerRoots:       peerRoots,
 	}, nil
 }
 
 // Push pushes the content, matching the expected descriptor.
+// If the content exists in the peer layouts, it is taken from the peer layouts
+// and content is not read at all. In either case, content is not closed by
+// Push.
 func (s *Storage) Push(_ context.Context, expected ocispec.Descriptor, content io.Reader) error {
 	path, err := blobPath(expected.Digest)
 	if err != nil {
@@ -85,13 +127,48 @@ func (s *Storage) Push(_ context.Context, expected ocispec.Descriptor, content i
 		return err
 	}
 
+	// try to take the content from the peer layouts.
+	for _, peerRoot := range s.peerRoots {
+		if ok, err := s.pushFromPeer(expected, filepath.Join(peerRoot, path), target); ok || err != nil {
+			return err
+		}
+	}
+
 	// write the content to a temporary ingest file.
 	ingest, err := s.ingest(expected, content)
 	if err != nil {
 		return err
 	}
+	return s.commit(expected, ingest, target)
+}
+
+// pushFromPeer stores the blob at peerPath in the peer layout to the target
+// path, if the blob matches the expected descriptor. The blob is hard linked,
+// cloned or copied, in the order of preference.
+// It returns false with no error if the blob is missing or corrupted in the
+// peer layout, or cannot be taken from the peer layout.
+func (s *Storage) pushFromPeer(expected ocispec.Descriptor, peerPath, target string) (bool, error) {
+	if !verifyFile(expected, peerPath) {
+		return false, nil
+	}
+
+	// blobs are read-only once stored, so that a verified blob can be shared
+	// by hard links.
+	if err := os.Link(peerPath, target); err == nil {
+		return true, nil
+	} else if errors.Is(err, fs.ErrExist) {
+		return false, fmt.Errorf("%s: %s: %w", expected.Digest, expected.MediaType, errdef.ErrAlreadyExists)
+	}
 
-	// move the content from the temporary ingest file to the target path.
+	ingest, err := s.ingestFile(expected, peerPath)
+	if err != nil {
+		return false, nil
+	}
+	return true, s.commit(expected, ingest, target)
+}
+
+// commit moves the content from the temporary ingest file to the target path.
+func (s *Storage) commit(expected ocispec.Descriptor, ingest, target string) error {
 	// since blobs are read-only once stored, if the target blob already exists,
 	// Rename() will fail for permission denied when trying to overwrite it.
 	if err := os.Rename(ingest, target); err != nil {
@@ -125,7 +202,41 @@ func (s *Storage) Delete(ctx context.Context, target ocispec.Descriptor) error {
 }
 
 // ingest write the content into a temporary ingest file.
-func (s *Storage) ingest(expected ocispec.Descriptor, content io.Reader) (path string, ingestErr error) {
+func (s *Storage) ingest(expected ocispec.Descriptor, content io.Reader) (string, error) {
+	return s.ingestWith(expected, func(fp *os.File) error {
+		buf := bufPool.Get().(*[]byte)
+		defer bufPool.Put(buf)
+		if err := ioutil.CopyBuffer(fp, content, *buf, expected); err != nil {
+			return fmt.Errorf("failed to ingest: %w", err)
+		}
+		return nil
+	})
+}
+
+// ingestFile clones or copies the file at path into a temporary ingest file.
+// The content is verified if it is copied.
+func (s *Storage) ingestFile(expected ocispec.Descriptor, path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	return s.ingestWith(expected, func(fp *os.File) error {
+		if err := reflink(fp, src); err == nil {
+			return nil
+		}
+		buf := bufPool.Get().(*[]byte)
+		defer bufPool.Put(buf)
+		if err := ioutil.CopyBuffer(fp, src, *buf, expected); err != nil {
+			return fmt.Errorf("failed to ingest: %w", err)
+		}
+		return nil
+	})
+}
+
+// ingestWith writes a temporary ingest file by the write function.
+func (s *Storage) ingestWith(expected ocispec.Descriptor, write func(fp *os.File) error) (path string, ingestErr error) {
 	if err := ensureDir(s.ingestRoot); err != nil {
 		return "", fmt.Errorf("failed to ensure ingest dir: %w", err)
 	}
@@ -152,10 +263,8 @@ func (s *Storage) ingest(expected ocispec.Descriptor, content io.Reader) (path s
 		}
 	}()
 
-	buf := bufPool.Get().(*[]byte)
-	defer bufPool.Put(buf)
-	if err := ioutil.CopyBuffer(fp, content, *buf, expected); err != nil {
-		return "", fmt.Errorf("failed to ingest: %w", err)
+	if err := write(fp); err != nil {
+		return "", err
 	}
 
 	// change to readonly
@@ -166,6 +275,23 @@ func (s *Storage) ingest(expected ocispec.Descriptor, content io.Reader) (path s
 	return
 }
 
+// verifyFile returns true if the content of the file at path matches the
+// descriptor.
+func verifyFile(desc ocispec.Descriptor, path string) bool {
+	fp, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer fp.Close()
+
+	if fi, err := fp.Stat(); err != nil || !fi.Mode().IsRegular() || fi.Size() != desc.Size {
+		return false
+	}
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	return ioutil.CopyBuffer(io.Discard, fp, *buf, desc) == nil
+}
+
 // ensureDir ensures the directories of the path exists.
 func ensureDir(path string) error {
 	return os.MkdirAll(path, 0777)
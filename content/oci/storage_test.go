@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -417,3 +418,131 @@ func TestStorage_Delete(t *testing.T) {
 		t.Fatalf("got error = %v, want %v", err, errdef.ErrNotFound)
 	}
 }
+
+func TestStorage_PeerRoots(t *testing.T) {
+	content := []byte("hello world")
+	desc := ocispec.Descriptor{
+		MediaType: "test",
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+	ctx := context.Background()
+	peerDir := t.TempDir()
+	peer, err := NewStorage(peerDir)
+	if err != nil {
+		t.Fatal("NewStorage() error =", err)
+	}
+	if err := peer.Push(ctx, desc, bytes.NewReader(content)); err != nil {
+		t.Fatal("Storage.Push() error =", err)
+	}
+
+	tempDir := t.TempDir()
+	s, err := NewStorageWithOptions(tempDir, StorageOptions{
+		PeerRoots: []string{filepath.Join(tempDir, "missing"), peerDir},
+	})
+	if err != nil {
+		t.Fatal("NewStorageWithOptions() error =", err)
+	}
+	// the content is taken from the peer layout without being read
+	if err := s.Push(ctx, desc, iotest.ErrReader(errors.New("unexpected read"))); err != nil {
+		t.Fatal("Storage.Push() error =", err)
+	}
+	path := filepath.Join("blobs", "sha256", desc.Digest.Encoded())
+	peerInfo, err := os.Stat(filepath.Join(peerDir, path))
+	if err != nil {
+		t.Fatal("os.Stat() error =", err)
+	}
+	info, err := os.Stat(filepath.Join(tempDir, path))
+	if err != nil {
+		t.Fatal("os.Stat() error =", err)
+	}
+	if !os.SameFile(info, peerInfo) {
+		t.Errorf("os.SameFile() = %v, want %v", false, true)
+	}
+	rc, err := s.Fetch(ctx, desc)
+	if err != nil {
+		t.Fatal("Storage.Fetch() error =", err)
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal("Storage.Fetch().Read() error =", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Storage.Fetch() = %v, want %v", got, content)
+	}
+
+	if err := s.Push(ctx, desc, bytes.NewReader(content)); !errors.Is(err, errdef.ErrAlreadyExists) {
+		t.Errorf("Storage.Push() error = %v, wantErr %v", err, errdef.ErrAlreadyExists)
+	}
+}
+
+func TestStorage_PeerRoots_Corrupted(t *testing.T) {
+	content := []byte("hello world")
+	desc := ocispec.Descriptor{
+		MediaType: "test",
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+	ctx := context.Background()
+	peerDir := t.TempDir()
+	peerPath := filepath.Join(peerDir, "blobs", "sha256", desc.Digest.Encoded())
+	if err := os.MkdirAll(filepath.Dir(peerPath), 0777); err != nil {
+		t.Fatal("os.MkdirAll() error =", err)
+	}
+	if err := os.WriteFile(peerPath, []byte("hello wrold"), 0444); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+
+	tempDir := t.TempDir()
+	s, err := NewStorageWithOptions(tempDir, StorageOptions{
+		PeerRoots: []string{peerDir},
+	})
+	if err != nil {
+		t.Fatal("NewStorageWithOptions() error =", err)
+	}
+	// the corrupted blob in the peer layout is ignored
+	if err := s.Push(ctx, desc, bytes.NewReader(content)); err != nil {
+		t.Fatal("Storage.Push() error =", err)
+	}
+	got, err := os.ReadFile(filepath.Join(tempDir, "blobs", "sha256", desc.Digest.Encoded()))
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Storage.Push() stored %v, want %v", got, content)
+	}
+}
+
+func TestStorage_ingestFile(t *testing.T) {
+	content := []byte("hello world")
+	desc := ocispec.Descriptor{
+		MediaType: "test",
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+	tempDir := t.TempDir()
+	s, err := NewStorage(tempDir)
+	if err != nil {
+		t.Fatal("NewStorage() error =", err)
+	}
+	path := filepath.Join(t.TempDir(), "blob")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+
+	ingest, err := s.ingestFile(desc, path)
+	if err != nil {
+		t.Fatal("Storage.ingestFile() error =", err)
+	}
+	got, err := os.ReadFile(ingest)
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Storage.ingestFile() = %v, want %v", got, content)
+	}
+	if _, err := s.ingestFile(desc, filepath.Join(tempDir, "missing")); err == nil {
+		t.Errorf("Storage.ingestFile() error = %v, wantErr %v", err, true)
+	}
+}
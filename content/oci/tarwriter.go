@@ -0,0 +1,370 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/internal/container/set"
+	"oras.land/oras-go/v2/internal/descriptor"
+)
+
+// ErrTarWriterClosed is returned when operating a closed TarWriter.
+var ErrTarWriterClosed = errors.New("tar writer is closed")
+
+// TarWriter implements `oras.Target`, and writes an OCI image layout archive
+// incrementally: the `oci-layout` file is written first, the blobs are
+// appended to the archive as they are pushed, and the `index.json` file
+// listing the tagged descriptors is written on Close. Thus content can be
+// copied into an archive without an intermediate directory.
+//
+// Pushes are serialized as the blobs are streamed into the archive. As the
+// archive cannot be rewound, a push failing in the middle of a blob, for
+// example due to a broken source or a mismatched digest, leaves the archive
+// corrupted, and all the subsequent operations return the error.
+//
+// The manifests pushed are kept in memory so that they can be fetched. Other
+// blobs can be fetched only if the archive is uncompressed and the underlying
+// writer implements io.ReaderAt, such as *os.File.
+//
+// Reference: https://github.com/opencontainers/image-spec/blob/v1.1.1/image-layout.md
+type TarWriter struct {
+	// lock guards the fields below and serializes the writes.
+	lock      sync.Mutex
+	cw        *countingWriter
+	compress  io.WriteCloser
+	tw        *tar.Writer
+	readerAt  io.ReaderAt
+	started   bool
+	closed    bool
+	err       error
+	dirs      set.Set[string]
+	blobs     map[digest.Digest]tarBlob
+	manifests map[digest.Digest][]byte
+	tags      map[string]ocispec.Descriptor
+}
+
+// tarBlob records a blob written to the archive.
+type tarBlob struct {
+	desc ocispec.Descriptor
+	// offset is the offset of the blob content in the uncompressed archive.
+	offset int64
+}
+
+// NewTarWriter creates a TarWriter writing an OCI image layout archive to w,
+// applying the compression in opts. Export options other than the compression
+// are ignored. The caller is responsible for closing w after closing the
+// returned TarWriter.
+func NewTarWriter(w io.Writer, opts ExportOptions) (*TarWriter, error) {
+	cw := &countingWriter{w: w}
+	compress, err := compressWriter(cw, opts)
+	if err != nil {
+		return nil, err
+	}
+	t := &TarWriter{
+		cw:        cw,
+		compress:  compress,
+		tw:        tar.NewWriter(compress),
+		dirs:      set.New[string](),
+		blobs:     make(map[digest.Digest]tarBlob),
+		manifests: make(map[digest.Digest][]byte),
+		tags:      make(map[string]ocispec.Descriptor),
+	}
+	if ra, ok := w.(io.ReaderAt); ok && opts.Compression == CompressionNone {
+		t.readerAt = ra
+	}
+	return t, nil
+}
+
+// Push appends the content, matching the expected descriptor, to the archive.
+func (t *TarWriter) Push(ctx context.Context, expected ocispec.Descriptor, reader io.Reader) error {
+	if expected.Size < 0 {
+		return fmt.Errorf("%s: %s: %w", expected.Digest, expected.MediaType, content.ErrInvalidDescriptorSize)
+	}
+	blobPath, err := blobPath(expected.Digest)
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", expected.Digest, expected.MediaType, errdef.ErrInvalidDigest)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.checkWritable(); err != nil {
+		return err
+	}
+	if _, ok := t.blobs[expected.Digest]; ok {
+		return fmt.Errorf("%s: %s: %w", expected.Digest, expected.MediaType, errdef.ErrAlreadyExists)
+	}
+	if err := isContextDone(ctx); err != nil {
+		return err
+	}
+
+	var manifest *bytes.Buffer
+	if descriptor.IsManifest(expected) {
+		// the buffer grows with the content read, which is bounded by the
+		// expected size as verified in writeBlob, rather than preallocating
+		// the size claimed by the caller
+		manifest = new(bytes.Buffer)
+		reader = io.TeeReader(reader, manifest)
+	}
+	offset, err := t.writeBlob(blobPath, expected, reader)
+	if err != nil {
+		return err
+	}
+	t.blobs[expected.Digest] = tarBlob{
+		desc:   descriptor.Plain(expected),
+		offset: offset,
+	}
+	if manifest != nil {
+		t.manifests[expected.Digest] = manifest.Bytes()
+	}
+	return nil
+}
+
+// writeBlob writes the blob to the archive, and returns the offset of the
+// content in the uncompressed archive.
+// Any failure after writing the header of the blob breaks the archive.
+func (t *TarWriter) writeBlob(name string, expected ocispec.Descriptor, reader io.Reader) (int64, error) {
+	if err := t.start(); err != nil {
+		return 0, err
+	}
+	if err := writeTarDirs(t.tw, path.Dir(name), t.dirs); err != nil {
+		t.err = err
+		return 0, err
+	}
+	if err := t.tw.WriteHeader(tarHeader(name, tar.TypeReg, 0444, expected.Size)); err != nil {
+		t.err = fmt.Errorf("failed to write tar header of %s: %w", name, err)
+		return 0, t.err
+	}
+	offset := t.cw.n
+
+	vr := content.NewVerifyReader(reader, expected)
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	if _, err := io.CopyBuffer(t.tw, vr, *buf); err != nil {
+		t.err = fmt.Errorf("failed to write %s: %w", name, err)
+		return 0, t.err
+	}
+	if err := vr.Verify(); err != nil {
+		t.err = fmt.Errorf("failed to write %s: %w", name, err)
+		return 0, t.err
+	}
+	return offset, nil
+}
+
+// start writes the `oci-layout` file if not written.
+func (t *TarWriter) start() error {
+	if t.started {
+		return nil
+	}
+	layoutJSON, err := json.Marshal(ocispec.ImageLayout{
+		Version: ocispec.ImageLayoutVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal OCI layout file: %w", err)
+	}
+	if err := writeTarFile(t.tw, ocispec.ImageLayoutFile, layoutJSON); err != nil {
+		t.err = err
+		return err
+	}
+	t.started = true
+	return nil
+}
+
+// writeFile writes a regular file with the given content to the archive.
+func (t *TarWriter) writeFile(name string, data []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.checkWritable(); err != nil {
+		return err
+	}
+	if err := t.start(); err != nil {
+		return err
+	}
+	if err := writeTarFile(t.tw, name, data); err != nil {
+		t.err = err
+		return err
+	}
+	return nil
+}
+
+// Fetch fetches the content identified by the descriptor.
+// Blobs other than manifests can be fetched only if the archive is
+// uncompressed and the underlying writer implements io.ReaderAt.
+func (t *TarWriter) Fetch(_ context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	blob, ok := t.blobs[target.Digest]
+	if !ok {
+		return nil, fmt.Errorf("%s: %s: %w", target.Digest, target.MediaType, errdef.ErrNotFound)
+	}
+	if manifest, ok := t.manifests[target.Digest]; ok {
+		return io.NopCloser(bytes.NewReader(manifest)), nil
+	}
+	if t.readerAt == nil {
+		return nil, fmt.Errorf("%s: %s: fetching blobs from the archive: %w", target.Digest, target.MediaType, errdef.ErrUnsupported)
+	}
+	return io.NopCloser(io.NewSectionReader(t.readerAt, blob.offset, blob.desc.Size)), nil
+}
+
+// Exists returns true if the described content has been pushed.
+func (t *TarWriter) Exists(_ context.Context, target ocispec.Descriptor) (bool, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	_, ok := t.blobs[target.Digest]
+	return ok, nil
+}
+
+// Tag associates a reference string (e.g. "latest") with the descriptor,
+// which will be listed in the `index.json` file written on Close.
+func (t *TarWriter) Tag(_ context.Context, desc ocispec.Descriptor, reference string) error {
+	if err := validateReference(reference); err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.checkWritable(); err != nil {
+		return err
+	}
+	if _, ok := t.blobs[desc.Digest]; !ok {
+		return fmt.Errorf("%s: %s: %w", desc.Digest, desc.MediaType, errdef.ErrNotFound)
+	}
+	t.tags[reference] = deleteAnnotationRefName(desc)
+	return nil
+}
+
+// Resolve resolves a reference to a descriptor.
+//   - If the reference to be resolved is a tag, the returned descriptor will be
+//     the full descriptor tagged.
+//   - If the reference is a digest, the returned descriptor will be a
+//     plain descriptor (containing only the digest, media type and size).
+func (t *TarWriter) Resolve(_ context.Context, reference string) (ocispec.Descriptor, error) {
+	if reference == "" {
+		return ocispec.Descriptor{}, errdef.ErrMissingReference
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if desc, ok := t.tags[reference]; ok {
+		if reference == desc.Digest.String() {
+			return descriptor.Plain(desc), nil
+		}
+		return desc, nil
+	}
+	if blob, ok := t.blobs[digest.Digest(reference)]; ok {
+		return blob.desc, nil
+	}
+	return ocispec.Descriptor{}, fmt.Errorf("%s: %w", reference, errdef.ErrNotFound)
+}
+
+// Close writes the `index.json` file and completes the archive. Close does
+// not close the underlying writer.
+// The descriptors are listed in `index.json` in the order of the references.
+func (t *TarWriter) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.checkWritable(); err != nil {
+		return err
+	}
+	t.closed = true
+	if err := t.start(); err != nil {
+		return err
+	}
+
+	references := make([]string, 0, len(t.tags))
+	for reference := range t.tags {
+		references = append(references, reference)
+	}
+	slices.Sort(references)
+	manifests := make([]ocispec.Descriptor, 0, len(references))
+	tagged := set.New[digest.Digest]()
+	for _, reference := range references {
+		desc := t.tags[reference]
+		if reference != desc.Digest.String() {
+			desc.Annotations = withRefName(desc.Annotations, reference)
+			manifests = append(manifests, desc)
+			tagged.Add(desc.Digest)
+		}
+	}
+	for _, reference := range references {
+		desc := t.tags[reference]
+		if reference == desc.Digest.String() && !tagged.Contains(desc.Digest) {
+			manifests = append(manifests, desc)
+		}
+	}
+	indexJSON, err := json.Marshal(ocispec.Index{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2, // historical value
+		},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: manifests,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal index file: %w", err)
+	}
+	if err := writeTarFile(t.tw, ocispec.ImageIndexFile, indexJSON); err != nil {
+		return err
+	}
+	if err := t.tw.Close(); err != nil {
+		return fmt.Errorf("failed to close tar writer: %w", err)
+	}
+	if err := t.compress.Close(); err != nil {
+		return fmt.Errorf("failed to close compressor: %w", err)
+	}
+	return nil
+}
+
+// checkWritable returns an error if the archive is closed or broken.
+func (t *TarWriter) checkWritable() error {
+	if t.closed {
+		return ErrTarWriterClosed
+	}
+	return t.err
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write writes p to the underlying writer.
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,258 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/errdef"
+)
+
+func TestTarWriterInterface(t *testing.T) {
+	var tw interface{} = &TarWriter{}
+	if _, ok := tw.(oras.Target); !ok {
+		t.Error("&TarWriter{} does not conform oras.Target")
+	}
+}
+
+// pushToMemory pushes a blob into the memory store and returns its descriptor.
+func pushToMemory(t *testing.T, m *memory.Store, mediaType string, blob []byte) ocispec.Descriptor {
+	t.Helper()
+	desc := content.NewDescriptorFromBytes(mediaType, blob)
+	if err := m.Push(context.Background(), desc, bytes.NewReader(blob)); err != nil {
+		t.Fatal("Memory.Push() error =", err)
+	}
+	return desc
+}
+
+func TestTarWriter_Copy(t *testing.T) {
+	ctx := context.Background()
+	src := memory.New()
+	config := pushToMemory(t, src, "test/config", []byte("config"))
+	layer := pushToMemory(t, src, "test/layer", []byte("layer"))
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+	if err != nil {
+		t.Fatal("json.Marshal() error =", err)
+	}
+	manifest := pushToMemory(t, src, ocispec.MediaTypeImageManifest, manifestJSON)
+	if err := src.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatal("Memory.Tag() error =", err)
+	}
+
+	tarPath := filepath.Join(t.TempDir(), "layout.tar")
+	f, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatal("os.Create() error =", err)
+	}
+	defer f.Close()
+	dst, err := NewTarWriter(f, ExportOptions{})
+	if err != nil {
+		t.Fatal("NewTarWriter() error =", err)
+	}
+	if _, err := oras.Copy(ctx, src, "v1", dst, "", oras.DefaultCopyOptions); err != nil {
+		t.Fatal("oras.Copy() error =", err)
+	}
+
+	// resolve and fetch before closing
+	got, err := dst.Resolve(ctx, "v1")
+	if err != nil {
+		t.Fatal("TarWriter.Resolve() error =", err)
+	}
+	if !content.Equal(got, manifest) {
+		t.Errorf("TarWriter.Resolve() = %v, want %v", got, manifest)
+	}
+	for _, desc := range []ocispec.Descriptor{manifest, config, layer} {
+		data, err := content.FetchAll(ctx, dst, desc)
+		if err != nil {
+			t.Fatalf("TarWriter.Fetch(%s) error = %v", desc.Digest, err)
+		}
+		want, err := content.FetchAll(ctx, src, desc)
+		if err != nil {
+			t.Fatal("Memory.Fetch() error =", err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("TarWriter.Fetch(%s) = %s, want %s", desc.Digest, data, want)
+		}
+	}
+
+	if err := dst.Close(); err != nil {
+		t.Fatal("TarWriter.Close() error =", err)
+	}
+	if err := dst.Close(); !errors.Is(err, ErrTarWriterClosed) {
+		t.Errorf("TarWriter.Close() error = %v, wantErr %v", err, ErrTarWriterClosed)
+	}
+
+	// read the archive back
+	rs, err := NewFromTar(ctx, tarPath)
+	if err != nil {
+		t.Fatal("NewFromTar() error =", err)
+	}
+	got, err = rs.Resolve(ctx, "v1")
+	if err != nil {
+		t.Fatal("ReadOnlyStore.Resolve() error =", err)
+	}
+	if !content.Equal(got, manifest) {
+		t.Errorf("ReadOnlyStore.Resolve() = %v, want %v", got, manifest)
+	}
+	report, err := rs.Verify(ctx)
+	if err != nil {
+		t.Fatal("ReadOnlyStore.Verify() error =", err)
+	}
+	if !report.OK() || report.BlobsChecked != 3 {
+		t.Errorf("ReadOnlyStore.Verify() = %v, want 3 blobs with no issue", report)
+	}
+}
+
+func TestTarWriter_Gzip(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	tw, err := NewTarWriter(&buf, ExportOptions{Compression: CompressionGzip})
+	if err != nil {
+		t.Fatal("NewTarWriter() error =", err)
+	}
+	blob := []byte("hello")
+	desc := content.NewDescriptorFromBytes("test/layer", blob)
+	if err := tw.Push(ctx, desc, bytes.NewReader(blob)); err != nil {
+		t.Fatal("TarWriter.Push() error =", err)
+	}
+	if _, err := tw.Fetch(ctx, desc); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("TarWriter.Fetch() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal("TarWriter.Close() error =", err)
+	}
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal("gzip.NewReader() error =", err)
+	}
+	archive, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal("io.ReadAll() error =", err)
+	}
+	want := []string{"oci-layout", "blobs/", "blobs/sha256/", "blobs/sha256/" + desc.Digest.Encoded(), "index.json"}
+	if got := tarEntryNames(t, archive); !reflect.DeepEqual(got, want) {
+		t.Errorf("archive entries = %v, want %v", got, want)
+	}
+}
+
+func TestTarWriter_Error(t *testing.T) {
+	ctx := context.Background()
+	tw, err := NewTarWriter(io.Discard, ExportOptions{})
+	if err != nil {
+		t.Fatal("NewTarWriter() error =", err)
+	}
+	blob := []byte("hello")
+	desc := content.NewDescriptorFromBytes("test/layer", blob)
+	if err := tw.Tag(ctx, desc, "v1"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("TarWriter.Tag() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if err := tw.Push(ctx, desc, bytes.NewReader(blob)); err != nil {
+		t.Fatal("TarWriter.Push() error =", err)
+	}
+	if err := tw.Push(ctx, desc, bytes.NewReader(blob)); !errors.Is(err, errdef.ErrAlreadyExists) {
+		t.Errorf("TarWriter.Push() error = %v, wantErr %v", err, errdef.ErrAlreadyExists)
+	}
+	if err := tw.Tag(ctx, desc, ""); !errors.Is(err, errdef.ErrMissingReference) {
+		t.Errorf("TarWriter.Tag() error = %v, wantErr %v", err, errdef.ErrMissingReference)
+	}
+	if _, err := tw.Resolve(ctx, "missing"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("TarWriter.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+
+	// a broken push breaks the archive
+	bad := content.NewDescriptorFromBytes("test/layer", []byte("world"))
+	if err := tw.Push(ctx, bad, bytes.NewReader([]byte("wrong"))); !errors.Is(err, content.ErrMismatchedDigest) {
+		t.Errorf("TarWriter.Push() error = %v, wantErr %v", err, content.ErrMismatchedDigest)
+	}
+	other := content.NewDescriptorFromBytes("test/layer", []byte("other"))
+	if err := tw.Push(ctx, other, bytes.NewReader([]byte("other"))); !errors.Is(err, content.ErrMismatchedDigest) {
+		t.Errorf("TarWriter.Push() error = %v, wantErr %v", err, content.ErrMismatchedDigest)
+	}
+	if err := tw.Close(); !errors.Is(err, content.ErrMismatchedDigest) {
+		t.Errorf("TarWriter.Close() error = %v, wantErr %v", err, content.ErrMismatchedDigest)
+	}
+}
+
+func TestTarWriter_Push_InvalidSize(t *testing.T) {
+	ctx := context.Background()
+	tw, err := NewTarWriter(io.Discard, ExportOptions{})
+	if err != nil {
+		t.Fatal("NewTarWriter() error =", err)
+	}
+	manifest := []byte(`{"schemaVersion":2}`)
+	desc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageManifest, manifest)
+	desc.Size = -1
+	if err := tw.Push(ctx, desc, bytes.NewReader(manifest)); !errors.Is(err, content.ErrInvalidDescriptorSize) {
+		t.Errorf("TarWriter.Push() error = %v, wantErr %v", err, content.ErrInvalidDescriptorSize)
+	}
+
+	// the rejected push does not break the archive
+	blob := []byte("hello")
+	if err := tw.Push(ctx, content.NewDescriptorFromBytes("test/layer", blob), bytes.NewReader(blob)); err != nil {
+		t.Fatal("TarWriter.Push() error =", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal("TarWriter.Close() error =", err)
+	}
+
+	// a huge size is not preallocated
+	tw, err = NewTarWriter(io.Discard, ExportOptions{})
+	if err != nil {
+		t.Fatal("NewTarWriter() error =", err)
+	}
+	desc.Size = 1 << 50
+	if err := tw.Push(ctx, desc, bytes.NewReader(manifest)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("TarWriter.Push() error = %v, wantErr %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestTarWriter_Close_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	tw, err := NewTarWriter(&buf, ExportOptions{})
+	if err != nil {
+		t.Fatal("NewTarWriter() error =", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal("TarWriter.Close() error =", err)
+	}
+	want := []string{"oci-layout", "index.json"}
+	if got := tarEntryNames(t, buf.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("archive entries = %v, want %v", got, want)
+	}
+	if _, err := NewTarWriter(&buf, ExportOptions{Compression: "unknown"}); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("NewTarWriter() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+}
@@ -0,0 +1,436 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/internal/container/set"
+	"oras.land/oras-go/v2/internal/descriptor"
+	"oras.land/oras-go/v2/internal/manifestutil"
+)
+
+const (
+	// ingestDir is the directory of the temporary ingest files.
+	ingestDir = "ingest"
+	// quarantineDir is the directory where the corrupt blobs are moved to on
+	// repair.
+	quarantineDir = "quarantine"
+)
+
+// VerifyIssueType is the type of an issue found by verifying an OCI layout.
+type VerifyIssueType string
+
+const (
+	// VerifyIssueCorruptBlob indicates that the content of a blob does not
+	// match the digest of its path.
+	VerifyIssueCorruptBlob VerifyIssueType = "CorruptBlob"
+	// VerifyIssueMissingBlob indicates that a descriptor reachable from
+	// `index.json` refers to a blob not in the layout.
+	VerifyIssueMissingBlob VerifyIssueType = "MissingBlob"
+	// VerifyIssueSizeMismatch indicates that the size of a blob does not match
+	// the size of the descriptor reachable from `index.json`.
+	VerifyIssueSizeMismatch VerifyIssueType = "SizeMismatch"
+	// VerifyIssueOrphanIngestFile indicates a temporary ingest file left
+	// behind, typically by a crashed or an in-progress push.
+	VerifyIssueOrphanIngestFile VerifyIssueType = "OrphanIngestFile"
+	// VerifyIssueDanglingReferrer indicates a referrer manifest whose subject
+	// is not in the layout.
+	VerifyIssueDanglingReferrer VerifyIssueType = "DanglingReferrer"
+)
+
+// VerifyIssue describes an issue found by verifying an OCI layout.
+type VerifyIssue struct {
+	// Type is the type of the issue.
+	Type VerifyIssueType
+	// Path is the slash-separated path, relative to the root of the layout,
+	// of the file concerned, if any.
+	Path string
+	// Descriptor describes the content concerned, if any. For corrupt blobs,
+	// it is the descriptor derived from the path and the size of the blob.
+	Descriptor ocispec.Descriptor
+	// ActualDigest is the digest computed from the content of a corrupt blob.
+	ActualDigest digest.Digest
+	// ActualSize is the actual size of a blob whose size does not match the
+	// descriptor.
+	ActualSize int64
+	// Subject is the missing subject of a dangling referrer.
+	Subject *ocispec.Descriptor
+	// QuarantinePath is the slash-separated path, relative to the root of the
+	// layout, where a corrupt blob is moved to on repair, in the form of
+	// `quarantine/<algorithm>/<encoded>.<timestamp>`.
+	QuarantinePath string
+}
+
+// VerifyReport is the result of verifying an OCI layout.
+type VerifyReport struct {
+	// BlobsChecked is the number of blobs re-hashed.
+	BlobsChecked int
+	// BytesChecked is the total size of the blobs re-hashed.
+	BytesChecked int64
+	// Issues are the issues found, in the order of discovery.
+	Issues []VerifyIssue
+}
+
+// OK returns true if no issue is found.
+func (r *VerifyReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// VerifyOptions contains parameters for Store.Verify.
+type VerifyOptions struct {
+	// Repair, if set to true, moves the corrupt blobs out of the blob
+	// directory into the `quarantine` directory under the root of the layout,
+	// so that the blobs can be pushed again. The quarantined blobs are named
+	// after their digests and the time of the repair, so that the blobs
+	// quarantined earlier are kept.
+	Repair bool
+}
+
+// Verify checks the integrity of the OCI layout on the file system. Verify
+// re-hashes every blob against the digest of its path, checks that every
+// descriptor reachable from the saved `index.json` refers to a blob with
+// matching size and digest, and reports orphan ingest files and dangling
+// referrers. The issues found are returned in the report, while the returned
+// error is reserved for failures of the verification itself.
+//
+// The ingest files of pushes in progress in other processes are reported as
+// orphans. The files under the root of the layout other than the blobs, such as
+// `index.json.lock` and the temporary `index.json.*.tmp` files created when
+// saving the index, are ignored.
+//
+// The scan runs concurrently with the other operations except Delete and GC.
+// If opts.Repair is set, the store is exclusively locked only while moving the
+// corrupt blobs.
+func (s *Store) Verify(ctx context.Context, opts VerifyOptions) (*VerifyReport, error) {
+	report, err := s.verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Repair {
+		s.sync.Lock()
+		defer s.sync.Unlock()
+
+		if err := s.quarantine(report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// verify verifies the OCI layout of the store, allowing the concurrent
+// operations other than Delete and GC.
+func (s *Store) verify(ctx context.Context) (*VerifyReport, error) {
+	s.sync.RLock()
+	defer s.sync.RUnlock()
+
+	return verifyLayout(ctx, os.DirFS(s.root))
+}
+
+// Verify checks the integrity of the OCI layout. Verify re-hashes every blob
+// against the digest of its path, checks that every descriptor reachable from
+// `index.json` refers to a blob with matching size and digest, and reports
+// orphan ingest files and dangling referrers. The issues found are returned
+// in the report, while the returned error is reserved for failures of the
+// verification itself.
+func (s *ReadOnlyStore) Verify(ctx context.Context) (*VerifyReport, error) {
+	return verifyLayout(ctx, s.fsys)
+}
+
+// quarantine moves the corrupt blobs in the report to the quarantine
+// directory. The blobs fixed or removed since the verification are skipped.
+// The caller must hold s.sync exclusively.
+func (s *Store) quarantine(report *VerifyReport) error {
+	for i := range report.Issues {
+		issue := &report.Issues[i]
+		if issue.Type != VerifyIssueCorruptBlob {
+			continue
+		}
+		blobPath := filepath.Join(s.root, filepath.FromSlash(issue.Path))
+		fi, err := os.Stat(blobPath)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return fmt.Errorf("failed to quarantine %s: %w", issue.Path, err)
+		}
+		if verifyFile(ocispec.Descriptor{Digest: issue.Descriptor.Digest, Size: fi.Size()}, blobPath) {
+			// the blob has been pushed again
+			continue
+		}
+
+		dgst := issue.Descriptor.Digest
+		dir := path.Join(quarantineDir, dgst.Algorithm().String())
+		if err := ensureDir(filepath.Join(s.root, filepath.FromSlash(dir))); err != nil {
+			return err
+		}
+		target, err := s.reserveQuarantinePath(dir, dgst.Encoded())
+		if err != nil {
+			return fmt.Errorf("failed to quarantine %s: %w", issue.Path, err)
+		}
+		if err := os.Rename(blobPath, filepath.Join(s.root, filepath.FromSlash(target))); err != nil {
+			return fmt.Errorf("failed to quarantine %s: %w", issue.Path, err)
+		}
+		issue.QuarantinePath = target
+	}
+	return nil
+}
+
+// reserveQuarantinePath creates an empty file in dir named after the encoded
+// digest and the current time, so that the blobs quarantined earlier with the
+// same digest are never overwritten. The returned path is slash-separated and
+// relative to the root of the layout.
+func (s *Store) reserveQuarantinePath(dir string, encoded string) (string, error) {
+	name := encoded + "." + time.Now().UTC().Format("20060102T150405.000000000Z")
+	for try := 0; ; try++ {
+		target := path.Join(dir, name)
+		if try > 0 {
+			target += "." + strconv.Itoa(try)
+		}
+		fp, err := os.OpenFile(filepath.Join(s.root, filepath.FromSlash(target)), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if err == nil {
+			return target, fp.Close()
+		}
+		if !errors.Is(err, fs.ErrExist) || try >= 10000 {
+			return "", err
+		}
+	}
+}
+
+// verifyLayout verifies the OCI layout in fsys.
+func verifyLayout(ctx context.Context, fsys fs.FS) (*VerifyReport, error) {
+	report := &VerifyReport{}
+	if err := verifyBlobs(ctx, fsys, report); err != nil {
+		return nil, err
+	}
+	if err := verifyReachable(ctx, fsys, report); err != nil {
+		return nil, err
+	}
+	if err := verifyIngest(fsys, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// verifyBlobs re-hashes every blob in fsys against the digest of its path.
+// Files of unknown or unavailable algorithms, or with invalid digests are
+// skipped.
+func verifyBlobs(ctx context.Context, fsys fs.FS, report *VerifyReport) error {
+	algDirs, err := fs.ReadDir(fsys, ocispec.ImageBlobsDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, algDir := range algDirs {
+		alg := algDir.Name()
+		if !algDir.IsDir() || !isKnownAlgorithm(alg) || !digest.Algorithm(alg).Available() {
+			continue
+		}
+		algPath := path.Join(ocispec.ImageBlobsDir, alg)
+		digestEntries, err := fs.ReadDir(fsys, algPath)
+		if err != nil {
+			return err
+		}
+		for _, digestEntry := range digestEntries {
+			if err := isContextDone(ctx); err != nil {
+				return err
+			}
+			if digestEntry.IsDir() {
+				continue
+			}
+			blobDigest := digest.NewDigestFromEncoded(digest.Algorithm(alg), digestEntry.Name())
+			if err := blobDigest.Validate(); err != nil {
+				// skip irrelevant content
+				continue
+			}
+			blobPath := path.Join(algPath, digestEntry.Name())
+			actual, size, err := digestFile(fsys, blobPath, blobDigest.Algorithm())
+			if err != nil {
+				return err
+			}
+			report.BlobsChecked++
+			report.BytesChecked += size
+			if actual != blobDigest {
+				report.Issues = append(report.Issues, VerifyIssue{
+					Type: VerifyIssueCorruptBlob,
+					Path: blobPath,
+					Descriptor: ocispec.Descriptor{
+						MediaType: descriptor.DefaultMediaType,
+						Digest:    blobDigest,
+						Size:      size,
+					},
+					ActualDigest: actual,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// digestFile computes the digest and the size of the file at path in fsys.
+func digestFile(fsys fs.FS, path string, alg digest.Algorithm) (digest.Digest, int64, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	digester := alg.Digester()
+	buf := bufPool.Get().(*[]byte)
+	defer bufPool.Put(buf)
+	size, err := io.CopyBuffer(digester.Hash(), f, *buf)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return digester.Digest(), size, nil
+}
+
+// verifyReachable checks that every descriptor reachable from `index.json`
+// in fsys refers to a blob with matching size, and finds dangling referrers.
+// The digests of the blobs are verified by verifyBlobs.
+func verifyReachable(ctx context.Context, fsys fs.FS, report *VerifyReport) error {
+	indexFile, err := fsys.Open(ocispec.ImageIndexFile)
+	if err != nil {
+		return fmt.Errorf("failed to open index file: %w", err)
+	}
+	defer indexFile.Close()
+	var index ocispec.Index
+	if err := json.NewDecoder(indexFile).Decode(&index); err != nil {
+		return fmt.Errorf("failed to decode index file: %w", err)
+	}
+
+	storage := NewStorageFromFS(fsys)
+	visited := set.New[digest.Digest]()
+	queue := make([]ocispec.Descriptor, 0, len(index.Manifests))
+	for _, desc := range index.Manifests {
+		queue = append(queue, descriptor.Plain(desc))
+	}
+	for len(queue) > 0 {
+		if err := isContextDone(ctx); err != nil {
+			return err
+		}
+		node := queue[0]
+		queue = queue[1:]
+		if visited.Contains(node.Digest) {
+			continue
+		}
+		visited.Add(node.Digest)
+
+		if !verifyDescriptor(fsys, node, report) || !descriptor.IsManifest(node) {
+			continue
+		}
+		successors, err := content.Successors(ctx, storage, node)
+		if err != nil {
+			// corrupt or invalid manifests cannot be traversed
+			continue
+		}
+		subject, err := manifestutil.Subject(ctx, storage, node)
+		if err != nil {
+			continue
+		}
+		for _, successor := range successors {
+			if subject != nil && content.Equal(successor, *subject) && !blobExists(fsys, successor) {
+				report.Issues = append(report.Issues, VerifyIssue{
+					Type:       VerifyIssueDanglingReferrer,
+					Descriptor: node,
+					Subject:    subject,
+				})
+				continue
+			}
+			queue = append(queue, successor)
+		}
+	}
+	return nil
+}
+
+// verifyDescriptor checks that the blob described by desc exists in fsys
+// with matching size, and reports the issue otherwise.
+// Returns true if the blob is verified.
+func verifyDescriptor(fsys fs.FS, desc ocispec.Descriptor, report *VerifyReport) bool {
+	blobPath, err := blobPath(desc.Digest)
+	if err != nil {
+		report.Issues = append(report.Issues, VerifyIssue{
+			Type:       VerifyIssueMissingBlob,
+			Descriptor: desc,
+		})
+		return false
+	}
+	fi, err := fs.Stat(fsys, blobPath)
+	if err != nil {
+		report.Issues = append(report.Issues, VerifyIssue{
+			Type:       VerifyIssueMissingBlob,
+			Path:       blobPath,
+			Descriptor: desc,
+		})
+		return false
+	}
+	if fi.Size() != desc.Size {
+		report.Issues = append(report.Issues, VerifyIssue{
+			Type:       VerifyIssueSizeMismatch,
+			Path:       blobPath,
+			Descriptor: desc,
+			ActualSize: fi.Size(),
+		})
+		return false
+	}
+	return true
+}
+
+// blobExists returns true if the blob described by desc exists in fsys.
+func blobExists(fsys fs.FS, desc ocispec.Descriptor) bool {
+	blobPath, err := blobPath(desc.Digest)
+	if err != nil {
+		return false
+	}
+	_, err = fs.Stat(fsys, blobPath)
+	return err == nil
+}
+
+// verifyIngest reports the files in the ingest directory of fsys.
+func verifyIngest(fsys fs.FS, report *VerifyReport) error {
+	entries, err := fs.ReadDir(fsys, ingestDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		report.Issues = append(report.Issues, VerifyIssue{
+			Type: VerifyIssueOrphanIngestFile,
+			Path: path.Join(ingestDir, entry.Name()),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+)
+
+// pushBlob pushes a blob into the store and returns its descriptor.
+func pushBlob(t *testing.T, s *Store, mediaType string, blob []byte) ocispec.Descriptor {
+	t.Helper()
+	desc := content.NewDescriptorFromBytes(mediaType, blob)
+	if err := s.Push(context.Background(), desc, bytes.NewReader(blob)); err != nil {
+		t.Fatal("Store.Push() error =", err)
+	}
+	return desc
+}
+
+// pushManifest pushes an image manifest into the store and returns its
+// descriptor.
+func pushManifest(t *testing.T, s *Store, subject *ocispec.Descriptor, config ocispec.Descriptor, layers ...ocispec.Descriptor) ocispec.Descriptor {
+	t.Helper()
+	manifest := ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Subject:   subject,
+		Layers:    layers,
+	}
+	manifest.SchemaVersion = 2
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal("json.Marshal() error =", err)
+	}
+	return pushBlob(t, s, ocispec.MediaTypeImageManifest, manifestJSON)
+}
+
+func TestStore_Verify(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	manifest := pushManifest(t, s, nil, config, layer)
+	if err := s.Tag(ctx, manifest, "latest"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	pushManifest(t, s, &manifest, config)
+
+	report, err := s.Verify(ctx, VerifyOptions{})
+	if err != nil {
+		t.Fatal("Store.Verify() error =", err)
+	}
+	if !report.OK() {
+		t.Errorf("Store.Verify().Issues = %v, want none", report.Issues)
+	}
+	if got, want := report.BlobsChecked, 4; got != want {
+		t.Errorf("Store.Verify().BlobsChecked = %v, want %v", got, want)
+	}
+}
+
+func TestStore_Verify_Issues(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	config := pushBlob(t, s, "test/config", []byte("config"))
+	corrupt := pushBlob(t, s, "test/layer", []byte("corrupt"))
+	missing := pushBlob(t, s, "test/layer", []byte("missing"))
+	resized := pushBlob(t, s, "test/layer", []byte("resized"))
+	subject := pushManifest(t, s, nil, config)
+	manifest := pushManifest(t, s, nil, config, corrupt, missing, resized)
+	if err := s.Tag(ctx, manifest, "latest"); err != nil {
+		t.Fatal("Store.Tag() error =", err)
+	}
+	referrer := pushManifest(t, s, &subject, config, pushBlob(t, s, "test/layer", []byte("referrer")))
+
+	// damage the layout
+	blobFilePath := func(desc ocispec.Descriptor) string {
+		return filepath.Join(tempDir, "blobs", desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+	}
+	for _, p := range []string{blobFilePath(corrupt), blobFilePath(resized)} {
+		if err := os.Chmod(p, 0644); err != nil {
+			t.Fatal("os.Chmod() error =", err)
+		}
+	}
+	if err := os.WriteFile(blobFilePath(corrupt), []byte("tpurroc"), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+	if err := os.WriteFile(blobFilePath(resized), []byte("resized!"), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+	for _, p := range []string{blobFilePath(missing), blobFilePath(subject)} {
+		if err := os.Remove(p); err != nil {
+			t.Fatal("os.Remove() error =", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "ingest", "orphan_123"), []byte("orphan"), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+
+	report, err := s.Verify(ctx, VerifyOptions{Repair: true})
+	if err != nil {
+		t.Fatal("Store.Verify() error =", err)
+	}
+	issues := make(map[VerifyIssueType][]VerifyIssue)
+	for _, issue := range report.Issues {
+		issues[issue.Type] = append(issues[issue.Type], issue)
+	}
+	if got, want := len(report.Issues), 7; got != want {
+		t.Errorf("len(Store.Verify().Issues) = %v, want %v: %v", got, want, report.Issues)
+	}
+
+	// the resized blob is also corrupt
+	if got := issues[VerifyIssueCorruptBlob]; len(got) != 2 {
+		t.Errorf("corrupt blobs = %v, want 2", got)
+	} else {
+		issue := got[0]
+		if issue.Descriptor.Digest != corrupt.Digest {
+			issue = got[1]
+		}
+		if want := digest.FromBytes([]byte("tpurroc")); issue.ActualDigest != want {
+			t.Errorf("VerifyIssue.ActualDigest = %v, want %v", issue.ActualDigest, want)
+		}
+		if prefix := "quarantine/sha256/" + corrupt.Digest.Encoded() + "."; !strings.HasPrefix(issue.QuarantinePath, prefix) {
+			t.Errorf("VerifyIssue.QuarantinePath = %v, want prefix %v", issue.QuarantinePath, prefix)
+		}
+		if _, err := os.Stat(blobFilePath(corrupt)); !os.IsNotExist(err) {
+			t.Errorf("corrupt blob is not removed, error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, filepath.FromSlash(issue.QuarantinePath))); err != nil {
+			t.Errorf("corrupt blob is not quarantined, error = %v", err)
+		}
+	}
+	// the subject is also referenced by index.json
+	if got := issues[VerifyIssueMissingBlob]; len(got) != 2 ||
+		!content.Equal(got[0].Descriptor, subject) || !content.Equal(got[1].Descriptor, missing) {
+		t.Errorf("missing blobs = %v, want %v and %v", got, subject, missing)
+	}
+	if got := issues[VerifyIssueSizeMismatch]; len(got) != 1 || got[0].ActualSize != 8 {
+		t.Errorf("size mismatches = %v, want %v with size 8", got, resized)
+	}
+	if got := issues[VerifyIssueOrphanIngestFile]; len(got) != 1 || got[0].Path != "ingest/orphan_123" {
+		t.Errorf("orphan ingest files = %v, want %v", got, "ingest/orphan_123")
+	}
+	if got := issues[VerifyIssueDanglingReferrer]; len(got) != 1 ||
+		!content.Equal(got[0].Descriptor, referrer) || !content.Equal(*got[0].Subject, subject) {
+		t.Errorf("dangling referrers = %v, want %v", got, referrer)
+	}
+
+	// the corrupt blobs are quarantined
+	report, err = s.Verify(ctx, VerifyOptions{})
+	if err != nil {
+		t.Fatal("Store.Verify() error =", err)
+	}
+	for _, issue := range report.Issues {
+		if issue.Type == VerifyIssueCorruptBlob {
+			t.Errorf("unexpected issue after repair: %v", issue)
+		}
+	}
+}
+
+func TestStore_Verify_QuarantineTwice(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	layer := []byte("layer")
+	desc := pushBlob(t, s, "test/layer", layer)
+	path := filepath.Join(tempDir, "blobs", "sha256", desc.Digest.Encoded())
+
+	var quarantined []string
+	for _, corrupted := range []string{"reyal", "lyaer"} {
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatal("os.Chmod() error =", err)
+		}
+		if err := os.WriteFile(path, []byte(corrupted), 0644); err != nil {
+			t.Fatal("os.WriteFile() error =", err)
+		}
+		report, err := s.Verify(ctx, VerifyOptions{Repair: true})
+		if err != nil {
+			t.Fatal("Store.Verify() error =", err)
+		}
+		if len(report.Issues) != 1 || report.Issues[0].QuarantinePath == "" {
+			t.Fatalf("Store.Verify().Issues = %v, want a quarantined blob", report.Issues)
+		}
+		quarantined = append(quarantined, report.Issues[0].QuarantinePath)
+		// push the blob again
+		if err := s.Push(ctx, desc, bytes.NewReader(layer)); err != nil {
+			t.Fatal("Store.Push() error =", err)
+		}
+	}
+
+	// the blob quarantined earlier is kept
+	if quarantined[0] == quarantined[1] {
+		t.Fatalf("VerifyIssue.QuarantinePath = %v, want unique paths", quarantined)
+	}
+	for i, want := range []string{"reyal", "lyaer"} {
+		got, err := os.ReadFile(filepath.Join(tempDir, filepath.FromSlash(quarantined[i])))
+		if err != nil {
+			t.Fatal("os.ReadFile() error =", err)
+		}
+		if string(got) != want {
+			t.Errorf("quarantined blob = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestReadOnlyStore_Verify(t *testing.T) {
+	ctx := context.Background()
+	for _, tarPath := range []string{
+		"testdata/hello-world.tar",
+		"testdata/hello-world-prefixed-path.tar",
+	} {
+		t.Run(tarPath, func(t *testing.T) {
+			s, err := NewFromTar(ctx, tarPath)
+			if err != nil {
+				t.Fatal("NewFromTar() error =", err)
+			}
+			report, err := s.Verify(ctx)
+			if err != nil {
+				t.Fatal("ReadOnlyStore.Verify() error =", err)
+			}
+			if got, want := report.BlobsChecked, 4; got != want {
+				t.Errorf("ReadOnlyStore.Verify().BlobsChecked = %v, want %v", got, want)
+			}
+			// the archive contains the manifests of a single platform only
+			for _, issue := range report.Issues {
+				if issue.Type != VerifyIssueMissingBlob {
+					t.Errorf("unexpected issue: %v", issue)
+				}
+			}
+			if got, want := len(report.Issues), 10; got != want {
+				t.Errorf("len(ReadOnlyStore.Verify().Issues) = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadOnlyStore_Verify_CorruptBlob(t *testing.T) {
+	tempDir := t.TempDir()
+	ctx := context.Background()
+	s, err := New(tempDir)
+	if err != nil {
+		t.Fatal("New() error =", err)
+	}
+	layer := pushBlob(t, s, "test/layer", []byte("layer"))
+	path := filepath.Join(tempDir, "blobs", "sha256", layer.Digest.Encoded())
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal("os.Chmod() error =", err)
+	}
+	if err := os.WriteFile(path, []byte("reyal"), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+
+	rs, err := NewFromFS(ctx, os.DirFS(tempDir))
+	if err != nil {
+		t.Fatal("NewFromFS() error =", err)
+	}
+	report, err := rs.Verify(ctx)
+	if err != nil {
+		t.Fatal("ReadOnlyStore.Verify() error =", err)
+	}
+	if got, want := len(report.Issues), 1; got != want {
+		t.Fatalf("len(ReadOnlyStore.Verify().Issues) = %v, want %v", got, want)
+	}
+	if got, want := report.Issues[0].Type, VerifyIssueCorruptBlob; got != want {
+		t.Errorf("VerifyIssue.Type = %v, want %v", got, want)
+	}
+	if got := report.Issues[0].QuarantinePath; got != "" {
+		t.Errorf("VerifyIssue.QuarantinePath = %v, want empty", got)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filelock provides advisory file locks for coordinating access
+// among processes.
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Lock acquires an exclusive lock of the lock file at the given path, blocking
+// until the lock is acquired. The lock file and its parent directories are
+// created if not exist.
+// The returned function releases the lock.
+func Lock(path string) (unlock func() error, err error) {
+	return lockPath(path, true)
+}
+
+// RLock acquires a shared lock of the lock file at the given path, blocking
+// until the lock is acquired. The lock file and its parent directories are
+// created if not exist.
+// The returned function releases the lock.
+func RLock(path string) (unlock func() error, err error) {
+	return lockPath(path, false)
+}
+
+// lockPath opens the lock file and locks it.
+func lockPath(path string, exclusive bool) (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock(f, exclusive); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		unlockErr := unlock(f)
+		if err := f.Close(); err != nil && unlockErr == nil {
+			return err
+		}
+		return unlockErr
+	}, nil
+}
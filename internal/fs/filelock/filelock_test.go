@@ -0,0 +1,84 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filelock
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "test.lock")
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	var acquired atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		unlock, err := RLock(path)
+		if err != nil {
+			t.Errorf("RLock() error = %v", err)
+			return
+		}
+		acquired.Store(true)
+		if err := unlock(); err != nil {
+			t.Errorf("unlock() error = %v", err)
+		}
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if acquired.Load() {
+		t.Fatal("RLock() acquired while the exclusive lock is held")
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock() error = %v", err)
+	}
+	<-done
+	if !acquired.Load() {
+		t.Error("RLock() not acquired after the exclusive lock is released")
+	}
+}
+
+func TestRLock_Shared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	unlock1, err := RLock(path)
+	if err != nil {
+		t.Fatalf("RLock() error = %v", err)
+	}
+	defer unlock1()
+
+	done := make(chan error)
+	go func() {
+		unlock2, err := RLock(path)
+		if err == nil {
+			err = unlock2()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RLock() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RLock() blocked by another shared lock")
+	}
+}
@@ -0,0 +1,58 @@
+//go:build windows
+
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filelock
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// lockfileExclusiveLock is the LOCKFILE_EXCLUSIVE_LOCK flag of LockFileEx.
+// Reference: https://learn.microsoft.com/windows/win32/api/fileapi/nf-fileapi-lockfileex
+const lockfileExclusiveLock = 0x00000002
+
+var (
+	modkernel32      = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+)
+
+// lock places a lock on the entire file.
+func lock(f *os.File, exclusive bool) error {
+	var flags uintptr
+	if exclusive {
+		flags = lockfileExclusiveLock
+	}
+	var ol syscall.Overlapped
+	r1, _, err := procLockFileEx.Call(f.Fd(), flags, 0, 0xFFFFFFFF, 0xFFFFFFFF, uintptr(unsafe.Pointer(&ol)))
+	if r1 == 0 {
+		return err
+	}
+	return nil
+}
+
+// unlock removes the lock on the entire file.
+func unlock(f *os.File) error {
+	var ol syscall.Overlapped
+	r1, _, err := procUnlockFileEx.Call(f.Fd(), 0, 0xFFFFFFFF, 0xFFFFFFFF, uintptr(unsafe.Pointer(&ol)))
+	if r1 == 0 {
+		return err
+	}
+	return nil
+}
@@ -17,6 +17,10 @@ package tarfs
 
 import (
 	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -24,6 +28,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"oras.land/oras-go/v2/errdef"
 )
@@ -31,10 +38,58 @@ import (
 // blockSize is the size of each block in a tar archive.
 const blockSize int64 = 512
 
+// DefaultMaxExtractedSize is the default maximum size of the uncompressed
+// archive extracted from a compressed archive.
+const DefaultMaxExtractedSize int64 = 32 << 30 // 32 GiB
+
+// magic numbers of the compressed archives.
+var (
+	gzipMagic = []byte{0x1f, 0x8b, 0x08}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
 // TarFS represents a file system (an fs.FS) based on a tar archive.
 type TarFS struct {
+	// path is the path to the uncompressed tar archive, which is the
+	// extraction cache if the archive is compressed.
 	path    string
 	entries map[string]*entry
+	// file is the open temporary extraction cache, if any, which is read
+	// instead of the file at path. The file is removed from the file system
+	// right after the extraction where supported, so that its space is freed
+	// once it is closed.
+	file *os.File
+	// size is the size of file.
+	size int64
+	// tempPath is the path to the temporary extraction cache to be removed
+	// on Close, if it cannot be removed while open.
+	tempPath string
+}
+
+// Options contains parameters for NewWithOptions.
+type Options struct {
+	// CacheDir is the directory where compressed archives are extracted to.
+	// The extracted archives are reused by subsequent opens of the same
+	// compressed archive, as identified by its path, size and modification
+	// time, and they are left for the caller to clean up.
+	// If empty, compressed archives are extracted to temporary files, which
+	// are removed right after the extraction while kept open, and are freed on
+	// Close. On platforms not supporting the removal of open files, such as
+	// Windows, the temporary files are removed on Close.
+	CacheDir string
+
+	// MaxExtractedSize is the maximum size of the uncompressed archive
+	// extracted from a compressed archive, limiting the disk space used by
+	// the extraction, for example, of a decompression bomb. The extraction
+	// fails with errdef.ErrSizeExceedsLimit if the limit is exceeded.
+	// If zero, DefaultMaxExtractedSize is used. If negative, the size is not
+	// limited.
+	MaxExtractedSize int64
+
+	// NewZstdReader creates a zstd decompressing reader reading from r. It is
+	// required by zstd compressed archives as zstd is not supported by the
+	// standard library.
+	NewZstdReader func(r io.Reader) (io.ReadCloser, error)
 }
 
 // entry represents an entry in a tar archive.
@@ -44,7 +99,17 @@ type entry struct {
 }
 
 // New returns a file system (an fs.FS) for a tar archive located at path.
+// Gzip compressed archives are extracted to a temporary file, which is
+// released on Close.
 func New(path string) (*TarFS, error) {
+	return NewWithOptions(path, Options{})
+}
+
+// NewWithOptions returns a file system (an fs.FS) for a tar archive located
+// at path, which is optionally compressed with gzip or zstd.
+// Compressed archives are extracted once to an uncompressed cache, which
+// serves the random reads of the files in the archive.
+func NewWithOptions(path string, opts Options) (*TarFS, error) {
 	pathAbs, err := filepath.Abs(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve absolute path for %s: %w", path, err)
@@ -53,12 +118,158 @@ func New(path string) (*TarFS, error) {
 		path:    pathAbs,
 		entries: make(map[string]*entry),
 	}
+	if err := tarfs.extract(opts); err != nil {
+		return nil, err
+	}
 	if err := tarfs.indexEntries(); err != nil {
+		tarfs.Close()
 		return nil, err
 	}
 	return tarfs, nil
 }
 
+// Close releases the temporary extraction cache, if any.
+func (tfs *TarFS) Close() error {
+	if tfs.file != nil {
+		if err := tfs.file.Close(); err != nil {
+			return err
+		}
+		tfs.file = nil
+	}
+	if tfs.tempPath == "" {
+		return nil
+	}
+	if err := os.Remove(tfs.tempPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	tfs.tempPath = ""
+	return nil
+}
+
+// extract extracts the archive to the extraction cache if it is compressed,
+// and points tfs.path to the cache.
+func (tfs *TarFS) extract(opts Options) error {
+	archive, err := os.Open(tfs.path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	magic := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(archive, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	magic = magic[:n]
+	var newReader func(r io.Reader) (io.ReadCloser, error)
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		newReader = func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		}
+	case bytes.HasPrefix(magic, zstdMagic):
+		if opts.NewZstdReader == nil {
+			return fmt.Errorf("%s: zstd compressed archive requires NewZstdReader: %w", tfs.path, errdef.ErrUnsupported)
+		}
+		newReader = opts.NewZstdReader
+	default:
+		// not compressed
+		return nil
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var cachePath string
+	if opts.CacheDir != "" {
+		fi, err := archive.Stat()
+		if err != nil {
+			return err
+		}
+		cachePath = filepath.Join(opts.CacheDir, cacheName(tfs.path, fi))
+		if _, err := os.Stat(cachePath); err == nil {
+			tfs.path = cachePath
+			return nil
+		}
+		if err := os.MkdirAll(opts.CacheDir, 0755); err != nil {
+			return err
+		}
+	}
+
+	tempDir := opts.CacheDir
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+	fp, err := os.CreateTemp(tempDir, "tarfs-*.tar")
+	if err != nil {
+		return fmt.Errorf("failed to create extraction cache: %w", err)
+	}
+	tempPath := fp.Name()
+	maxSize := opts.MaxExtractedSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxExtractedSize
+	}
+	size, err := decompress(fp, archive, newReader, maxSize)
+	if err != nil {
+		fp.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to extract %s: %w", tfs.path, err)
+	}
+
+	if cachePath == "" {
+		tfs.path = tempPath
+		tfs.file = fp
+		tfs.size = size
+		// remove the temporary cache while it is open, so that it does not
+		// outlive the file even if Close is not called
+		if err := os.Remove(tempPath); err != nil {
+			tfs.tempPath = tempPath
+		}
+		return nil
+	}
+	if err := fp.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to extract %s: %w", tfs.path, err)
+	}
+	// publish the cache atomically
+	if err := os.Rename(tempPath, cachePath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to save extraction cache: %w", err)
+	}
+	tfs.path = cachePath
+	return nil
+}
+
+// decompress decompresses the content read from r to w, and returns the size
+// of the decompressed content. If maxSize is not negative, decompress fails
+// once the size exceeds maxSize.
+func decompress(w io.Writer, r io.Reader, newReader func(r io.Reader) (io.ReadCloser, error), maxSize int64) (int64, error) {
+	rc, err := newReader(r)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	if maxSize < 0 {
+		return io.Copy(w, rc)
+	}
+	n, err := io.Copy(w, io.LimitReader(rc, maxSize+1))
+	if err != nil {
+		return n, err
+	}
+	if n > maxSize {
+		return n, fmt.Errorf("decompressed size exceeds limit %d: %w", maxSize, errdef.ErrSizeExceedsLimit)
+	}
+	return n, nil
+}
+
+// cacheName returns the name of the extraction cache of the compressed
+// archive located at path.
+func cacheName(path string, fi fs.FileInfo) string {
+	key := fmt.Sprintf("%s\x00%d\x00%d", path, fi.Size(), fi.ModTime().UnixNano())
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + ".tar"
+}
+
 // Open opens the named file.
 // When Open returns an error, it should be of type *PathError
 // with the Op field set to "open", the Path field set to name,
@@ -72,7 +283,7 @@ func (tfs *TarFS) Open(name string) (file fs.File, openErr error) {
 	if err != nil {
 		return nil, err
 	}
-	tarFile, err := os.Open(tfs.path)
+	tarFile, err := tfs.openArchive()
 	if err != nil {
 		return nil, err
 	}
@@ -106,6 +317,55 @@ func (tfs *TarFS) Stat(name string) (fs.FileInfo, error) {
 	return entry.header.FileInfo(), nil
 }
 
+// ReadDir reads the named directory and returns a list of directory entries
+// sorted by filename. Directories not present in the tar archive but implied
+// by the paths of the entries are listed as well.
+func (tfs *TarFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if entry, ok := tfs.entries[name]; ok && entry.header.Typeflag != tar.TypeDir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	children := make(map[string]fs.DirEntry)
+	found := name == "."
+	for entryPath, entry := range tfs.entries {
+		rel := entryPath
+		if name != "." {
+			var ok bool
+			if rel, ok = strings.CutPrefix(entryPath, name+"/"); !ok {
+				if entryPath == name {
+					found = true
+				}
+				continue
+			}
+		}
+		found = true
+		if rel == "." {
+			continue
+		}
+		child, _, nested := strings.Cut(rel, "/")
+		if nested || entry.header.Typeflag == tar.TypeDir {
+			children[child] = dirEntry(child)
+			continue
+		}
+		children[child] = fs.FileInfoToDirEntry(entry.header.FileInfo())
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, child)
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
 // getEntry returns the named entry.
 func (tfs *TarFS) getEntry(operation string, path string) (*entry, error) {
 	if !fs.ValidPath(path) {
@@ -123,9 +383,28 @@ func (tfs *TarFS) getEntry(operation string, path string) (*entry, error) {
 	return entry, nil
 }
 
+// openArchive opens the uncompressed tar archive for reading.
+func (tfs *TarFS) openArchive() (io.ReadSeekCloser, error) {
+	if tfs.file == nil {
+		return os.Open(tfs.path)
+	}
+	return sectionFile{io.NewSectionReader(tfs.file, 0, tfs.size)}, nil
+}
+
+// sectionFile reads a section of the open extraction cache, which is closed
+// by TarFS.Close.
+type sectionFile struct {
+	*io.SectionReader
+}
+
+// Close does nothing.
+func (sectionFile) Close() error {
+	return nil
+}
+
 // indexEntries index entries in the tar archive.
 func (tfs *TarFS) indexEntries() error {
-	tarFile, err := os.Open(tfs.path)
+	tarFile, err := tfs.openArchive()
 	if err != nil {
 		return err
 	}
@@ -165,3 +444,31 @@ type entryFile struct {
 func (e *entryFile) Stat() (fs.FileInfo, error) {
 	return e.header.FileInfo(), nil
 }
+
+// dirEntry represents a directory in a tar archive and implements both
+// `fs.DirEntry` and `fs.FileInfo`.
+type dirEntry string
+
+// Name returns the name of the directory.
+func (d dirEntry) Name() string { return string(d) }
+
+// IsDir returns true.
+func (d dirEntry) IsDir() bool { return true }
+
+// Type returns fs.ModeDir.
+func (d dirEntry) Type() fs.FileMode { return fs.ModeDir }
+
+// Info returns the fs.FileInfo of the directory.
+func (d dirEntry) Info() (fs.FileInfo, error) { return d, nil }
+
+// Size returns 0.
+func (d dirEntry) Size() int64 { return 0 }
+
+// Mode returns the file mode of the directory.
+func (d dirEntry) Mode() fs.FileMode { return fs.ModeDir | 0555 }
+
+// ModTime returns the zero time.
+func (d dirEntry) ModTime() time.Time { return time.Time{} }
+
+// Sys returns nil.
+func (d dirEntry) Sys() any { return nil }
@@ -17,10 +17,14 @@ package tarfs
 
 import (
 	"bytes"
+	"compress/gzip"
 	"errors"
 	"io"
 	"io/fs"
+	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"testing"
 
 	"oras.land/oras-go/v2/errdef"
@@ -421,3 +425,261 @@ func TestTarFs_New_Error(t *testing.T) {
 		}
 	})
 }
+
+func TestTarFS_ReadDir(t *testing.T) {
+	tarPaths := []string{
+		"testdata/cleaned_path.tar",
+		"testdata/prefixed_path.tar",
+	}
+	tests := []struct {
+		name     string
+		wantName []string
+		wantDir  []bool
+	}{
+		{
+			name:     ".",
+			wantName: []string{"dir", "foobar", "foobar_link", "foobar_symlink"},
+			wantDir:  []bool{true, false, false, false},
+		},
+		{
+			name:     "dir",
+			wantName: []string{"hello", "subdir"},
+			wantDir:  []bool{false, true},
+		},
+		{
+			name:     "dir/subdir",
+			wantName: []string{"world"},
+			wantDir:  []bool{false},
+		},
+	}
+	for _, tarPath := range tarPaths {
+		t.Run(tarPath, func(t *testing.T) {
+			tfs, err := New(tarPath)
+			if err != nil {
+				t.Fatalf("New() error = %v, wantErr %v", err, nil)
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					entries, err := tfs.ReadDir(tt.name)
+					if err != nil {
+						t.Fatal("ReadDir() error =", err)
+					}
+					var gotName []string
+					var gotDir []bool
+					for _, e := range entries {
+						gotName = append(gotName, e.Name())
+						gotDir = append(gotDir, e.IsDir())
+					}
+					if !reflect.DeepEqual(gotName, tt.wantName) {
+						t.Errorf("ReadDir() names = %v, want %v", gotName, tt.wantName)
+					}
+					if !reflect.DeepEqual(gotDir, tt.wantDir) {
+						t.Errorf("ReadDir() dirs = %v, want %v", gotDir, tt.wantDir)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestTarFS_ReadDir_Error(t *testing.T) {
+	tfs, err := New("testdata/cleaned_path.tar")
+	if err != nil {
+		t.Fatalf("New() error = %v, wantErr %v", err, nil)
+	}
+	if _, err := tfs.ReadDir("nonexistent"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadDir() error = %v, wantErr %v", err, fs.ErrNotExist)
+	}
+	if _, err := tfs.ReadDir("foobar"); err == nil {
+		t.Errorf("ReadDir() error = %v, wantErr %v", err, true)
+	}
+	if _, err := tfs.ReadDir("/dir"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("ReadDir() error = %v, wantErr %v", err, fs.ErrInvalid)
+	}
+}
+
+// compressTestTar writes the tar archive at tarPath compressed with gzip to a
+// new file in dir, and returns the path to the new file.
+func compressTestTar(t *testing.T, dir string, tarPath string) string {
+	t.Helper()
+	data, err := os.ReadFile(tarPath)
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal("gzip.Writer.Write() error =", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal("gzip.Writer.Close() error =", err)
+	}
+	path := filepath.Join(dir, filepath.Base(tarPath)+".gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+	return path
+}
+
+// checkTestFiles checks the content of the files in testdata/cleaned_path.tar.
+func checkTestFiles(t *testing.T, tfs *TarFS) {
+	t.Helper()
+	testFiles := map[string][]byte{
+		"foobar":           []byte("foobar"),
+		"dir/hello":        []byte("hello"),
+		"dir/subdir/world": []byte("world"),
+	}
+	for name, want := range testFiles {
+		got, err := fs.ReadFile(tfs, name)
+		if err != nil {
+			t.Fatalf("fs.ReadFile(%s) error = %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("fs.ReadFile(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestTarFS_New_Gzip(t *testing.T) {
+	gzPath := compressTestTar(t, t.TempDir(), "testdata/cleaned_path.tar")
+	tfs, err := New(gzPath)
+	if err != nil {
+		t.Fatalf("New() error = %v, wantErr %v", err, nil)
+	}
+	checkTestFiles(t, tfs)
+
+	// the temporary extraction cache is removed while open where supported,
+	// and is removed on close otherwise
+	cachePath := tfs.path
+	if cachePath == gzPath {
+		t.Fatalf("TarFS.path = %s, want extraction cache", cachePath)
+	}
+	if tfs.tempPath == "" {
+		if _, err := os.Stat(cachePath); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("os.Stat() error = %v, wantErr %v", err, fs.ErrNotExist)
+		}
+	}
+	if err := tfs.Close(); err != nil {
+		t.Fatal("TarFS.Close() error =", err)
+	}
+	if _, err := os.Stat(cachePath); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("os.Stat() error = %v, wantErr %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestTarFS_NewWithOptions_CacheDir(t *testing.T) {
+	gzPath := compressTestTar(t, t.TempDir(), "testdata/cleaned_path.tar")
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	opts := Options{CacheDir: cacheDir}
+	tfs, err := NewWithOptions(gzPath, opts)
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v, wantErr %v", err, nil)
+	}
+	checkTestFiles(t, tfs)
+	if err := tfs.Close(); err != nil {
+		t.Fatal("TarFS.Close() error =", err)
+	}
+	if got := filepath.Dir(tfs.path); got != cacheDir {
+		t.Fatalf("TarFS.path is in %s, want %s", got, cacheDir)
+	}
+
+	// the cache is reused
+	cacheInfo, err := os.Stat(tfs.path)
+	if err != nil {
+		t.Fatal("os.Stat() error =", err)
+	}
+	tfs, err = NewWithOptions(gzPath, opts)
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v, wantErr %v", err, nil)
+	}
+	checkTestFiles(t, tfs)
+	gotInfo, err := os.Stat(tfs.path)
+	if err != nil {
+		t.Fatal("os.Stat() error =", err)
+	}
+	if !os.SameFile(gotInfo, cacheInfo) {
+		t.Errorf("extraction cache is not reused")
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal("os.ReadDir() error =", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("len(os.ReadDir()) = %v, want %v", len(entries), 1)
+	}
+}
+
+func TestTarFS_NewWithOptions_Zstd(t *testing.T) {
+	data, err := os.ReadFile("testdata/cleaned_path.tar")
+	if err != nil {
+		t.Fatal("os.ReadFile() error =", err)
+	}
+	// fake a zstd compressed archive by prepending the magic number
+	zstdPath := filepath.Join(t.TempDir(), "cleaned_path.tar.zst")
+	if err := os.WriteFile(zstdPath, append(slices.Clone(zstdMagic), data...), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+
+	if _, err := New(zstdPath); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("New() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+	tfs, err := NewWithOptions(zstdPath, Options{
+		NewZstdReader: func(r io.Reader) (io.ReadCloser, error) {
+			if _, err := io.CopyN(io.Discard, r, int64(len(zstdMagic))); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(r), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v, wantErr %v", err, nil)
+	}
+	defer tfs.Close()
+	checkTestFiles(t, tfs)
+}
+
+func TestTarFS_New_CorruptGzip(t *testing.T) {
+	gzPath := filepath.Join(t.TempDir(), "corrupt.tar.gz")
+	if err := os.WriteFile(gzPath, append(slices.Clone(gzipMagic), "corrupt"...), 0644); err != nil {
+		t.Fatal("os.WriteFile() error =", err)
+	}
+	if _, err := New(gzPath); err == nil {
+		t.Errorf("New() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestTarFS_NewWithOptions_MaxExtractedSize(t *testing.T) {
+	gzPath := compressTestTar(t, t.TempDir(), "testdata/cleaned_path.tar")
+	fi, err := os.Stat("testdata/cleaned_path.tar")
+	if err != nil {
+		t.Fatal("os.Stat() error =", err)
+	}
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	for _, opts := range []Options{
+		{MaxExtractedSize: fi.Size() - 1},
+		{MaxExtractedSize: fi.Size() - 1, CacheDir: cacheDir},
+	} {
+		if _, err := NewWithOptions(gzPath, opts); !errors.Is(err, errdef.ErrSizeExceedsLimit) {
+			t.Errorf("NewWithOptions() error = %v, wantErr %v", err, errdef.ErrSizeExceedsLimit)
+		}
+	}
+	// the partial extraction is removed
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal("os.ReadDir() error =", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("len(os.ReadDir()) = %v, want %v", len(entries), 0)
+	}
+
+	for _, maxSize := range []int64{fi.Size(), -1} {
+		tfs, err := NewWithOptions(gzPath, Options{MaxExtractedSize: maxSize})
+		if err != nil {
+			t.Fatalf("NewWithOptions() error = %v, wantErr %v", err, nil)
+		}
+		checkTestFiles(t, tfs)
+		if err := tfs.Close(); err != nil {
+			t.Fatal("TarFS.Close() error =", err)
+		}
+	}
+}
@@ -73,6 +73,17 @@ func (m *Memory) Index(ctx context.Context, fetcher content.Fetcher, node ocispe
 
 // Index indexes predecessors for all the successors of the given node.
 func (m *Memory) IndexAll(ctx context.Context, fetcher content.Fetcher, node ocispec.Descriptor) error {
+	return m.IndexAllFunc(ctx, func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		return content.Successors(ctx, fetcher, desc)
+	}, node)
+}
+
+// SuccessorFunc returns the direct successors of the given node.
+type SuccessorFunc func(ctx context.Context, node ocispec.Descriptor) ([]ocispec.Descriptor, error)
+
+// IndexAllFunc indexes predecessors for all the successors of the given node,
+// where the successors are found by successorFn.
+func (m *Memory) IndexAllFunc(ctx context.Context, successorFn SuccessorFunc, node ocispec.Descriptor) error {
 	// track content status
 	tracker := status.NewTracker()
 	var fn syncutil.GoFunc[ocispec.Descriptor]
@@ -82,7 +93,7 @@ func (m *Memory) IndexAll(ctx context.Context, fetcher content.Fetcher, node oci
 		if !committed {
 			return nil
 		}
-		successors, err := m.index(ctx, fetcher, desc)
+		successors, err := successorFn(ctx, desc)
 		if err != nil {
 			if errors.Is(err, errdef.ErrNotFound) {
 				// skip the node if it does not exist
@@ -90,6 +101,7 @@ func (m *Memory) IndexAll(ctx context.Context, fetcher content.Fetcher, node oci
 			}
 			return err
 		}
+		m.Add(desc, successors)
 		if len(successors) > 0 {
 			// traverse and index successors
 			return syncutil.Go(ctx, nil, fn, successors...)
@@ -166,6 +178,13 @@ func (m *Memory) index(ctx context.Context, fetcher content.Fetcher, node ocispe
 	if err != nil {
 		return nil, err
 	}
+	m.Add(node, successors)
+	return successors, nil
+}
+
+// Add indexes predecessors for each of the given direct successors of the
+// node, without fetching the node.
+func (m *Memory) Add(node ocispec.Descriptor, successors []ocispec.Descriptor) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -187,7 +206,60 @@ func (m *Memory) index(ctx context.Context, fetcher content.Fetcher, node ocispe
 		}
 		predecessorSet.Add(nodeKey)
 	}
-	return successors, nil
+}
+
+// Successors returns the direct successors of the node indexed in the memory.
+// It returns false if the node has not been indexed.
+// The successors indexed as nodes are returned as they are indexed, while the
+// others are returned as plain descriptors.
+func (m *Memory) Successors(node ocispec.Descriptor) ([]ocispec.Descriptor, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	successorSet, exists := m.successors[descriptor.FromOCI(node)]
+	if !exists {
+		return nil, false
+	}
+	successors := make([]ocispec.Descriptor, 0, len(successorSet))
+	for key := range successorSet {
+		successors = append(successors, m.descriptorOf(key))
+	}
+	return successors, true
+}
+
+// Node is a node indexed in Memory along with its direct successors.
+type Node struct {
+	Descriptor ocispec.Descriptor   `json:"descriptor"`
+	Successors []ocispec.Descriptor `json:"successors,omitempty"`
+}
+
+// Nodes returns all the nodes indexed in the memory along with their direct
+// successors, which can be indexed again by Add.
+func (m *Memory) Nodes() []Node {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	nodes := make([]Node, 0, len(m.nodes))
+	for key, desc := range m.nodes {
+		node := Node{Descriptor: desc}
+		for successorKey := range m.successors[key] {
+			node.Successors = append(node.Successors, m.descriptorOf(successorKey))
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// descriptorOf returns the descriptor of the node identified by key.
+func (m *Memory) descriptorOf(key descriptor.Descriptor) ocispec.Descriptor {
+	if desc, ok := m.nodes[key]; ok {
+		return desc
+	}
+	return ocispec.Descriptor{
+		MediaType: key.MediaType,
+		Digest:    key.Digest,
+		Size:      key.Size,
+	}
 }
 
 // Exists checks if the node exists in the graph
@@ -765,3 +765,87 @@ func TestMemory_Exists(t *testing.T) {
 		}
 	}
 }
+
+func TestMemory_NodesAndAdd(t *testing.T) {
+	testFetcher := cas.NewMemory()
+	testMemory := NewMemory()
+	ctx := context.Background()
+
+	// generate test content
+	layer := []byte("layer")
+	descLayer := ocispec.Descriptor{
+		MediaType:   "test layer",
+		Digest:      digest.FromBytes(layer),
+		Size:        int64(len(layer)),
+		Annotations: map[string]string{"foo": "bar"},
+	}
+	manifestJSON, err := json.Marshal(ocispec.Manifest{
+		Config: ocispec.Descriptor{MediaType: "test config"},
+		Layers: []ocispec.Descriptor{descLayer},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	descManifest := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifestJSON),
+		Size:      int64(len(manifestJSON)),
+	}
+	if err := testFetcher.Push(ctx, descManifest, bytes.NewReader(manifestJSON)); err != nil {
+		t.Fatal(err)
+	}
+	if err := testMemory.IndexAll(ctx, testFetcher, descManifest); err != nil {
+		t.Fatalf("Memory.IndexAll() error = %v", err)
+	}
+
+	// successors of indexed nodes
+	successors, ok := testMemory.Successors(descManifest)
+	if !ok {
+		t.Fatal("Memory.Successors() = false, want true")
+	}
+	if len(successors) != 2 {
+		t.Fatalf("len(Memory.Successors()) = %v, want %v", len(successors), 2)
+	}
+	if _, ok := testMemory.Successors(ocispec.Descriptor{MediaType: "unknown"}); ok {
+		t.Errorf("Memory.Successors() = true, want false")
+	}
+
+	// restore the nodes into another memory without fetching
+	nodes := testMemory.Nodes()
+	if len(nodes) != 3 {
+		t.Fatalf("len(Memory.Nodes()) = %v, want %v", len(nodes), 3)
+	}
+	restored := NewMemory()
+	for _, node := range nodes {
+		restored.Add(node.Descriptor, node.Successors)
+	}
+	for _, desc := range []ocispec.Descriptor{descLayer, successors[0], successors[1]} {
+		want, err := testMemory.Predecessors(ctx, desc)
+		if err != nil {
+			t.Fatalf("Memory.Predecessors() error = %v", err)
+		}
+		got, err := restored.Predecessors(ctx, desc)
+		if err != nil {
+			t.Fatalf("Memory.Predecessors() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Memory.Predecessors() = %v, want %v", got, want)
+		}
+	}
+	if !reflect.DeepEqual(restored.nodes, testMemory.nodes) {
+		t.Errorf("Memory.nodes = %v, want %v", restored.nodes, testMemory.nodes)
+	}
+
+	// index from the restored memory
+	indexed := NewMemory()
+	successorFn := func(_ context.Context, node ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		successors, _ := restored.Successors(node)
+		return successors, nil
+	}
+	if err := indexed.IndexAllFunc(ctx, successorFn, descManifest); err != nil {
+		t.Fatalf("Memory.IndexAllFunc() error = %v", err)
+	}
+	if !reflect.DeepEqual(indexed.successors, testMemory.successors) {
+		t.Errorf("Memory.successors = %v, want %v", indexed.successors, testMemory.successors)
+	}
+}
@@ -45,3 +45,21 @@ func ExampleParseReference_digest() {
 	// Repository: oras-project/oras-go
 	// Digest: sha256:601d05a48832e7946dab8f49b14953549bebf42e42f4d7973b1a5a287d77ab76
 }
+
+// ExampleParseNormalizedReference demonstrates parsing a short Docker Hub
+// reference string and formatting it back to the familiar form.
+func ExampleParseNormalizedReference() {
+	ref, err := registry.ParseNormalizedReference("ubuntu:22.04")
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Reference:", ref)
+	fmt.Println("Host:", ref.Host())
+	fmt.Println("Familiar:", ref.FamiliarString())
+
+	// Output:
+	// Reference: docker.io/library/ubuntu:22.04
+	// Host: registry-1.docker.io
+	// Familiar: ubuntu:22.04
+}
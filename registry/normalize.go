@@ -0,0 +1,108 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"oras.land/oras-go/v2/errdef"
+)
+
+const (
+	// dockerHubRegistry is the canonical registry name of Docker Hub.
+	dockerHubRegistry = "docker.io"
+	// dockerHubLegacyRegistry is the legacy registry name of Docker Hub.
+	dockerHubLegacyRegistry = "index.docker.io"
+	// dockerHubHost is the host of the Docker Hub registry API endpoint.
+	dockerHubHost = "registry-1.docker.io"
+	// dockerHubOfficialRepoPrefix is the namespace of the Docker Hub official
+	// images.
+	dockerHubOfficialRepoPrefix = "library/"
+)
+
+// ParseNormalizedReference parses a string (artifact) into an `artifact
+// reference` the way docker does, where the registry can be omitted.
+//
+// Unlike ParseReference, the first component of the artifact is treated as
+// the registry only if it contains a "." or a ":", or if it is "localhost".
+// Otherwise, the artifact is considered as a Docker Hub repository and
+// normalized as follows:
+//   - The registry defaults to "docker.io".
+//   - The legacy registry "index.docker.io" is replaced with "docker.io".
+//   - Single-component Docker Hub repositories are prefixed with "library/".
+//
+// For example, "ubuntu:22.04" is normalized to "docker.io/library/ubuntu:22.04"
+// and "library/nginx" is normalized to "docker.io/library/nginx".
+// The normalized reference is then validated as ParseReference does.
+// Reference.Host can be used to get the API endpoint "registry-1.docker.io" of
+// "docker.io".
+//
+// Reference: https://github.com/distribution/reference/blob/v0.6.0/normalize.go
+func ParseNormalizedReference(artifact string) (Reference, error) {
+	if artifact == "" {
+		return Reference{}, fmt.Errorf("%w: empty reference", errdef.ErrInvalidReference)
+	}
+
+	registry, path := splitDockerDomain(artifact)
+	if registry == dockerHubLegacyRegistry {
+		registry = dockerHubRegistry
+	}
+	if registry == dockerHubRegistry && !strings.ContainsRune(repositoryPart(path), '/') {
+		path = dockerHubOfficialRepoPrefix + path
+	}
+	return ParseReference(registry + "/" + path)
+}
+
+// FamiliarString returns the familiar form of the reference, which is the
+// shortest string parsed by ParseNormalizedReference to the same reference.
+// Specifically, the "docker.io" registry and the "library/" repository prefix
+// of Docker Hub references are removed.
+// The resulted string is meaningful only if the reference is valid.
+func (r Reference) FamiliarString() string {
+	if r.Registry != dockerHubRegistry || r.Repository == "" {
+		return r.String()
+	}
+
+	// trim the registry, and the "library/" prefix for official images
+	ref := Reference{
+		Repository: r.Repository,
+		Reference:  r.Reference,
+	}
+	if repo, ok := strings.CutPrefix(r.Repository, dockerHubOfficialRepoPrefix); ok && !strings.ContainsRune(repo, '/') {
+		ref.Repository = repo
+	}
+	return strings.TrimPrefix(ref.String(), "/")
+}
+
+// splitDockerDomain splits the artifact into the registry and the remaining
+// path. If no registry is present, "docker.io" is returned as the registry.
+func splitDockerDomain(artifact string) (registry, path string) {
+	if IsShortName(artifact) {
+		return dockerHubRegistry, artifact
+	}
+	registry, path, _ = strings.Cut(artifact, "/")
+	return registry, path
+}
+
+// repositoryPart returns the repository part of the path by trimming the tag
+// and the digest.
+func repositoryPart(path string) string {
+	if index := strings.IndexAny(path, ":@"); index != -1 {
+		return path[:index]
+	}
+	return path
+}
@@ -0,0 +1,194 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"oras.land/oras-go/v2/errdef"
+)
+
+func TestParseNormalizedReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		artifact string
+		want     Reference
+		familiar string
+	}{
+		{
+			name:     "official image",
+			artifact: "ubuntu",
+			want: Reference{
+				Registry:   "docker.io",
+				Repository: "library/ubuntu",
+			},
+			familiar: "ubuntu",
+		},
+		{
+			name:     "official image with tag",
+			artifact: "ubuntu:22.04",
+			want: Reference{
+				Registry:   "docker.io",
+				Repository: "library/ubuntu",
+				Reference:  "22.04",
+			},
+			familiar: "ubuntu:22.04",
+		},
+		{
+			name:     "official image with digest",
+			artifact: "ubuntu@" + ValidDigest,
+			want: Reference{
+				Registry:   "docker.io",
+				Repository: "library/ubuntu",
+				Reference:  ValidDigest,
+			},
+			familiar: "ubuntu@" + ValidDigest,
+		},
+		{
+			name:     "official image with library prefix",
+			artifact: "library/nginx",
+			want: Reference{
+				Registry:   "docker.io",
+				Repository: "library/nginx",
+			},
+			familiar: "nginx",
+		},
+		{
+			name:     "user image",
+			artifact: "oras/hello:v1",
+			want: Reference{
+				Registry:   "docker.io",
+				Repository: "oras/hello",
+				Reference:  "v1",
+			},
+			familiar: "oras/hello:v1",
+		},
+		{
+			name:     "docker.io official image",
+			artifact: "docker.io/ubuntu",
+			want: Reference{
+				Registry:   "docker.io",
+				Repository: "library/ubuntu",
+			},
+			familiar: "ubuntu",
+		},
+		{
+			name:     "legacy registry",
+			artifact: "index.docker.io/library/ubuntu:latest",
+			want: Reference{
+				Registry:   "docker.io",
+				Repository: "library/ubuntu",
+				Reference:  "latest",
+			},
+			familiar: "ubuntu:latest",
+		},
+		{
+			name:     "nested library repository",
+			artifact: "docker.io/library/foo/bar",
+			want: Reference{
+				Registry:   "docker.io",
+				Repository: "library/foo/bar",
+			},
+			familiar: "library/foo/bar",
+		},
+		{
+			name:     "localhost",
+			artifact: "localhost/hello",
+			want: Reference{
+				Registry:   "localhost",
+				Repository: "hello",
+			},
+			familiar: "localhost/hello",
+		},
+		{
+			name:     "registry with port",
+			artifact: "myregistry:5000/hello:v1",
+			want: Reference{
+				Registry:   "myregistry:5000",
+				Repository: "hello",
+				Reference:  "v1",
+			},
+			familiar: "myregistry:5000/hello:v1",
+		},
+		{
+			name:     "fully qualified",
+			artifact: "ghcr.io/oras-project/oras-go:v2",
+			want: Reference{
+				Registry:   "ghcr.io",
+				Repository: "oras-project/oras-go",
+				Reference:  "v2",
+			},
+			familiar: "ghcr.io/oras-project/oras-go:v2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNormalizedReference(tt.artifact)
+			if err != nil {
+				t.Fatalf("ParseNormalizedReference() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNormalizedReference() = %v, want %v", got, tt.want)
+			}
+			if familiar := got.FamiliarString(); familiar != tt.familiar {
+				t.Errorf("Reference.FamiliarString() = %v, want %v", familiar, tt.familiar)
+			}
+			roundTrip, err := ParseNormalizedReference(got.FamiliarString())
+			if err != nil {
+				t.Fatalf("ParseNormalizedReference() error = %v", err)
+			}
+			if !reflect.DeepEqual(roundTrip, tt.want) {
+				t.Errorf("ParseNormalizedReference(FamiliarString()) = %v, want %v", roundTrip, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNormalizedReference_Host(t *testing.T) {
+	ref, err := ParseNormalizedReference("ubuntu")
+	if err != nil {
+		t.Fatalf("ParseNormalizedReference() error = %v", err)
+	}
+	if got, want := ref.Host(), "registry-1.docker.io"; got != want {
+		t.Errorf("Reference.Host() = %v, want %v", got, want)
+	}
+}
+
+func TestParseNormalizedReference_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"Ubuntu",
+		"ubuntu@sha256:invalid",
+		"docker.io/",
+	}
+	for _, artifact := range tests {
+		t.Run(artifact, func(t *testing.T) {
+			if _, err := ParseNormalizedReference(artifact); !errors.Is(err, errdef.ErrInvalidReference) {
+				t.Errorf("ParseNormalizedReference() error = %v, wantErr %v", err, errdef.ErrInvalidReference)
+			}
+		})
+	}
+}
+
+func TestParseReference_NotNormalized(t *testing.T) {
+	for _, artifact := range []string{"ubuntu:22.04", "ubuntu"} {
+		if _, err := ParseReference(artifact); !errors.Is(err, errdef.ErrInvalidReference) {
+			t.Errorf("ParseReference(%q) error = %v, wantErr %v", artifact, err, errdef.ErrInvalidReference)
+		}
+	}
+}
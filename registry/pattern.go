@@ -0,0 +1,210 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"oras.land/oras-go/v2/errdef"
+)
+
+// segmentWildcard matches zero or more repository path segments.
+const segmentWildcard = "**"
+
+// Pattern is a pattern matching references, in the form of
+//
+//	<registry>/<repository>[:<tag>|@<digest>]
+//
+// where each component is a glob using the syntax of path.Match:
+//   - The registry glob is matched against Reference.Registry, including the
+//     port if any. For example, "*.example.com" matches "registry.example.com"
+//     but not "registry.example.com:5000".
+//   - The repository glob is matched segment by segment, where a segment is
+//     separated by "/". A segment of "**" matches zero or more segments.
+//     For example, "team-*/**" matches "team-a/app" and "team-b/x/y".
+//   - The optional tag glob only matches references with a tag, and the
+//     optional digest glob only matches references with a digest. For
+//     example, ":v*" matches "v1.0" and "@sha256:*" matches any SHA-256
+//     digest. If neither is present, any reference is matched, including the
+//     empty one.
+//
+// Example: "registry.example.com/team-*/**:v*"
+type Pattern struct {
+	// Registry is the glob of the registry.
+	Registry string
+
+	// Repository is the glob of the repository.
+	Repository string
+
+	// Tag is the glob of the tag.
+	// Tag and Digest are mutually exclusive.
+	Tag string
+
+	// Digest is the glob of the digest.
+	// Tag and Digest are mutually exclusive.
+	Digest string
+}
+
+// ParsePattern parses a string into a Pattern and validates it.
+func ParsePattern(s string) (Pattern, error) {
+	registry, rest, found := strings.Cut(s, "/")
+	if !found {
+		return Pattern{}, fmt.Errorf("%w: invalid pattern %q: missing registry or repository", errdef.ErrInvalidReference, s)
+	}
+
+	p := Pattern{
+		Registry: registry,
+	}
+	if index := strings.Index(rest, "@"); index != -1 {
+		p.Repository, p.Digest = rest[:index], rest[index+1:]
+		if p.Digest == "" {
+			return Pattern{}, fmt.Errorf("%w: invalid pattern %q: empty digest", errdef.ErrInvalidReference, s)
+		}
+	} else if index := strings.LastIndex(rest, ":"); index != -1 && !strings.Contains(rest[index:], "/") {
+		p.Repository, p.Tag = rest[:index], rest[index+1:]
+		if p.Tag == "" {
+			return Pattern{}, fmt.Errorf("%w: invalid pattern %q: empty tag", errdef.ErrInvalidReference, s)
+		}
+	} else {
+		p.Repository = rest
+	}
+
+	if err := p.Validate(); err != nil {
+		return Pattern{}, err
+	}
+	return p, nil
+}
+
+// Validate validates the pattern.
+func (p Pattern) Validate() error {
+	if p.Registry == "" {
+		return fmt.Errorf("%w: invalid pattern %q: empty registry", errdef.ErrInvalidReference, p)
+	}
+	if err := validateGlob(p.Registry); err != nil {
+		return fmt.Errorf("%w: invalid registry pattern %q: %v", errdef.ErrInvalidReference, p.Registry, err)
+	}
+	if p.Repository == "" {
+		return fmt.Errorf("%w: invalid pattern %q: empty repository", errdef.ErrInvalidReference, p)
+	}
+	for _, segment := range strings.Split(p.Repository, "/") {
+		if segment == "" {
+			return fmt.Errorf("%w: invalid repository pattern %q: empty segment", errdef.ErrInvalidReference, p.Repository)
+		}
+		if segment == segmentWildcard {
+			continue
+		}
+		if strings.Contains(segment, segmentWildcard) {
+			return fmt.Errorf("%w: invalid repository pattern %q: %q must be a whole segment", errdef.ErrInvalidReference, p.Repository, segmentWildcard)
+		}
+		if err := validateGlob(segment); err != nil {
+			return fmt.Errorf("%w: invalid repository pattern %q: %v", errdef.ErrInvalidReference, p.Repository, err)
+		}
+	}
+	if p.Tag != "" && p.Digest != "" {
+		return fmt.Errorf("%w: invalid pattern %q: tag and digest are mutually exclusive", errdef.ErrInvalidReference, p)
+	}
+	if err := validateGlob(p.Tag); err != nil {
+		return fmt.Errorf("%w: invalid tag pattern %q: %v", errdef.ErrInvalidReference, p.Tag, err)
+	}
+	if err := validateGlob(p.Digest); err != nil {
+		return fmt.Errorf("%w: invalid digest pattern %q: %v", errdef.ErrInvalidReference, p.Digest, err)
+	}
+	return nil
+}
+
+// Match reports whether the reference matches the pattern.
+// Invalid patterns match nothing.
+func (p Pattern) Match(ref Reference) bool {
+	if !globMatch(p.Registry, ref.Registry) {
+		return false
+	}
+	if !matchSegments(strings.Split(p.Repository, "/"), strings.Split(ref.Repository, "/")) {
+		return false
+	}
+
+	switch {
+	case p.Tag != "":
+		return ref.ValidateReferenceAsTag() == nil && globMatch(p.Tag, ref.Reference)
+	case p.Digest != "":
+		return ref.ValidateReferenceAsDigest() == nil && globMatch(p.Digest, ref.Reference)
+	default:
+		return true
+	}
+}
+
+// MatchString parses the artifact by ParseReference and reports whether it
+// matches the pattern.
+func (p Pattern) MatchString(artifact string) (bool, error) {
+	ref, err := ParseReference(artifact)
+	if err != nil {
+		return false, err
+	}
+	return p.Match(ref), nil
+}
+
+// String implements `fmt.Stringer` and returns the pattern string.
+func (p Pattern) String() string {
+	s := p.Registry + "/" + p.Repository
+	switch {
+	case p.Tag != "":
+		return s + ":" + p.Tag
+	case p.Digest != "":
+		return s + "@" + p.Digest
+	default:
+		return s
+	}
+}
+
+// validateGlob validates the syntax of the glob pattern.
+func validateGlob(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// globMatch reports whether name matches the glob pattern.
+func globMatch(pattern, name string) bool {
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// matchSegments reports whether the path segments match the segment patterns,
+// where the segment pattern "**" matches zero or more segments.
+func matchSegments(patterns, segments []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == segmentWildcard {
+			// collapse consecutive wildcards
+			for len(patterns) > 0 && patterns[0] == segmentWildcard {
+				patterns = patterns[1:]
+			}
+			if len(patterns) == 0 {
+				return true
+			}
+			for i := range segments {
+				if matchSegments(patterns, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 || !globMatch(patterns[0], segments[0]) {
+			return false
+		}
+		patterns, segments = patterns[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
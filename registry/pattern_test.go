@@ -0,0 +1,164 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"oras.land/oras-go/v2/errdef"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    Pattern
+	}{
+		{
+			name:    "repository only",
+			pattern: "registry.example.com/team/app",
+			want: Pattern{
+				Registry:   "registry.example.com",
+				Repository: "team/app",
+			},
+		},
+		{
+			name:    "tag",
+			pattern: "registry.example.com/team-*/**:v*",
+			want: Pattern{
+				Registry:   "registry.example.com",
+				Repository: "team-*/**",
+				Tag:        "v*",
+			},
+		},
+		{
+			name:    "digest",
+			pattern: "*.example.com:5000/app@sha256:*",
+			want: Pattern{
+				Registry:   "*.example.com:5000",
+				Repository: "app",
+				Digest:     "sha256:*",
+			},
+		},
+		{
+			name:    "registry with port",
+			pattern: "localhost:*/**",
+			want: Pattern{
+				Registry:   "localhost:*",
+				Repository: "**",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParsePattern() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePattern() = %v, want %v", got, tt.want)
+			}
+			if s := got.String(); s != tt.pattern {
+				t.Errorf("Pattern.String() = %v, want %v", s, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestParsePattern_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"registry.example.com",
+		"/app",
+		"registry.example.com/",
+		"registry.example.com/app:",
+		"registry.example.com/app@",
+		"registry.example.com/team//app",
+		"registry.example.com/team/a**",
+		"registry.example.com/[/app",
+		"[.example.com/app",
+		"registry.example.com/app:[",
+	}
+	for _, pattern := range tests {
+		t.Run(pattern, func(t *testing.T) {
+			if _, err := ParsePattern(pattern); !errors.Is(err, errdef.ErrInvalidReference) {
+				t.Errorf("ParsePattern() error = %v, wantErr %v", err, errdef.ErrInvalidReference)
+			}
+		})
+	}
+
+	p := Pattern{Registry: "registry.example.com", Repository: "app", Tag: "v1", Digest: "sha256:*"}
+	if err := p.Validate(); !errors.Is(err, errdef.ErrInvalidReference) {
+		t.Errorf("Pattern.Validate() error = %v, wantErr %v", err, errdef.ErrInvalidReference)
+	}
+}
+
+func TestPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		artifact string
+		want     bool
+	}{
+		{"registry.example.com/team-*/**:v*", "registry.example.com/team-a/app:v1", true},
+		{"registry.example.com/team-*/**:v*", "registry.example.com/team-a/x/y:v1.2", true},
+		{"registry.example.com/team-*/**:v*", "registry.example.com/team-a:v1", true},
+		{"registry.example.com/team-*/**:v*", "registry.example.com/team-a/app:latest", false},
+		{"registry.example.com/team-*/**:v*", "registry.example.com/team-a/app", false},
+		{"registry.example.com/team-*/**:v*", "registry.example.com/team-a/app@" + ValidDigest, false},
+		{"registry.example.com/team-*/**:v*", "registry.example.com/other/app:v1", false},
+		{"registry.example.com/team-*/**:v*", "mirror.example.com/team-a/app:v1", false},
+		{"*.example.com/app", "registry.example.com/app", true},
+		{"*.example.com/app", "registry.example.com:5000/app", false},
+		{"*.example.com/app", "registry.example.com/app:v1", true},
+		{"*.example.com/app", "registry.example.com/app@" + ValidDigest, true},
+		{"*.example.com/app", "registry.example.com/team/app", false},
+		{"*.example.com:*/app", "registry.example.com:5000/app", true},
+		{"registry.example.com/**/app", "registry.example.com/app", true},
+		{"registry.example.com/**/app", "registry.example.com/a/b/app", true},
+		{"registry.example.com/**/app", "registry.example.com/a/b/app2", false},
+		{"registry.example.com/a/**/**/b", "registry.example.com/a/x/b", true},
+		{"registry.example.com/app@sha256:*", "registry.example.com/app@" + ValidDigest, true},
+		{"registry.example.com/app@sha256:*", "registry.example.com/app:v1", false},
+		{"registry.example.com/app@" + ValidDigest, "registry.example.com/app@" + ValidDigest, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.artifact, func(t *testing.T) {
+			p, err := ParsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParsePattern() error = %v", err)
+			}
+			got, err := p.MatchString(tt.artifact)
+			if err != nil {
+				t.Fatalf("Pattern.MatchString() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Pattern.MatchString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPattern_MatchString_InvalidReference(t *testing.T) {
+	p, err := ParsePattern("registry.example.com/**")
+	if err != nil {
+		t.Fatalf("ParsePattern() error = %v", err)
+	}
+	if _, err := p.MatchString("ubuntu"); !errors.Is(err, errdef.ErrInvalidReference) {
+		t.Errorf("Pattern.MatchString() error = %v, wantErr %v", err, errdef.ErrInvalidReference)
+	}
+}
@@ -238,8 +238,8 @@ func (r Reference) ValidateReference() error {
 
 // Host returns the host name of the registry.
 func (r Reference) Host() string {
-	if r.Registry == "docker.io" {
-		return "registry-1.docker.io"
+	if r.Registry == dockerHubRegistry {
+		return dockerHubHost
 	}
 	return r.Registry
 }
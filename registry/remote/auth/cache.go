@@ -19,6 +19,7 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"oras.land/oras-go/v2/errdef"
 	"oras.land/oras-go/v2/internal/syncutil"
@@ -53,10 +54,41 @@ type Cache interface {
 	Set(ctx context.Context, registry string, scheme Scheme, key string, fetch func(context.Context) (string, error)) (string, error)
 }
 
+// ExpiringCache is a Cache which tracks the expiry of auth-tokens and
+// refreshes them shortly before they expire.
+// The cache returned by NewCache implements ExpiringCache.
+type ExpiringCache interface {
+	Cache
+
+	// SetToken fetches the token with its expiry using the given fetch
+	// function and caches the token for the given scheme with the given key
+	// for the given registry.
+	// The fetch function MAY be retained to refresh the token before it
+	// expires.
+	SetToken(ctx context.Context, registry string, scheme Scheme, key string, fetch func(context.Context) (Token, error)) (string, error)
+
+	// EvictToken removes the token cached for the given scheme with the given
+	// key for the given registry.
+	EvictToken(ctx context.Context, registry string, scheme Scheme, key string) error
+
+	// Evict removes the scheme and all tokens cached for the given registry.
+	Evict(ctx context.Context, registry string) error
+}
+
 // cacheEntry is a cache entry for a single registry.
 type cacheEntry struct {
 	scheme Scheme
-	tokens sync.Map // map[string]string
+	tokens sync.Map // map[string]*tokenEntry
+}
+
+// tokenEntry is a cached auth-token.
+type tokenEntry struct {
+	token Token
+	// refreshAt is the time after which the token should be refreshed.
+	// The zero value indicates that the token is never refreshed.
+	refreshAt time.Time
+	// refresh fetches a new token.
+	refresh func(context.Context) (Token, error)
 }
 
 // concurrentCache is a cache suitable for concurrent invocation.
@@ -66,6 +98,7 @@ type concurrentCache struct {
 }
 
 // NewCache creates a new go-routine safe cache instance.
+// The returned cache implements ExpiringCache.
 func NewCache() Cache {
 	return &concurrentCache{}
 }
@@ -81,6 +114,8 @@ func (cc *concurrentCache) GetScheme(ctx context.Context, registry string) (Sche
 
 // GetToken returns the auth-token part cached for the given registry of a given
 // scheme.
+// If the token is about to expire, GetToken refreshes the token. If the
+// refresh fails, the cached token is returned until it expires.
 func (cc *concurrentCache) GetToken(ctx context.Context, registry string, scheme Scheme, key string) (string, error) {
 	entryValue, ok := cc.cache.Load(registry)
 	if !ok {
@@ -90,17 +125,55 @@ func (cc *concurrentCache) GetToken(ctx context.Context, registry string, scheme
 	if entry.scheme != scheme {
 		return "", errdef.ErrNotFound
 	}
-	if token, ok := entry.tokens.Load(key); ok {
-		return token.(string), nil
+	tokenValue, ok := entry.tokens.Load(key)
+	if !ok {
+		return "", errdef.ErrNotFound
+	}
+	te := tokenValue.(*tokenEntry)
+	now := time.Now()
+	if te.refreshAt.IsZero() || now.Before(te.refreshAt) {
+		return te.token.Value, nil
+	}
+
+	// refresh the token
+	if te.refresh != nil {
+		if token, err := cc.SetToken(ctx, registry, scheme, key, te.refresh); err == nil {
+			return token, nil
+		}
+	}
+	if now.Before(te.token.ExpiresAt) {
+		return te.token.Value, nil
 	}
+	entry.tokens.CompareAndDelete(key, te)
 	return "", errdef.ErrNotFound
 }
 
 // Set fetches the token using the given fetch function and caches the token
 // for the given scheme with the given key for the given registry.
+// The expiry of the token is determined by the "exp" claim if the token is a
+// JWT bearer token.
 // Set combines the fetch operation if the Set is invoked multiple times at the
 // same time.
 func (cc *concurrentCache) Set(ctx context.Context, registry string, scheme Scheme, key string, fetch func(context.Context) (string, error)) (string, error) {
+	return cc.SetToken(ctx, registry, scheme, key, func(ctx context.Context) (Token, error) {
+		token, err := fetch(ctx)
+		if err != nil {
+			return Token{}, err
+		}
+		if scheme == SchemeBearer {
+			return Token{Value: token, ExpiresAt: jwtExpiry(token)}, nil
+		}
+		return Token{Value: token}, nil
+	})
+}
+
+// SetToken fetches the token with its expiry using the given fetch function
+// and caches the token for the given scheme with the given key for the given
+// registry. The fetch function is retained to refresh the token if the expiry
+// of the token is known.
+// SetToken combines the fetch operation if the SetToken is invoked multiple
+// times at the same time.
+func (cc *concurrentCache) SetToken(ctx context.Context, registry string, scheme Scheme, key string, fetch func(context.Context) (Token, error)) (string, error) {
 	// fetch token
 	statusKey := strings.Join([]string{
 		registry,
@@ -118,9 +191,9 @@ func (cc *concurrentCache) Set(ctx context.Context, registry string, scheme Sche
 	if err != nil {
 		return "", err
 	}
-	token := result.(string)
+	token := result.(Token)
 	if !fetchedFirst {
-		return token, nil
+		return token.Value, nil
 	}
 
 	// cache token
@@ -135,9 +208,36 @@ func (cc *concurrentCache) Set(ctx context.Context, registry string, scheme Sche
 		entry = newEntry
 		cc.cache.Store(registry, entry)
 	}
-	entry.tokens.Store(key, token)
+	te := &tokenEntry{
+		token:     token,
+		refreshAt: token.refreshAt(time.Now()),
+	}
+	if !te.refreshAt.IsZero() {
+		te.refresh = fetch
+	}
+	entry.tokens.Store(key, te)
 
-	return token, nil
+	return token.Value, nil
+}
+
+// EvictToken removes the token cached for the given scheme with the given key
+// for the given registry.
+func (cc *concurrentCache) EvictToken(ctx context.Context, registry string, scheme Scheme, key string) error {
+	entryValue, ok := cc.cache.Load(registry)
+	if !ok {
+		return nil
+	}
+	entry := entryValue.(*cacheEntry)
+	if entry.scheme == scheme {
+		entry.tokens.Delete(key)
+	}
+	return nil
+}
+
+// Evict removes the scheme and all tokens cached for the given registry.
+func (cc *concurrentCache) Evict(ctx context.Context, registry string) error {
+	cc.cache.Delete(registry)
+	return nil
 }
 
 // noCache is a cache implementation that does not do cache at all.
@@ -158,6 +258,25 @@ func (noCache) Set(ctx context.Context, registry string, scheme Scheme, key stri
 	return fetch(ctx)
 }
 
+// SetToken calls fetch directly without caching.
+func (noCache) SetToken(ctx context.Context, registry string, scheme Scheme, key string, fetch func(context.Context) (Token, error)) (string, error) {
+	token, err := fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	return token.Value, nil
+}
+
+// EvictToken does nothing as it has no cache.
+func (noCache) EvictToken(ctx context.Context, registry string, scheme Scheme, key string) error {
+	return nil
+}
+
+// Evict does nothing as it has no cache.
+func (noCache) Evict(ctx context.Context, registry string) error {
+	return nil
+}
+
 // hostCache is an auth cache that ignores scopes.  Uses only the registry's hostname to find a token.
 type hostCache struct {
 	Cache
@@ -173,6 +292,21 @@ func (c *hostCache) Set(ctx context.Context, registry string, scheme Scheme, key
 	return c.Cache.Set(ctx, registry, scheme, "", fetch)
 }
 
+// SetToken implements ExpiringCache.
+func (c *hostCache) SetToken(ctx context.Context, registry string, scheme Scheme, key string, fetch func(context.Context) (Token, error)) (string, error) {
+	return setToken(ctx, c.Cache, registry, scheme, "", fetch)
+}
+
+// EvictToken implements ExpiringCache.
+func (c *hostCache) EvictToken(ctx context.Context, registry string, scheme Scheme, key string) error {
+	return evictToken(ctx, c.Cache, registry, scheme, "")
+}
+
+// Evict implements ExpiringCache.
+func (c *hostCache) Evict(ctx context.Context, registry string) error {
+	return evict(ctx, c.Cache, registry)
+}
+
 // fallbackCache tries the primary cache then falls back to the secondary cache.
 type fallbackCache struct {
 	primary   Cache
@@ -213,6 +347,43 @@ func (fc *fallbackCache) Set(ctx context.Context, registry string, scheme Scheme
 	})
 }
 
+// SetToken implements ExpiringCache.
+func (fc *fallbackCache) SetToken(ctx context.Context, registry string, scheme Scheme, key string, fetch func(context.Context) (Token, error)) (string, error) {
+	var fetched Token
+	value, err := setToken(ctx, fc.primary, registry, scheme, key, func(ctx context.Context) (Token, error) {
+		token, err := fetch(ctx)
+		fetched = token
+		return token, err
+	})
+	if err != nil {
+		return "", err
+	}
+	if fetched.Value != value {
+		// the token was fetched by a concurrent call
+		fetched = Token{Value: value}
+	}
+
+	return setToken(ctx, fc.secondary, registry, scheme, key, func(ctx context.Context) (Token, error) {
+		return fetched, nil
+	})
+}
+
+// EvictToken implements ExpiringCache.
+func (fc *fallbackCache) EvictToken(ctx context.Context, registry string, scheme Scheme, key string) error {
+	if err := evictToken(ctx, fc.primary, registry, scheme, key); err != nil {
+		return err
+	}
+	return evictToken(ctx, fc.secondary, registry, scheme, key)
+}
+
+// Evict implements ExpiringCache.
+func (fc *fallbackCache) Evict(ctx context.Context, registry string) error {
+	if err := evict(ctx, fc.primary, registry); err != nil {
+		return err
+	}
+	return evict(ctx, fc.secondary, registry)
+}
+
 // NewSingleContextCache creates a host-based cache for optimizing the auth flow for non-compliant registries.
 // It is intended to be used in a single context, such as pulling from a single repository.
 // This cache should not be shared.
@@ -230,3 +401,35 @@ func NewSingleContextCache() Cache {
 		secondary: &hostCache{cache},
 	}
 }
+
+// setToken fetches and caches the token, tracking the expiry of the token if
+// the cache implements ExpiringCache.
+func setToken(ctx context.Context, cache Cache, registry string, scheme Scheme, key string, fetch func(context.Context) (Token, error)) (string, error) {
+	if ec, ok := cache.(ExpiringCache); ok {
+		return ec.SetToken(ctx, registry, scheme, key, fetch)
+	}
+	return cache.Set(ctx, registry, scheme, key, func(ctx context.Context) (string, error) {
+		token, err := fetch(ctx)
+		if err != nil {
+			return "", err
+		}
+		return token.Value, nil
+	})
+}
+
+// evictToken evicts the token if the cache implements ExpiringCache.
+func evictToken(ctx context.Context, cache Cache, registry string, scheme Scheme, key string) error {
+	if ec, ok := cache.(ExpiringCache); ok {
+		return ec.EvictToken(ctx, registry, scheme, key)
+	}
+	return nil
+}
+
+// evict evicts the cache of the registry if the cache implements
+// ExpiringCache.
+func evict(ctx context.Context, cache Cache, registry string) error {
+	if ec, ok := cache.(ExpiringCache); ok {
+		return ec.Evict(ctx, registry)
+	}
+	return nil
+}
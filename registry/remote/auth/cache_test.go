@@ -673,3 +673,204 @@ func Test_fallbackCache(t *testing.T) {
 		}
 	}
 }
+
+func Test_concurrentCache_SetToken_Refresh(t *testing.T) {
+	cache := NewCache().(ExpiringCache)
+	ctx := context.Background()
+	registry := "localhost:5000"
+	scheme := SchemeBearer
+	key := "key"
+
+	// a token expiring soon is refreshed on GetToken
+	var count int64
+	fetch := func(expiresAt time.Time) func(context.Context) (Token, error) {
+		return func(context.Context) (Token, error) {
+			n := atomic.AddInt64(&count, 1)
+			return Token{
+				Value:     strconv.FormatInt(n, 10),
+				ExpiresAt: expiresAt,
+			}, nil
+		}
+	}
+	got, err := cache.SetToken(ctx, registry, scheme, key, fetch(time.Now().Add(time.Second)))
+	if err != nil {
+		t.Fatalf("concurrentCache.SetToken() error = %v", err)
+	}
+	if want := "1"; got != want {
+		t.Errorf("concurrentCache.SetToken() = %v, want %v", got, want)
+	}
+	got, err = cache.GetToken(ctx, registry, scheme, key)
+	if err != nil {
+		t.Fatalf("concurrentCache.GetToken() error = %v", err)
+	}
+	if want := "1"; got != want {
+		t.Errorf("concurrentCache.GetToken() = %v, want %v", got, want)
+	}
+
+	// an expired token is refreshed on GetToken
+	if _, err := cache.SetToken(ctx, registry, scheme, key, fetch(time.Now().Add(-time.Second))); err != nil {
+		t.Fatalf("concurrentCache.SetToken() error = %v", err)
+	}
+	got, err = cache.GetToken(ctx, registry, scheme, key)
+	if err != nil {
+		t.Fatalf("concurrentCache.GetToken() error = %v", err)
+	}
+	if want := "3"; got != want {
+		t.Errorf("concurrentCache.GetToken() = %v, want %v", got, want)
+	}
+
+	// an expired token is evicted if the refresh fails
+	refreshErr := errors.New("refresh failed")
+	if _, err := cache.SetToken(ctx, registry, scheme, key, func(context.Context) (Token, error) {
+		if atomic.AddInt64(&count, 1) > 4 {
+			return Token{}, refreshErr
+		}
+		return Token{Value: "4", ExpiresAt: time.Now().Add(-time.Second)}, nil
+	}); err != nil {
+		t.Fatalf("concurrentCache.SetToken() error = %v", err)
+	}
+	if _, err := cache.GetToken(ctx, registry, scheme, key); !errors.Is(err, errdef.ErrNotFound) {
+		t.Fatalf("concurrentCache.GetToken() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if _, err := cache.GetToken(ctx, registry, scheme, key); !errors.Is(err, errdef.ErrNotFound) {
+		t.Fatalf("concurrentCache.GetToken() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+
+	// a token without expiry is never refreshed
+	if _, err := cache.SetToken(ctx, registry, scheme, key, fetch(time.Time{})); err != nil {
+		t.Fatalf("concurrentCache.SetToken() error = %v", err)
+	}
+	want := strconv.FormatInt(atomic.LoadInt64(&count), 10)
+	for i := 0; i < 2; i++ {
+		got, err = cache.GetToken(ctx, registry, scheme, key)
+		if err != nil {
+			t.Fatalf("concurrentCache.GetToken() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("concurrentCache.GetToken() = %v, want %v", got, want)
+		}
+	}
+}
+
+func Test_concurrentCache_Set_JWTExpiry(t *testing.T) {
+	cache := NewCache()
+	ctx := context.Background()
+	registry := "localhost:5000"
+
+	// an expired JWT is refreshed on GetToken
+	token := newTestJWT(t, time.Now().Add(-time.Minute))
+	var fetchCount int64
+	fetch := func(context.Context) (string, error) {
+		atomic.AddInt64(&fetchCount, 1)
+		return token, nil
+	}
+	if _, err := cache.Set(ctx, registry, SchemeBearer, "key", fetch); err != nil {
+		t.Fatalf("concurrentCache.Set() error = %v", err)
+	}
+	got, err := cache.GetToken(ctx, registry, SchemeBearer, "key")
+	if err != nil {
+		t.Fatalf("concurrentCache.GetToken() error = %v", err)
+	}
+	if got != token {
+		t.Errorf("concurrentCache.GetToken() = %v, want %v", got, token)
+	}
+	if want := int64(2); fetchCount != want {
+		t.Errorf("fetch count = %v, want %v", fetchCount, want)
+	}
+
+	// a basic token is never refreshed
+	if _, err := cache.Set(ctx, "basic.example.com", SchemeBasic, "", fetch); err != nil {
+		t.Fatalf("concurrentCache.Set() error = %v", err)
+	}
+	if _, err := cache.GetToken(ctx, "basic.example.com", SchemeBasic, ""); err != nil {
+		t.Fatalf("concurrentCache.GetToken() error = %v", err)
+	}
+	if want := int64(3); fetchCount != want {
+		t.Errorf("fetch count = %v, want %v", fetchCount, want)
+	}
+
+	// a valid JWT is returned
+	token = newTestJWT(t, time.Now().Add(time.Hour))
+	if _, err := cache.Set(ctx, registry, SchemeBearer, "key", func(context.Context) (string, error) {
+		return token, nil
+	}); err != nil {
+		t.Fatalf("concurrentCache.Set() error = %v", err)
+	}
+	got, err = cache.GetToken(ctx, registry, SchemeBearer, "key")
+	if err != nil {
+		t.Fatalf("concurrentCache.GetToken() error = %v", err)
+	}
+	if got != token {
+		t.Errorf("concurrentCache.GetToken() = %v, want %v", got, token)
+	}
+}
+
+func Test_concurrentCache_Evict(t *testing.T) {
+	cache := NewCache().(ExpiringCache)
+	ctx := context.Background()
+	registry := "localhost:5000"
+	fetch := func(token string) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) {
+			return token, nil
+		}
+	}
+	for _, key := range []string{"key1", "key2"} {
+		if _, err := cache.Set(ctx, registry, SchemeBearer, key, fetch(key)); err != nil {
+			t.Fatalf("concurrentCache.Set() error = %v", err)
+		}
+	}
+
+	// evict a single token
+	if err := cache.EvictToken(ctx, registry, SchemeBearer, "key1"); err != nil {
+		t.Fatalf("concurrentCache.EvictToken() error = %v", err)
+	}
+	if _, err := cache.GetToken(ctx, registry, SchemeBearer, "key1"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("concurrentCache.GetToken() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if got, err := cache.GetToken(ctx, registry, SchemeBearer, "key2"); err != nil || got != "key2" {
+		t.Errorf("concurrentCache.GetToken() = %v, %v, want %v", got, err, "key2")
+	}
+
+	// evict the registry
+	if err := cache.Evict(ctx, registry); err != nil {
+		t.Fatalf("concurrentCache.Evict() error = %v", err)
+	}
+	if _, err := cache.GetScheme(ctx, registry); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("concurrentCache.GetScheme() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if _, err := cache.GetToken(ctx, registry, SchemeBearer, "key2"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("concurrentCache.GetToken() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+}
+
+func Test_fallbackCache_SetToken(t *testing.T) {
+	cache := NewSingleContextCache().(ExpiringCache)
+	ctx := context.Background()
+	registry := "reg.example.com"
+	expiresAt := time.Now().Add(time.Hour)
+	if _, err := cache.SetToken(ctx, registry, SchemeBearer, "key1", func(context.Context) (Token, error) {
+		return Token{Value: "foo", ExpiresAt: expiresAt}, nil
+	}); err != nil {
+		t.Fatalf("fallbackCache.SetToken() error = %v", err)
+	}
+
+	// the expiry is tracked by both primary and secondary caches
+	primary := cache.(*fallbackCache).primary.(*concurrentCache)
+	entryValue, _ := primary.cache.Load(registry)
+	for _, key := range []string{"key1", ""} {
+		value, ok := entryValue.(*cacheEntry).tokens.Load(key)
+		if !ok {
+			t.Fatalf("token of key %q is not cached", key)
+		}
+		if got := value.(*tokenEntry).token.ExpiresAt; !got.Equal(expiresAt) {
+			t.Errorf("token of key %q expires at %v, want %v", key, got, expiresAt)
+		}
+	}
+
+	if err := cache.EvictToken(ctx, registry, SchemeBearer, "key1"); err != nil {
+		t.Fatalf("fallbackCache.EvictToken() error = %v", err)
+	}
+	if _, err := cache.GetToken(ctx, registry, SchemeBearer, "key1"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("fallbackCache.GetToken() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+}
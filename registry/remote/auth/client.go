@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"oras.land/oras-go/v2/registry/remote/internal/errutil"
 	"oras.land/oras-go/v2/registry/remote/retry"
@@ -65,6 +66,64 @@ var defaultClientID = "oras-go"
 // an error.
 type CredentialFunc func(ctx context.Context, hostport string) (Credential, error)
 
+// ScopedCredentialFunc represents a function that resolves the credential for
+// the given registry (i.e. host:port) and the scopes requested for the
+// registry, allowing different credentials for different repositories of the
+// same registry.
+//
+// [EmptyCredential] is a valid return value and should not be considered as
+// an error.
+type ScopedCredentialFunc func(ctx context.Context, hostport string, scopes []string) (Credential, error)
+
+// RepositoryCredentialFunc represents a function that resolves the credential
+// for the given repository of the given registry (i.e. host:port).
+// The repository is empty if the credential is requested for the registry as
+// a whole.
+//
+// [EmptyCredential] is a valid return value and should not be considered as
+// an error.
+type RepositoryCredentialFunc func(ctx context.Context, hostport string, repository string) (Credential, error)
+
+// ScopedCredential adapts the CredentialFunc to a ScopedCredentialFunc
+// ignoring the scopes.
+func ScopedCredential(fn CredentialFunc) ScopedCredentialFunc {
+	return func(ctx context.Context, hostport string, _ []string) (Credential, error) {
+		return fn(ctx, hostport)
+	}
+}
+
+// RepositoryCredential adapts the RepositoryCredentialFunc to a
+// ScopedCredentialFunc. The repository is resolved from the repository scopes.
+// If the scopes refer to no repository or more than one repository, such as
+// cross-repository blob mounts, the repository is resolved to empty.
+func RepositoryCredential(fn RepositoryCredentialFunc) ScopedCredentialFunc {
+	return func(ctx context.Context, hostport string, scopes []string) (Credential, error) {
+		return fn(ctx, hostport, scopeRepository(scopes))
+	}
+}
+
+// scopeRepository returns the repository if the scopes refer to exactly one
+// repository. Otherwise, an empty string is returned.
+func scopeRepository(scopes []string) string {
+	var repository string
+	for _, scope := range scopes {
+		resourceType, rest, ok := strings.Cut(scope, ":")
+		if !ok || resourceType != "repository" {
+			continue
+		}
+		i := strings.LastIndex(rest, ":")
+		if i == -1 {
+			continue
+		}
+		name := rest[:i]
+		if repository != "" && name != repository {
+			return ""
+		}
+		repository = name
+	}
+	return repository
+}
+
 // StaticCredential specifies static credentials for the given host.
 func StaticCredential(registry string, cred Credential) CredentialFunc {
 	if registry == "docker.io" {
@@ -102,8 +161,20 @@ type Client struct {
 	// EmptyCredential is a valid return value and should not be considered as
 	// an error.
 	// If nil, the credential is always resolved to EmptyCredential.
+	// Credential is ignored if ScopedCredential is set.
 	Credential CredentialFunc
 
+	// ScopedCredential specifies the function for resolving the credential for
+	// the given registry (i.e. host:port) and the requested scopes, taking
+	// precedence over Credential. The scopes are the scopes of the bearer
+	// token to be fetched, or the scope hints of the request for basic auth.
+	// Basic auth tokens are cached per scopes if ScopedCredential is set.
+	// EmptyCredential is a valid return value and should not be considered as
+	// an error.
+	// The adapters ScopedCredential() and RepositoryCredential() are available
+	// for the functions not aware of scopes.
+	ScopedCredential ScopedCredentialFunc
+
 	// Cache caches credentials for direct accessing the remote registry.
 	// If nil, no cache is used.
 	Cache Cache
@@ -120,6 +191,22 @@ type Client struct {
 	// - https://docs.docker.com/registry/spec/auth/jwt/
 	// - https://docs.docker.com/registry/spec/auth/oauth/
 	ForceAttemptOAuth2 bool
+
+	// OfflineAccess controls whether to request a refresh token by setting
+	// `access_type=offline` when fetching OAuth2 tokens with password grant.
+	// The returned refresh token is passed to HandleRefreshToken.
+	// Reference: https://docs.docker.com/registry/spec/auth/oauth/#getting-a-token
+	OfflineAccess bool
+
+	// HandleRefreshToken handles the refresh token returned by the
+	// authorization service for the given registry (i.e. host:port) when
+	// fetching OAuth2 tokens. It is also called if the authorization service
+	// rotates the refresh token in a refresh token grant.
+	// The returned refresh token can be saved as the RefreshToken of the
+	// Credential, so that subsequent authentications do not need passwords.
+	// If HandleRefreshToken returns an error, fetching the OAuth2 token fails.
+	// If nil, the returned refresh tokens are discarded.
+	HandleRefreshToken func(ctx context.Context, registry string, refreshToken string) error
 }
 
 // client returns an HTTP client used to access the remote registry.
@@ -139,14 +226,28 @@ func (c *Client) send(req *http.Request) (*http.Response, error) {
 	return c.client().Do(req)
 }
 
-// credential resolves the credential for the given registry.
-func (c *Client) credential(ctx context.Context, reg string) (Credential, error) {
+// credential resolves the credential for the given registry and scopes.
+func (c *Client) credential(ctx context.Context, reg string, scopes []string) (Credential, error) {
+	if c.ScopedCredential != nil {
+		return c.ScopedCredential(ctx, reg, scopes)
+	}
 	if c.Credential == nil {
 		return EmptyCredential, nil
 	}
 	return c.Credential(ctx, reg)
 }
 
+// basicKey returns the cache key of the basic auth token for the given
+// registry. Basic auth tokens are cached per scopes only if the credential
+// depends on scopes.
+func (c *Client) basicKey(ctx context.Context, reg string) (string, []string) {
+	if c.ScopedCredential == nil {
+		return "", nil
+	}
+	scopes := GetAllScopesForHost(ctx, reg)
+	return strings.Join(scopes, " "), scopes
+}
+
 // cache resolves the cache.
 // noCache is return if the cache is not configured.
 func (c *Client) cache() Cache {
@@ -186,7 +287,8 @@ func (c *Client) Do(originalReq *http.Request) (*http.Response, error) {
 	if err == nil {
 		switch scheme {
 		case SchemeBasic:
-			token, err := cache.GetToken(ctx, host, SchemeBasic, "")
+			key, _ := c.basicKey(ctx, host)
+			token, err := cache.GetToken(ctx, host, SchemeBasic, key)
 			if err == nil {
 				req.Header.Set("Authorization", "Basic "+token)
 			}
@@ -215,8 +317,9 @@ func (c *Client) Do(originalReq *http.Request) (*http.Response, error) {
 	case SchemeBasic:
 		resp.Body.Close()
 
-		token, err := cache.Set(ctx, host, SchemeBasic, "", func(ctx context.Context) (string, error) {
-			return c.fetchBasicAuth(ctx, host)
+		key, scopes := c.basicKey(ctx, host)
+		token, err := cache.Set(ctx, host, SchemeBasic, key, func(ctx context.Context) (string, error) {
+			return c.fetchBasicAuth(ctx, host, scopes)
 		})
 		if err != nil {
 			return nil, fmt.Errorf("%s %q: %w", resp.Request.Method, resp.Request.URL, err)
@@ -258,7 +361,7 @@ func (c *Client) Do(originalReq *http.Request) (*http.Response, error) {
 		// attempt with credentials
 		realm := params["realm"]
 		service := params["service"]
-		token, err := cache.Set(ctx, host, SchemeBearer, key, func(ctx context.Context) (string, error) {
+		token, err := setToken(ctx, cache, host, SchemeBearer, key, func(ctx context.Context) (Token, error) {
 			return c.fetchBearerToken(ctx, host, realm, service, scopes)
 		})
 		if err != nil {
@@ -278,8 +381,8 @@ func (c *Client) Do(originalReq *http.Request) (*http.Response, error) {
 }
 
 // fetchBasicAuth fetches a basic auth token for the basic challenge.
-func (c *Client) fetchBasicAuth(ctx context.Context, registry string) (string, error) {
-	cred, err := c.credential(ctx, registry)
+func (c *Client) fetchBasicAuth(ctx context.Context, registry string, scopes []string) (string, error) {
+	cred, err := c.credential(ctx, registry, scopes)
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve credential: %w", err)
 	}
@@ -294,18 +397,21 @@ func (c *Client) fetchBasicAuth(ctx context.Context, registry string) (string, e
 }
 
 // fetchBearerToken fetches an access token for the bearer challenge.
-func (c *Client) fetchBearerToken(ctx context.Context, registry, realm, service string, scopes []string) (string, error) {
-	cred, err := c.credential(ctx, registry)
+func (c *Client) fetchBearerToken(ctx context.Context, registry, realm, service string, scopes []string) (Token, error) {
+	cred, err := c.credential(ctx, registry, scopes)
 	if err != nil {
-		return "", err
+		return Token{}, err
 	}
 	if cred.AccessToken != "" {
-		return cred.AccessToken, nil
+		return Token{
+			Value:     cred.AccessToken,
+			ExpiresAt: jwtExpiry(cred.AccessToken),
+		}, nil
 	}
 	if cred == EmptyCredential || (cred.RefreshToken == "" && !c.ForceAttemptOAuth2) {
 		return c.fetchDistributionToken(ctx, realm, service, scopes, cred.Username, cred.Password)
 	}
-	return c.fetchOAuth2Token(ctx, realm, service, scopes, cred)
+	return c.fetchOAuth2Token(ctx, registry, realm, service, scopes, cred)
 }
 
 // fetchDistributionToken fetches an access token as defined by the distribution
@@ -314,10 +420,10 @@ func (c *Client) fetchBearerToken(ctx context.Context, registry, realm, service
 // References:
 // - https://docs.docker.com/registry/spec/auth/jwt/
 // - https://docs.docker.com/registry/spec/auth/token/
-func (c *Client) fetchDistributionToken(ctx context.Context, realm, service string, scopes []string, username, password string) (string, error) {
+func (c *Client) fetchDistributionToken(ctx context.Context, realm, service string, scopes []string, username, password string) (Token, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm, nil)
 	if err != nil {
-		return "", err
+		return Token{}, err
 	}
 	if username != "" || password != "" {
 		req.SetBasicAuth(username, password)
@@ -333,12 +439,13 @@ func (c *Client) fetchDistributionToken(ctx context.Context, realm, service stri
 
 	resp, err := c.send(req)
 	if err != nil {
-		return "", err
+		return Token{}, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", errutil.ParseErrorResponse(resp)
+		return Token{}, errutil.ParseErrorResponse(resp)
 	}
+	received := time.Now()
 
 	// As specified in https://docs.docker.com/registry/spec/auth/token/ section
 	// "Token Response Fields", the token is either in `token` or
@@ -346,23 +453,29 @@ func (c *Client) fetchDistributionToken(ctx context.Context, realm, service stri
 	var result struct {
 		Token       string `json:"token"`
 		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		IssuedAt    string `json:"issued_at"`
 	}
 	lr := io.LimitReader(resp.Body, maxResponseBytes)
 	if err := json.NewDecoder(lr).Decode(&result); err != nil {
-		return "", fmt.Errorf("%s %q: failed to decode response: %w", resp.Request.Method, resp.Request.URL, err)
+		return Token{}, fmt.Errorf("%s %q: failed to decode response: %w", resp.Request.Method, resp.Request.URL, err)
 	}
-	if result.AccessToken != "" {
-		return result.AccessToken, nil
+	token := result.AccessToken
+	if token == "" {
+		token = result.Token
 	}
-	if result.Token != "" {
-		return result.Token, nil
+	if token == "" {
+		return Token{}, fmt.Errorf("%s %q: empty token returned", resp.Request.Method, resp.Request.URL)
 	}
-	return "", fmt.Errorf("%s %q: empty token returned", resp.Request.Method, resp.Request.URL)
+	return Token{
+		Value:     token,
+		ExpiresAt: tokenExpiry(token, result.ExpiresIn, result.IssuedAt, received),
+	}, nil
 }
 
 // fetchOAuth2Token fetches an OAuth2 access token.
 // Reference: https://docs.docker.com/registry/spec/auth/oauth/
-func (c *Client) fetchOAuth2Token(ctx context.Context, realm, service string, scopes []string, cred Credential) (string, error) {
+func (c *Client) fetchOAuth2Token(ctx context.Context, registry, realm, service string, scopes []string, cred Credential) (Token, error) {
 	form := url.Values{}
 	if cred.RefreshToken != "" {
 		form.Set("grant_type", "refresh_token")
@@ -371,8 +484,11 @@ func (c *Client) fetchOAuth2Token(ctx context.Context, realm, service string, sc
 		form.Set("grant_type", "password")
 		form.Set("username", cred.Username)
 		form.Set("password", cred.Password)
+		if c.OfflineAccess {
+			form.Set("access_type", "offline")
+		}
 	} else {
-		return "", errors.New("missing username or password for bearer auth")
+		return Token{}, errors.New("missing username or password for bearer auth")
 	}
 	form.Set("service", service)
 	clientID := c.ClientID
@@ -387,30 +503,42 @@ func (c *Client) fetchOAuth2Token(ctx context.Context, realm, service string, sc
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, realm, body)
 	if err != nil {
-		return "", err
+		return Token{}, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := c.send(req)
 	if err != nil {
-		return "", err
+		return Token{}, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", errutil.ParseErrorResponse(resp)
+		return Token{}, errutil.ParseErrorResponse(resp)
 	}
+	received := time.Now()
 
 	var result struct {
-		AccessToken string `json:"access_token"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		IssuedAt     string `json:"issued_at"`
 	}
 	lr := io.LimitReader(resp.Body, maxResponseBytes)
 	if err := json.NewDecoder(lr).Decode(&result); err != nil {
-		return "", fmt.Errorf("%s %q: failed to decode response: %w", resp.Request.Method, resp.Request.URL, err)
+		return Token{}, fmt.Errorf("%s %q: failed to decode response: %w", resp.Request.Method, resp.Request.URL, err)
 	}
-	if result.AccessToken != "" {
-		return result.AccessToken, nil
+	if result.AccessToken == "" {
+		return Token{}, fmt.Errorf("%s %q: empty token returned", resp.Request.Method, resp.Request.URL)
+	}
+	if result.RefreshToken != "" && result.RefreshToken != cred.RefreshToken && c.HandleRefreshToken != nil {
+		if err := c.HandleRefreshToken(ctx, registry, result.RefreshToken); err != nil {
+			return Token{}, fmt.Errorf("failed to handle refresh token: %w", err)
+		}
 	}
-	return "", fmt.Errorf("%s %q: empty token returned", resp.Request.Method, resp.Request.URL)
+	return Token{
+		Value:     result.AccessToken,
+		ExpiresAt: tokenExpiry(result.AccessToken, result.ExpiresIn, result.IssuedAt, received),
+	}, nil
 }
 
 // rewindRequestBody tries to rewind the request body if exists.
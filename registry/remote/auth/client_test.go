@@ -20,6 +20,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -27,6 +28,7 @@ import (
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"oras.land/oras-go/v2/registry/remote/errcode"
 )
@@ -1862,6 +1864,122 @@ func TestClient_Do_Bearer_OAuth2_Password_Cached_PerHost(t *testing.T) {
 	}
 }
 
+func TestClient_Do_Bearer_OAuth2_OfflineAccess(t *testing.T) {
+	username := "test_user"
+	password := "test_password"
+	accessToken := "test/access/token"
+	refreshToken := "test/refresh/token"
+	as := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.PostForm.Get("grant_type") {
+		case "password":
+			if got := r.PostForm.Get("access_type"); got != "offline" {
+				t.Errorf("unexpected access type: %v, want %v", got, "offline")
+			}
+			if _, err := fmt.Fprintf(w, `{"access_token":%q,"refresh_token":%q}`, accessToken, refreshToken); err != nil {
+				t.Errorf("failed to write %q: %v", r.URL, err)
+			}
+		case "refresh_token":
+			if got := r.PostForm.Get("access_type"); got != "" {
+				t.Errorf("unexpected access type: %v, want %v", got, "")
+			}
+			// the refresh token is not rotated
+			if _, err := fmt.Fprintf(w, `{"access_token":%q,"refresh_token":%q}`, accessToken, refreshToken); err != nil {
+				t.Errorf("failed to write %q: %v", r.URL, err)
+			}
+		default:
+			t.Errorf("unexpected grant type: %v", r.PostForm.Get("grant_type"))
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer as.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer "+accessToken {
+			challenge := fmt.Sprintf("Bearer realm=%q,service=%q", as.URL, "test")
+			w.Header().Set("Www-Authenticate", challenge)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	var handled []string
+	cred := Credential{
+		Username: username,
+		Password: password,
+	}
+	client := &Client{
+		Credential: func(ctx context.Context, reg string) (Credential, error) {
+			return cred, nil
+		},
+		ForceAttemptOAuth2: true,
+		OfflineAccess:      true,
+		HandleRefreshToken: func(ctx context.Context, registry string, token string) error {
+			if registry != uri.Host {
+				t.Errorf("unexpected registry: %v, want %v", registry, uri.Host)
+			}
+			handled = append(handled, token)
+			return nil
+		},
+	}
+
+	// password grant returns a refresh token
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create test request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Client.Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Client.Do() = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+	if want := []string{refreshToken}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled refresh tokens = %v, want %v", handled, want)
+	}
+
+	// the same refresh token is not handled again
+	cred = Credential{
+		RefreshToken: refreshToken,
+	}
+	req, err = http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create test request: %v", err)
+	}
+	if _, err := client.Do(req); err != nil {
+		t.Fatalf("Client.Do() error = %v", err)
+	}
+	if want := []string{refreshToken}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled refresh tokens = %v, want %v", handled, want)
+	}
+
+	// errors of the handler fail the authentication
+	handleErr := errors.New("failed to save")
+	client.HandleRefreshToken = func(ctx context.Context, registry string, token string) error {
+		return handleErr
+	}
+	cred = Credential{
+		Username: username,
+		Password: password,
+	}
+	req, err = http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create test request: %v", err)
+	}
+	if _, err := client.Do(req); !errors.Is(err, handleErr) {
+		t.Errorf("Client.Do() error = %v, wantErr %v", err, handleErr)
+	}
+}
+
 func TestClient_Do_Bearer_OAuth2_RefreshToken(t *testing.T) {
 	refreshToken := "test/refresh/token"
 	accessToken := "test/access/token"
@@ -2612,6 +2730,101 @@ func TestClient_Do_Token_Expire(t *testing.T) {
 	}
 }
 
+func TestClient_Do_Token_Refresh(t *testing.T) {
+	var requestCount, wantRequestCount int64
+	var unauthorizedCount int64
+	var authCount, wantAuthCount int64
+	var service string
+	scope := "repository:test:push"
+	as := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/" {
+			t.Error("unexecuted attempt of authorization service")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		count := atomic.AddInt64(&authCount, 1)
+		// the token is issued long ago so that it expires immediately
+		issuedAt := time.Now().Add(-time.Hour).Format(time.RFC3339)
+		if _, err := fmt.Fprintf(w, `{"token":"token_%d","expires_in":60,"issued_at":%q}`, count, issuedAt); err != nil {
+			t.Errorf("failed to write %q: %v", r.URL, err)
+		}
+	}))
+	defer as.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requestCount, 1)
+		if r.Method != http.MethodPut || r.URL.Path != "/" {
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		header := fmt.Sprintf("Bearer token_%d", atomic.LoadInt64(&authCount))
+		if auth := r.Header.Get("Authorization"); auth != header {
+			atomic.AddInt64(&unauthorizedCount, 1)
+			challenge := fmt.Sprintf("Bearer realm=%q,service=%q,scope=%q", as.URL, service, scope)
+			w.Header().Set("Www-Authenticate", challenge)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, err := io.ReadAll(r.Body); err != nil {
+			t.Errorf("failed to read body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+	service = uri.Host
+
+	client := &Client{
+		Cache: NewCache(),
+	}
+	ctx := WithScopes(context.Background(), scope)
+
+	// first request with a rewindable body
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, ts.URL, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("failed to create test request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Client.Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Client.Do() = %v, want %v", resp.StatusCode, http.StatusCreated)
+	}
+	if wantRequestCount += 2; requestCount != wantRequestCount {
+		t.Errorf("unexpected number of requests: %d, want %d", requestCount, wantRequestCount)
+	}
+	if wantAuthCount++; authCount != wantAuthCount {
+		t.Errorf("unexpected number of auth requests: %d, want %d", authCount, wantAuthCount)
+	}
+
+	// the expired token is refreshed before sending the request so that
+	// non-rewindable bodies can be sent
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, ts.URL, io.NopCloser(strings.NewReader("hello")))
+	if err != nil {
+		t.Fatalf("failed to create test request: %v", err)
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Client.Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Client.Do() = %v, want %v", resp.StatusCode, http.StatusCreated)
+	}
+	if wantRequestCount++; requestCount != wantRequestCount {
+		t.Errorf("unexpected number of requests: %d, want %d", requestCount, wantRequestCount)
+	}
+	if wantAuthCount++; authCount != wantAuthCount {
+		t.Errorf("unexpected number of auth requests: %d, want %d", authCount, wantAuthCount)
+	}
+	if want := int64(1); unauthorizedCount != want {
+		t.Errorf("unexpected number of unauthorized requests: %d, want %d", unauthorizedCount, want)
+	}
+}
+
 func TestClient_Do_Token_Expire_PerHost(t *testing.T) {
 	// set up server 1
 	refreshToken1 := "test/refresh/token/1"
@@ -3971,8 +4184,209 @@ func TestClient_fetchBasicAuth(t *testing.T) {
 			return EmptyCredential, nil
 		},
 	}
-	_, err := c.fetchBasicAuth(context.Background(), "")
+	_, err := c.fetchBasicAuth(context.Background(), "", nil)
 	if err != ErrBasicCredentialNotFound {
 		t.Errorf("incorrect error: %v, expected %v", err, ErrBasicCredentialNotFound)
 	}
 }
+
+func TestClient_Do_RepositoryCredential_Basic_Cached(t *testing.T) {
+	creds := map[string]Credential{
+		"team-a/app": {Username: "user_a", Password: "password_a"},
+		"team-b/app": {Username: "user_b", Password: "password_b"},
+	}
+	var requestCount, wantRequestCount int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requestCount, 1)
+		repository := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/tags/list")
+		cred, ok := creds[repository]
+		if r.Method != http.MethodGet || !ok {
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		header := "Basic " + base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+cred.Password))
+		if auth := r.Header.Get("Authorization"); auth != header {
+			w.Header().Set("Www-Authenticate", `Basic realm="Test Server"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	client := &Client{
+		Credential: func(ctx context.Context, reg string) (Credential, error) {
+			t.Error("Credential() should not be called")
+			return EmptyCredential, nil
+		},
+		ScopedCredential: RepositoryCredential(func(ctx context.Context, hostport string, repository string) (Credential, error) {
+			if hostport != uri.Host {
+				err := fmt.Errorf("registry mismatch: got %v, want %v", hostport, uri.Host)
+				t.Error(err)
+				return EmptyCredential, err
+			}
+			return creds[repository], nil
+		}),
+		Cache: NewCache(),
+	}
+
+	for _, repository := range []string{"team-a/app", "team-b/app", "team-a/app", "team-b/app"} {
+		ctx := AppendScopesForHost(context.Background(), uri.Host, ScopeRepository(repository, ActionPull))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/v2/"+repository+"/tags/list", nil)
+		if err != nil {
+			t.Fatalf("failed to create test request: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Client.Do() error = %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Client.Do() = %v, want %v", resp.StatusCode, http.StatusOK)
+		}
+	}
+	// the first requests for each repository are challenged, and the basic
+	// auth tokens of the repositories are cached separately
+	if wantRequestCount = 6; requestCount != wantRequestCount {
+		t.Errorf("unexpected number of requests: %d, want %d", requestCount, wantRequestCount)
+	}
+}
+
+func TestClient_Do_ScopedCredential_Bearer(t *testing.T) {
+	creds := map[string]Credential{
+		"team-a/app": {Username: "user_a", Password: "password_a"},
+		"team-b/app": {Username: "user_b", Password: "password_b"},
+	}
+	service := "test registry"
+	as := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/" {
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if got := r.URL.Query().Get("service"); got != service {
+			t.Errorf("unexpected service: %v, want %v", got, service)
+		}
+		scope := r.URL.Query().Get("scope")
+		repository := strings.TrimSuffix(strings.TrimPrefix(scope, "repository:"), ":pull")
+		cred := creds[repository]
+		header := "Basic " + base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+cred.Password))
+		if auth := r.Header.Get("Authorization"); auth != header {
+			t.Errorf("unexpected auth for %s: got %s, want %s", scope, auth, header)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"token_%s"}`, cred.Username)
+	}))
+	defer as.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repository := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/tags/list")
+		cred, ok := creds[repository]
+		if r.Method != http.MethodGet || !ok {
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token_"+cred.Username {
+			challenge := fmt.Sprintf("Bearer realm=%q,service=%q,scope=%q", as.URL, service, ScopeRepository(repository, ActionPull))
+			w.Header().Set("Www-Authenticate", challenge)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	var gotScopes [][]string
+	client := &Client{
+		ScopedCredential: func(ctx context.Context, hostport string, scopes []string) (Credential, error) {
+			if hostport != uri.Host {
+				err := fmt.Errorf("registry mismatch: got %v, want %v", hostport, uri.Host)
+				t.Error(err)
+				return EmptyCredential, err
+			}
+			gotScopes = append(gotScopes, scopes)
+			return creds[scopeRepository(scopes)], nil
+		},
+	}
+	for _, repository := range []string{"team-a/app", "team-b/app"} {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/v2/"+repository+"/tags/list", nil)
+		if err != nil {
+			t.Fatalf("failed to create test request: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Client.Do() error = %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Client.Do() = %v, want %v", resp.StatusCode, http.StatusOK)
+		}
+	}
+	wantScopes := [][]string{
+		{"repository:team-a/app:pull"},
+		{"repository:team-b/app:pull"},
+	}
+	if !reflect.DeepEqual(gotScopes, wantScopes) {
+		t.Errorf("ScopedCredential() scopes = %v, want %v", gotScopes, wantScopes)
+	}
+}
+
+func TestScopedCredential(t *testing.T) {
+	want := Credential{Username: "username", Password: "password"}
+	fn := ScopedCredential(StaticCredential("registry.example.com", want))
+	got, err := fn(context.Background(), "registry.example.com", []string{"repository:app:pull"})
+	if err != nil {
+		t.Fatalf("ScopedCredential() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("ScopedCredential() = %v, want %v", got, want)
+	}
+}
+
+func Test_scopeRepository(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		want   string
+	}{
+		{
+			name: "no scope",
+		},
+		{
+			name:   "single repository",
+			scopes: []string{"repository:team/app:pull,push"},
+			want:   "team/app",
+		},
+		{
+			name:   "same repository",
+			scopes: []string{"repository:team/app:pull", "repository:team/app:delete"},
+			want:   "team/app",
+		},
+		{
+			name:   "with other resource types",
+			scopes: []string{ScopeRegistryCatalog, "repository:team/app:pull"},
+			want:   "team/app",
+		},
+		{
+			name:   "multiple repositories",
+			scopes: []string{"repository:team/app:pull", "repository:team/base:pull"},
+		},
+		{
+			name:   "registry only",
+			scopes: []string{ScopeRegistryCatalog},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scopeRepository(tt.scopes); got != tt.want {
+				t.Errorf("scopeRepository() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/internal/fs/filelock"
+)
+
+// fileCacheLockSuffix is the suffix of the lock file of a file cache.
+const fileCacheLockSuffix = ".lock"
+
+// fileCacheContent is the on-disk content of a file cache.
+type fileCacheContent struct {
+	// Registries maps registries to their cache entries.
+	Registries map[string]*fileCacheEntry `json:"registries"`
+}
+
+// fileCacheEntry is the on-disk cache entry for a single registry.
+type fileCacheEntry struct {
+	// Scheme is the auth-scheme of the registry.
+	Scheme string `json:"scheme"`
+	// Tokens maps keys to the cached bearer tokens.
+	Tokens map[string]fileCacheToken `json:"tokens,omitempty"`
+}
+
+// fileCacheToken is an on-disk cached bearer token.
+type fileCacheToken struct {
+	// Token is the auth-token.
+	Token string `json:"token"`
+	// ExpiresAt is the time when the token expires.
+	ExpiresAt time.Time `json:"expiresAt"`
+	// RefreshAt is the time after which the token should be refreshed.
+	RefreshAt time.Time `json:"refreshAt"`
+}
+
+// fileCache is a cache persisting the auth-schemes and bearer tokens in a file
+// so that they can be shared across processes.
+type fileCache struct {
+	// memory caches the tokens fetched by this process and refreshes them
+	// before they expire.
+	memory *concurrentCache
+	// path is the path of the cache file.
+	path string
+}
+
+// NewFileCache creates a cache persisting auth-schemes and bearer tokens in
+// the file at the given path, so that they can be shared by multiple processes
+// such as short-lived CLI invocations. The file and its parent directories are
+// created on the first write, where the file is only accessible by the
+// current user (permission 0600).
+//
+// Concurrent processes are coordinated by an advisory lock on a lock file
+// next to the cache file, with the suffix ".lock". The cache file is updated
+// atomically.
+//
+// Only bearer tokens with known expiry are persisted and they are no longer
+// used when they are about to expire. Basic auth tokens, which are encoded
+// credentials, are cached in memory only. Failures in persisting tokens are
+// ignored as the cache file is an optimization.
+//
+// The returned cache implements ExpiringCache.
+func NewFileCache(path string) Cache {
+	return &fileCache{
+		memory: &concurrentCache{},
+		path:   path,
+	}
+}
+
+// GetScheme returns the auth-scheme part cached for the given registry.
+func (fc *fileCache) GetScheme(ctx context.Context, registry string) (Scheme, error) {
+	if scheme, err := fc.memory.GetScheme(ctx, registry); err == nil {
+		return scheme, nil
+	}
+
+	content, err := fc.read()
+	if err != nil {
+		return SchemeUnknown, errdef.ErrNotFound
+	}
+	entry, ok := content.Registries[registry]
+	if !ok {
+		return SchemeUnknown, errdef.ErrNotFound
+	}
+	scheme := parseScheme(entry.Scheme)
+	if scheme == SchemeUnknown {
+		return SchemeUnknown, errdef.ErrNotFound
+	}
+	return scheme, nil
+}
+
+// GetToken returns the auth-token part cached for the given registry of a
+// given scheme.
+func (fc *fileCache) GetToken(ctx context.Context, registry string, scheme Scheme, key string) (string, error) {
+	if token, err := fc.memory.GetToken(ctx, registry, scheme, key); err == nil {
+		return token, nil
+	}
+	if scheme != SchemeBearer {
+		return "", errdef.ErrNotFound
+	}
+
+	content, err := fc.read()
+	if err != nil {
+		return "", errdef.ErrNotFound
+	}
+	entry, ok := content.Registries[registry]
+	if !ok || parseScheme(entry.Scheme) != scheme {
+		return "", errdef.ErrNotFound
+	}
+	token, ok := entry.Tokens[key]
+	if !ok || !time.Now().Before(token.RefreshAt) {
+		return "", errdef.ErrNotFound
+	}
+	return token.Token, nil
+}
+
+// Set fetches the token using the given fetch function and caches the token
+// for the given scheme with the given key for the given registry.
+func (fc *fileCache) Set(ctx context.Context, registry string, scheme Scheme, key string, fetch func(context.Context) (string, error)) (string, error) {
+	return fc.SetToken(ctx, registry, scheme, key, func(ctx context.Context) (Token, error) {
+		token, err := fetch(ctx)
+		if err != nil {
+			return Token{}, err
+		}
+		if scheme == SchemeBearer {
+			return Token{Value: token, ExpiresAt: jwtExpiry(token)}, nil
+		}
+		return Token{Value: token}, nil
+	})
+}
+
+// SetToken fetches the token with its expiry using the given fetch function
+// and caches the token for the given scheme with the given key for the given
+// registry.
+func (fc *fileCache) SetToken(ctx context.Context, registry string, scheme Scheme, key string, fetch func(context.Context) (Token, error)) (string, error) {
+	return fc.memory.SetToken(ctx, registry, scheme, key, func(ctx context.Context) (Token, error) {
+		token, err := fetch(ctx)
+		if err != nil {
+			return Token{}, err
+		}
+		// persist the token, including the refreshed ones
+		_ = fc.store(registry, scheme, key, token)
+		return token, nil
+	})
+}
+
+// EvictToken removes the token cached for the given scheme with the given key
+// for the given registry.
+func (fc *fileCache) EvictToken(ctx context.Context, registry string, scheme Scheme, key string) error {
+	if err := fc.memory.EvictToken(ctx, registry, scheme, key); err != nil {
+		return err
+	}
+	return fc.update(func(content *fileCacheContent) {
+		if entry, ok := content.Registries[registry]; ok && parseScheme(entry.Scheme) == scheme {
+			delete(entry.Tokens, key)
+		}
+	})
+}
+
+// Evict removes the scheme and all tokens cached for the given registry.
+func (fc *fileCache) Evict(ctx context.Context, registry string) error {
+	if err := fc.memory.Evict(ctx, registry); err != nil {
+		return err
+	}
+	return fc.update(func(content *fileCacheContent) {
+		delete(content.Registries, registry)
+	})
+}
+
+// store persists the scheme and the token for the given registry.
+func (fc *fileCache) store(registry string, scheme Scheme, key string, token Token) error {
+	return fc.update(func(content *fileCacheContent) {
+		entry, ok := content.Registries[registry]
+		if !ok || parseScheme(entry.Scheme) != scheme {
+			// invalidate all previous cache on scheme change
+			entry = &fileCacheEntry{
+				Scheme: scheme.String(),
+			}
+			content.Registries[registry] = entry
+		}
+		if scheme != SchemeBearer || token.ExpiresAt.IsZero() {
+			return
+		}
+		if entry.Tokens == nil {
+			entry.Tokens = make(map[string]fileCacheToken)
+		}
+		entry.Tokens[key] = fileCacheToken{
+			Token:     token.Value,
+			ExpiresAt: token.ExpiresAt,
+			RefreshAt: token.refreshAt(time.Now()),
+		}
+	})
+}
+
+// read reads the cache file under a shared lock.
+func (fc *fileCache) read() (*fileCacheContent, error) {
+	unlock, err := filelock.RLock(fc.path + fileCacheLockSuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return fc.load()
+}
+
+// update updates the cache file under an exclusive lock. Expired tokens are
+// pruned.
+func (fc *fileCache) update(fn func(content *fileCacheContent)) error {
+	unlock, err := filelock.Lock(fc.path + fileCacheLockSuffix)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	content, err := fc.load()
+	if err != nil {
+		// discard corrupted cache file
+		content = &fileCacheContent{}
+	}
+	if content.Registries == nil {
+		content.Registries = make(map[string]*fileCacheEntry)
+	}
+	fn(content)
+
+	now := time.Now()
+	for _, entry := range content.Registries {
+		for key, token := range entry.Tokens {
+			if !now.Before(token.ExpiresAt) {
+				delete(entry.Tokens, key)
+			}
+		}
+	}
+	return fc.save(content)
+}
+
+// load loads the cache file. An empty content is returned if the cache file
+// does not exist.
+func (fc *fileCache) load() (*fileCacheContent, error) {
+	data, err := os.ReadFile(fc.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &fileCacheContent{}, nil
+		}
+		return nil, err
+	}
+	var content fileCacheContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to decode auth cache file %s: %w", fc.path, err)
+	}
+	return &content, nil
+}
+
+// save atomically writes the content to the cache file.
+func (fc *fileCache) save(content *fileCacheContent) (saveErr error) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(fc.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	fp, err := os.CreateTemp(dir, filepath.Base(fc.path)+".tmp_*")
+	if err != nil {
+		return err
+	}
+	ingest := fp.Name()
+	defer func() {
+		if saveErr != nil {
+			os.Remove(ingest)
+		}
+	}()
+	if err := fp.Chmod(0600); err != nil {
+		fp.Close()
+		return err
+	}
+	if _, err := fp.Write(data); err != nil {
+		fp.Close()
+		return err
+	}
+	if err := fp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(ingest, fc.path)
+}
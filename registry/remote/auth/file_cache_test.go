@@ -0,0 +1,194 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"oras.land/oras-go/v2/errdef"
+)
+
+func Test_fileCache_Shared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "auth.json")
+	ctx := context.Background()
+	registry := "localhost:5000"
+	key := "repository:test:pull"
+
+	// set a token by a cache instance
+	cache1 := NewFileCache(path).(ExpiringCache)
+	expiresAt := time.Now().Add(time.Hour)
+	got, err := cache1.SetToken(ctx, registry, SchemeBearer, key, func(context.Context) (Token, error) {
+		return Token{Value: "foo", ExpiresAt: expiresAt}, nil
+	})
+	if err != nil {
+		t.Fatalf("fileCache.SetToken() error = %v", err)
+	}
+	if want := "foo"; got != want {
+		t.Errorf("fileCache.SetToken() = %v, want %v", got, want)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat cache file: %v", err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0600 {
+			t.Errorf("cache file permission = %v, want %v", perm, os.FileMode(0600))
+		}
+	}
+
+	// read the token by another cache instance
+	cache2 := NewFileCache(path).(ExpiringCache)
+	scheme, err := cache2.GetScheme(ctx, registry)
+	if err != nil {
+		t.Fatalf("fileCache.GetScheme() error = %v", err)
+	}
+	if scheme != SchemeBearer {
+		t.Errorf("fileCache.GetScheme() = %v, want %v", scheme, SchemeBearer)
+	}
+	got, err = cache2.GetToken(ctx, registry, SchemeBearer, key)
+	if err != nil {
+		t.Fatalf("fileCache.GetToken() error = %v", err)
+	}
+	if want := "foo"; got != want {
+		t.Errorf("fileCache.GetToken() = %v, want %v", got, want)
+	}
+	if _, err := cache2.GetToken(ctx, registry, SchemeBearer, "other key"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("fileCache.GetToken() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if _, err := cache2.GetToken(ctx, registry, SchemeBasic, key); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("fileCache.GetToken() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+
+	// evict the token by another cache instance
+	if err := cache2.EvictToken(ctx, registry, SchemeBearer, key); err != nil {
+		t.Fatalf("fileCache.EvictToken() error = %v", err)
+	}
+	if _, err := NewFileCache(path).GetToken(ctx, registry, SchemeBearer, key); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("fileCache.GetToken() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if err := cache2.Evict(ctx, registry); err != nil {
+		t.Fatalf("fileCache.Evict() error = %v", err)
+	}
+	if _, err := NewFileCache(path).GetScheme(ctx, registry); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("fileCache.GetScheme() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+}
+
+func Test_fileCache_Expiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	ctx := context.Background()
+	registry := "localhost:5000"
+
+	cache := NewFileCache(path)
+	tokens := map[string]Token{
+		"expired":   {Value: "expired", ExpiresAt: time.Now().Add(-time.Minute)},
+		"no expiry": {Value: "no expiry"},
+		"valid":     {Value: "valid", ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	for key, token := range tokens {
+		if _, err := cache.(ExpiringCache).SetToken(ctx, registry, SchemeBearer, key, func(context.Context) (Token, error) {
+			return token, nil
+		}); err != nil {
+			t.Fatalf("fileCache.SetToken() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read cache file: %v", err)
+	}
+	for _, value := range []string{"expired", "no expiry"} {
+		if strings.Contains(string(data), `"`+value+`"`) {
+			t.Errorf("token %q is persisted", value)
+		}
+	}
+
+	other := NewFileCache(path)
+	for key, want := range map[string]bool{
+		"expired":   false,
+		"no expiry": false,
+		"valid":     true,
+	} {
+		got, err := other.GetToken(ctx, registry, SchemeBearer, key)
+		if want {
+			if err != nil || got != key {
+				t.Errorf("fileCache.GetToken(%q) = %v, %v, want %v", key, got, err, key)
+			}
+		} else if !errors.Is(err, errdef.ErrNotFound) {
+			t.Errorf("fileCache.GetToken(%q) error = %v, wantErr %v", key, err, errdef.ErrNotFound)
+		}
+	}
+}
+
+func Test_fileCache_Basic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	ctx := context.Background()
+	registry := "localhost:5000"
+
+	cache := NewFileCache(path)
+	if _, err := cache.Set(ctx, registry, SchemeBasic, "", func(context.Context) (string, error) {
+		return "secret", nil
+	}); err != nil {
+		t.Fatalf("fileCache.Set() error = %v", err)
+	}
+	if got, err := cache.GetToken(ctx, registry, SchemeBasic, ""); err != nil || got != "secret" {
+		t.Errorf("fileCache.GetToken() = %v, %v, want %v", got, err, "secret")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read cache file: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Error("basic auth token is persisted")
+	}
+	other := NewFileCache(path)
+	if scheme, err := other.GetScheme(ctx, registry); err != nil || scheme != SchemeBasic {
+		t.Errorf("fileCache.GetScheme() = %v, %v, want %v", scheme, err, SchemeBasic)
+	}
+	if _, err := other.GetToken(ctx, registry, SchemeBasic, ""); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("fileCache.GetToken() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+}
+
+func Test_fileCache_Corrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatalf("failed to write cache file: %v", err)
+	}
+	ctx := context.Background()
+	registry := "localhost:5000"
+
+	cache := NewFileCache(path)
+	if _, err := cache.GetScheme(ctx, registry); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("fileCache.GetScheme() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+	if _, err := cache.Set(ctx, registry, SchemeBearer, "key", func(context.Context) (string, error) {
+		return newTestJWT(t, time.Now().Add(time.Hour)), nil
+	}); err != nil {
+		t.Fatalf("fileCache.Set() error = %v", err)
+	}
+	if _, err := NewFileCache(path).GetToken(ctx, registry, SchemeBearer, "key"); err != nil {
+		t.Errorf("fileCache.GetToken() error = %v", err)
+	}
+}
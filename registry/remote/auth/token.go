@@ -0,0 +1,82 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"time"
+
+	"oras.land/oras-go/v2/registry/remote/internal/jwtutil"
+)
+
+// maxTokenRefreshWindow is the maximum duration before the expiry of a token
+// in which the token is refreshed.
+const maxTokenRefreshWindow = 30 * time.Second
+
+// Token is an auth-token with its expiry.
+type Token struct {
+	// Value is the auth-token.
+	Value string
+
+	// ExpiresAt is the time when the token expires.
+	// The zero value indicates that the expiry is unknown, in which case the
+	// token is considered as valid until it is evicted or rejected by the
+	// remote server.
+	ExpiresAt time.Time
+}
+
+// refreshAt returns the time after which the token should be refreshed,
+// which is a short window before the token expires. The window is a tenth of
+// the remaining lifetime of the token at the given time, capped at 30
+// seconds. The zero time is returned if the expiry is unknown.
+func (t Token) refreshAt(now time.Time) time.Time {
+	if t.ExpiresAt.IsZero() {
+		return time.Time{}
+	}
+	lifetime := t.ExpiresAt.Sub(now)
+	if lifetime <= 0 {
+		return t.ExpiresAt
+	}
+	return t.ExpiresAt.Add(-min(lifetime/10, maxTokenRefreshWindow))
+}
+
+// tokenExpiry returns the expiry of a token issued by the authorization
+// service based on the "expires_in" and "issued_at" response fields. If
+// "expires_in" is absent, the "exp" claim is used if the token is a JWT.
+// The zero time is returned if the expiry cannot be determined.
+//
+// To tolerate clock skew, "issued_at" is taken into account only if it is
+// before the time the response is received.
+//
+// Reference: https://distribution.github.io/distribution/spec/auth/token/#token-response-fields
+func tokenExpiry(token string, expiresIn int64, issuedAt string, received time.Time) time.Time {
+	if expiresIn <= 0 {
+		return jwtExpiry(token)
+	}
+	issued := received
+	if issuedAt != "" {
+		if t, err := time.Parse(time.RFC3339, issuedAt); err == nil && t.Before(received) {
+			issued = t
+		}
+	}
+	return issued.Add(time.Duration(expiresIn) * time.Second)
+}
+
+// jwtExpiry returns the time specified by the "exp" claim if the token is a
+// JWT. The signature of the token is not verified.
+// The zero time is returned if the token is not a JWT or has no "exp" claim.
+func jwtExpiry(token string) time.Time {
+	return jwtutil.Expiry(token)
+}
@@ -0,0 +1,160 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// newTestJWT creates an unsigned JWT with the given expiry.
+func newTestJWT(t *testing.T, exp time.Time) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	claims, err := json.Marshal(map[string]int64{"exp": exp.Unix()})
+	if err != nil {
+		t.Fatalf("failed to marshal claims: %v", err)
+	}
+	return header + "." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+}
+
+func Test_jwtExpiry(t *testing.T) {
+	exp := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	tests := []struct {
+		name  string
+		token string
+		want  time.Time
+	}{
+		{
+			name:  "jwt",
+			token: newTestJWT(t, exp),
+			want:  exp,
+		},
+		{
+			name:  "opaque token",
+			token: "foo",
+		},
+		{
+			name:  "invalid payload",
+			token: "a.!!!.c",
+		},
+		{
+			name:  "no exp claim",
+			token: "e30.e30.sig",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jwtExpiry(tt.token); !got.Equal(tt.want) {
+				t.Errorf("jwtExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_tokenExpiry(t *testing.T) {
+	received := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	jwtExp := time.Unix(received.Add(time.Hour).Unix(), 0)
+	tests := []struct {
+		name      string
+		token     string
+		expiresIn int64
+		issuedAt  string
+		want      time.Time
+	}{
+		{
+			name:      "expires_in",
+			token:     "foo",
+			expiresIn: 300,
+			want:      received.Add(300 * time.Second),
+		},
+		{
+			name:      "expires_in with issued_at in the past",
+			token:     "foo",
+			expiresIn: 300,
+			issuedAt:  "2024-01-01T11:59:00Z",
+			want:      received.Add(240 * time.Second),
+		},
+		{
+			name:      "expires_in with issued_at in the future",
+			token:     "foo",
+			expiresIn: 300,
+			issuedAt:  "2024-01-01T12:10:00Z",
+			want:      received.Add(300 * time.Second),
+		},
+		{
+			name:      "expires_in with invalid issued_at",
+			token:     "foo",
+			expiresIn: 300,
+			issuedAt:  "yesterday",
+			want:      received.Add(300 * time.Second),
+		},
+		{
+			name:  "jwt exp",
+			token: newTestJWT(t, jwtExp),
+			want:  jwtExp,
+		},
+		{
+			name:  "unknown",
+			token: "foo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenExpiry(tt.token, tt.expiresIn, tt.issuedAt, received); !got.Equal(tt.want) {
+				t.Errorf("tokenExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToken_refreshAt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		token Token
+		want  time.Time
+	}{
+		{
+			name:  "unknown expiry",
+			token: Token{},
+		},
+		{
+			name:  "short lifetime",
+			token: Token{ExpiresAt: now.Add(100 * time.Second)},
+			want:  now.Add(90 * time.Second),
+		},
+		{
+			name:  "long lifetime",
+			token: Token{ExpiresAt: now.Add(time.Hour)},
+			want:  now.Add(time.Hour - 30*time.Second),
+		},
+		{
+			name:  "expired",
+			token: Token{ExpiresAt: now.Add(-time.Second)},
+			want:  now.Add(-time.Second),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.token.refreshAt(now); !got.Equal(tt.want) {
+				t.Errorf("Token.refreshAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"strings"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials/internal/config"
+)
+
+// containersDockerHubRegistry is the key of Docker Hub in the containers
+// auth.json file.
+const containersDockerHubRegistry = "docker.io"
+
+// containersDockerHubAliases are the other hosts of Docker Hub, which may key
+// the entries of Docker Hub in auth.json files shared with Docker, such as the
+// legacy key "https://index.docker.io/v1/".
+var containersDockerHubAliases = []string{"index.docker.io", "registry-1.docker.io"}
+
+// RepositoryStore is a Store that resolves credentials per repository in
+// addition to per server address.
+type RepositoryStore interface {
+	Store
+	// GetForRepository retrieves credentials from the store for the given
+	// repository of the given registry (i.e. host:port).
+	GetForRepository(ctx context.Context, registry, repository string) (auth.Credential, error)
+}
+
+// ContainersAuthStore implements a credentials store using the auth.json file
+// of containers tools, such as Podman, Buildah and Skopeo, to keep the
+// credentials in plain-text.
+//
+// In addition to registry hosts, entries of the auth.json file can be keyed by
+// "registry/namespace" or "registry/namespace/repository", which are used for
+// the matching repositories. The longest matching key wins.
+//
+// Reference: https://github.com/containers/image/blob/main/docs/containers-auth.json.5.md
+type ContainersAuthStore struct {
+	// DisablePut disables putting credentials in plaintext.
+	// If DisablePut is set to true, Put() will return ErrPlaintextPutDisabled.
+	DisablePut bool
+
+	config *config.Config
+}
+
+// NewContainersAuthStore creates a new credentials store based on the
+// containers auth.json file at the given path.
+//
+// Reference: https://github.com/containers/image/blob/main/docs/containers-auth.json.5.md
+func NewContainersAuthStore(authFilePath string) (*ContainersAuthStore, error) {
+	cfg, err := config.Load(authFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return &ContainersAuthStore{config: cfg}, nil
+}
+
+// Get retrieves credentials from the store for the given key, which is either
+// a registry or a repository-scoped key in the form of
+// "registry/namespace/repository". Only the exact key is matched.
+func (cs *ContainersAuthStore) Get(_ context.Context, key string) (auth.Credential, error) {
+	cred, _, err := cs.lookup(normalizeContainersKey(key))
+	return cred, err
+}
+
+// GetForRepository retrieves credentials from the store for the given
+// repository of the given registry. The credential of the longest key
+// matching the repository is returned, falling back to the credential of the
+// registry. For example, for the repository "ns/app" of the registry
+// "registry.example.com", the keys "registry.example.com/ns/app",
+// "registry.example.com/ns" and "registry.example.com" are looked up in order.
+func (cs *ContainersAuthStore) GetForRepository(_ context.Context, registry, repository string) (auth.Credential, error) {
+	key := normalizeContainersKey(registry)
+	if repository != "" {
+		key += "/" + repository
+	}
+	for {
+		cred, found, err := cs.lookup(key)
+		if err != nil {
+			return auth.EmptyCredential, err
+		}
+		if found {
+			return cred, nil
+		}
+		index := strings.LastIndex(key, "/")
+		if index == -1 {
+			return auth.EmptyCredential, nil
+		}
+		key = key[:index]
+	}
+}
+
+// lookup looks up the credential of the normalized key. Entries of Docker Hub
+// keyed by the other hosts of Docker Hub, such as the legacy key
+// "https://index.docker.io/v1/", are matched as well, as the docker config
+// store does.
+func (cs *ContainersAuthStore) lookup(key string) (auth.Credential, bool, error) {
+	cred, found, err := cs.config.LookupCredential(key)
+	if err != nil || found {
+		return cred, found, err
+	}
+	host, path, hasPath := strings.Cut(key, "/")
+	if host != containersDockerHubRegistry {
+		return auth.EmptyCredential, false, nil
+	}
+	for _, alias := range containersDockerHubAliases {
+		if hasPath {
+			alias += "/" + path
+		}
+		if cred, found, err := cs.config.LookupCredential(alias); err != nil || found {
+			return cred, found, err
+		}
+	}
+	return auth.EmptyCredential, false, nil
+}
+
+// Put saves credentials into the store for the given key, which is either a
+// registry or a repository-scoped key in the form of
+// "registry/namespace/repository".
+// Returns ErrPlaintextPutDisabled if cs.DisablePut is set to true.
+func (cs *ContainersAuthStore) Put(_ context.Context, key string, cred auth.Credential) error {
+	if cs.DisablePut {
+		return ErrPlaintextPutDisabled
+	}
+	if err := validateCredentialFormat(cred); err != nil {
+		return err
+	}
+	return cs.config.PutCredential(normalizeContainersKey(key), cred)
+}
+
+// Delete removes credentials from the store for the given key.
+func (cs *ContainersAuthStore) Delete(_ context.Context, key string) error {
+	return cs.config.DeleteCredential(normalizeContainersKey(key))
+}
+
+// normalizeContainersKey normalizes the key of the auth.json file by removing
+// the scheme, and mapping the hosts of Docker Hub to "docker.io".
+func normalizeContainersKey(key string) string {
+	key = strings.TrimPrefix(key, "http://")
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimSuffix(key, "/")
+	host, path, hasPath := strings.Cut(key, "/")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		host = containersDockerHubRegistry
+		if path == "v1" {
+			// legacy key "https://index.docker.io/v1/"
+			return host
+		}
+	}
+	if hasPath {
+		return host + "/" + path
+	}
+	return host
+}
+
+// RepositoryCredential returns a RepositoryCredentialFunc resolving credentials
+// for the given repository of the given registry (i.e. host:port) from the
+// store. It can be used by auth.Client via auth.RepositoryCredential().
+// If the store is a RepositoryStore, the credentials are resolved per
+// repository. Otherwise, the credentials of the registry are resolved as
+// Credential() does.
+func RepositoryCredential(store Store) auth.RepositoryCredentialFunc {
+	return func(ctx context.Context, hostport string, repository string) (auth.Credential, error) {
+		if hostport == "" {
+			return auth.EmptyCredential, nil
+		}
+		if rs, ok := store.(RepositoryStore); ok {
+			return rs.GetForRepository(ctx, hostport, repository)
+		}
+		return Credential(store)(ctx, hostport)
+	}
+}
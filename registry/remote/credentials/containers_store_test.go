@@ -0,0 +1,259 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+const testContainersAuthJSON = `{
+	"auths": {
+		"registry.example.com": {"auth": "cmVnaXN0cnk6cmVnaXN0cnlfcGFzcw=="},
+		"registry.example.com/team": {"auth": "dGVhbTp0ZWFtX3Bhc3M="},
+		"registry.example.com/team/app": {"auth": "YXBwOmFwcF9wYXNz"},
+		"https://legacy.example.com": {"auth": "bGVnYWN5OmxlZ2FjeV9wYXNz"},
+		"docker.io/library": {"auth": "bGlicmFyeTpsaWJyYXJ5X3Bhc3M="}
+	}
+}`
+
+func newTestContainersAuthStore(t *testing.T) (*ContainersAuthStore, string) {
+	t.Helper()
+	authFilePath := filepath.Join(t.TempDir(), "auth.json")
+	if err := os.WriteFile(authFilePath, []byte(testContainersAuthJSON), 0600); err != nil {
+		t.Fatalf("failed to write auth file: %v", err)
+	}
+	cs, err := NewContainersAuthStore(authFilePath)
+	if err != nil {
+		t.Fatalf("NewContainersAuthStore() error = %v", err)
+	}
+	return cs, authFilePath
+}
+
+func TestContainersAuthStore_GetForRepository(t *testing.T) {
+	cs, _ := newTestContainersAuthStore(t)
+	tests := []struct {
+		registry   string
+		repository string
+		want       auth.Credential
+	}{
+		{"registry.example.com", "team/app", auth.Credential{Username: "app", Password: "app_pass"}},
+		{"registry.example.com", "team/app/sub", auth.Credential{Username: "app", Password: "app_pass"}},
+		{"registry.example.com", "team/app2", auth.Credential{Username: "team", Password: "team_pass"}},
+		{"registry.example.com", "team", auth.Credential{Username: "team", Password: "team_pass"}},
+		{"registry.example.com", "team2/app", auth.Credential{Username: "registry", Password: "registry_pass"}},
+		{"registry.example.com", "", auth.Credential{Username: "registry", Password: "registry_pass"}},
+		{"legacy.example.com", "app", auth.Credential{Username: "legacy", Password: "legacy_pass"}},
+		{"registry-1.docker.io", "library/ubuntu", auth.Credential{Username: "library", Password: "library_pass"}},
+		{"registry-1.docker.io", "user/app", auth.EmptyCredential},
+		{"unknown.example.com", "team/app", auth.EmptyCredential},
+	}
+	for _, tt := range tests {
+		t.Run(tt.registry+"/"+tt.repository, func(t *testing.T) {
+			got, err := cs.GetForRepository(context.Background(), tt.registry, tt.repository)
+			if err != nil {
+				t.Fatalf("ContainersAuthStore.GetForRepository() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ContainersAuthStore.GetForRepository() = %v, want %v", got, tt.want)
+			}
+
+			got, err = RepositoryCredential(cs)(context.Background(), tt.registry, tt.repository)
+			if err != nil {
+				t.Fatalf("RepositoryCredential() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RepositoryCredential() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainersAuthStore_Get(t *testing.T) {
+	cs, _ := newTestContainersAuthStore(t)
+	tests := []struct {
+		key  string
+		want auth.Credential
+	}{
+		{"registry.example.com", auth.Credential{Username: "registry", Password: "registry_pass"}},
+		{"registry.example.com/team", auth.Credential{Username: "team", Password: "team_pass"}},
+		{"registry.example.com/team/app2", auth.EmptyCredential},
+		{"legacy.example.com", auth.Credential{Username: "legacy", Password: "legacy_pass"}},
+		{"https://index.docker.io/v1/", auth.EmptyCredential},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := cs.Get(context.Background(), tt.key)
+			if err != nil {
+				t.Fatalf("ContainersAuthStore.Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ContainersAuthStore.Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainersAuthStore_Put_Delete(t *testing.T) {
+	cs, authFilePath := newTestContainersAuthStore(t)
+	ctx := context.Background()
+	cred := auth.Credential{Username: "new", Password: "new_pass"}
+	if err := cs.Put(ctx, "registry.example.com/other/app", cred); err != nil {
+		t.Fatalf("ContainersAuthStore.Put() error = %v", err)
+	}
+	if err := cs.Put(ctx, "https://index.docker.io/v1/", cred); err != nil {
+		t.Fatalf("ContainersAuthStore.Put() error = %v", err)
+	}
+	if err := cs.Delete(ctx, "registry.example.com/team"); err != nil {
+		t.Fatalf("ContainersAuthStore.Delete() error = %v", err)
+	}
+
+	// verify the file content
+	data, err := os.ReadFile(authFilePath)
+	if err != nil {
+		t.Fatalf("failed to read auth file: %v", err)
+	}
+	var content struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &content); err != nil {
+		t.Fatalf("failed to decode auth file: %v", err)
+	}
+	var keys []string
+	for key := range content.Auths {
+		keys = append(keys, key)
+	}
+	wantKeys := map[string]bool{
+		"registry.example.com":           true,
+		"registry.example.com/team/app":  true,
+		"registry.example.com/other/app": true,
+		"https://legacy.example.com":     true,
+		"docker.io/library":              true,
+		"docker.io":                      true,
+	}
+	if len(keys) != len(wantKeys) {
+		t.Errorf("auth file keys = %v, want %v", keys, wantKeys)
+	}
+	for _, key := range keys {
+		if !wantKeys[key] {
+			t.Errorf("unexpected auth file key %q", key)
+		}
+	}
+
+	// verify with a new store
+	cs, err = NewContainersAuthStore(authFilePath)
+	if err != nil {
+		t.Fatalf("NewContainersAuthStore() error = %v", err)
+	}
+	got, err := cs.GetForRepository(ctx, "registry.example.com", "other/app")
+	if err != nil {
+		t.Fatalf("ContainersAuthStore.GetForRepository() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, cred) {
+		t.Errorf("ContainersAuthStore.GetForRepository() = %v, want %v", got, cred)
+	}
+	got, err = cs.GetForRepository(ctx, "registry.example.com", "team/other")
+	if err != nil {
+		t.Fatalf("ContainersAuthStore.GetForRepository() error = %v", err)
+	}
+	if want := (auth.Credential{Username: "registry", Password: "registry_pass"}); got != want {
+		t.Errorf("ContainersAuthStore.GetForRepository() = %v, want %v", got, want)
+	}
+	got, err = cs.GetForRepository(ctx, "registry-1.docker.io", "user/app")
+	if err != nil {
+		t.Fatalf("ContainersAuthStore.GetForRepository() error = %v", err)
+	}
+	if got != cred {
+		t.Errorf("ContainersAuthStore.GetForRepository() = %v, want %v", got, cred)
+	}
+}
+
+func TestContainersAuthStore_Put_Disabled(t *testing.T) {
+	cs, _ := newTestContainersAuthStore(t)
+	cs.DisablePut = true
+	err := cs.Put(context.Background(), "registry.example.com/team", auth.Credential{Username: "u", Password: "p"})
+	if !errors.Is(err, ErrPlaintextPutDisabled) {
+		t.Errorf("ContainersAuthStore.Put() error = %v, wantErr %v", err, ErrPlaintextPutDisabled)
+	}
+}
+
+func TestRepositoryCredential_Store(t *testing.T) {
+	ms := NewMemoryStore()
+	cred := auth.Credential{Username: "username", Password: "password"}
+	if err := ms.Put(context.Background(), "registry.example.com", cred); err != nil {
+		t.Fatalf("memoryStore.Put() error = %v", err)
+	}
+	got, err := RepositoryCredential(ms)(context.Background(), "registry.example.com", "team/app")
+	if err != nil {
+		t.Fatalf("RepositoryCredential() error = %v", err)
+	}
+	if got != cred {
+		t.Errorf("RepositoryCredential() = %v, want %v", got, cred)
+	}
+}
+
+func TestContainersAuthStore_GetForRepository_DockerHubLegacyKey(t *testing.T) {
+	authFilePath := filepath.Join(t.TempDir(), "auth.json")
+	authJSON := `{
+	"auths": {
+		"https://index.docker.io/v1/": {"auth": "bGVnYWN5OmxlZ2FjeV9wYXNz"},
+		"docker.io/library": {"auth": "bGlicmFyeTpsaWJyYXJ5X3Bhc3M="}
+	}
+}`
+	if err := os.WriteFile(authFilePath, []byte(authJSON), 0600); err != nil {
+		t.Fatalf("failed to write auth file: %v", err)
+	}
+	cs, err := NewContainersAuthStore(authFilePath)
+	if err != nil {
+		t.Fatalf("NewContainersAuthStore() error = %v", err)
+	}
+	legacy := auth.Credential{Username: "legacy", Password: "legacy_pass"}
+	tests := []struct {
+		registry   string
+		repository string
+		want       auth.Credential
+	}{
+		{"docker.io", "user/app", legacy},
+		{"registry-1.docker.io", "user/app", legacy},
+		{"docker.io", "", legacy},
+		{"docker.io", "library/ubuntu", auth.Credential{Username: "library", Password: "library_pass"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.registry+"/"+tt.repository, func(t *testing.T) {
+			got, err := cs.GetForRepository(context.Background(), tt.registry, tt.repository)
+			if err != nil {
+				t.Fatalf("ContainersAuthStore.GetForRepository() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ContainersAuthStore.GetForRepository() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	got, err := cs.Get(context.Background(), "docker.io")
+	if err != nil {
+		t.Fatalf("ContainersAuthStore.Get() error = %v", err)
+	}
+	if got != legacy {
+		t.Errorf("ContainersAuthStore.Get() = %v, want %v", got, legacy)
+	}
+}
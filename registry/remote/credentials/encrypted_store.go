@@ -0,0 +1,532 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/internal/fs/filelock"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials/internal/config"
+	"oras.land/oras-go/v2/registry/remote/credentials/internal/ioutil"
+)
+
+const (
+	// encryptedStoreVersion is the version of the encrypted store file format.
+	encryptedStoreVersion = 1
+
+	// encryptedStoreKDF is the key derivation function of the encrypted store.
+	encryptedStoreKDF = "pbkdf2-hmac-sha256"
+
+	// encryptedStoreSaltSize is the size of the salt of the key derivation.
+	encryptedStoreSaltSize = 16
+
+	// encryptedStoreKeySize is the size of the derived AES-256 key.
+	encryptedStoreKeySize = 32
+
+	// encryptedStoreKeyCheckData is the additional data authenticating the key
+	// check value, which is used to detect wrong keys.
+	encryptedStoreKeyCheckData = "oras-credentials-key-check"
+
+	// encryptedStoreLockSuffix is the suffix of the lock file guarding the
+	// updates of the encrypted store file among processes.
+	encryptedStoreLockSuffix = ".lock"
+)
+
+// encryptedStoreIterations is the number of iterations of the key derivation
+// for new files.
+var encryptedStoreIterations = 600000
+
+// maxEncryptedStoreIterations is the maximum number of iterations of the key
+// derivation accepted from store files, which is 10 times the default, so that
+// a tampered or corrupted file cannot stall the key derivation.
+const maxEncryptedStoreIterations = 6000000
+
+var (
+	// ErrMissingEncryptionKey is returned by NewEncryptedFileStore() when no
+	// encryption key is provided.
+	ErrMissingEncryptionKey = errors.New("missing encryption key")
+	// ErrInvalidEncryptionKey is returned when the encryption key cannot
+	// decrypt the encrypted store file.
+	ErrInvalidEncryptionKey = errors.New("invalid encryption key")
+	// ErrCorruptedEncryptedStore is returned when the encrypted store file
+	// is corrupted, such as having nonces of invalid sizes.
+	ErrCorruptedEncryptedStore = errors.New("corrupted encrypted store")
+)
+
+// encryptedStoreContent is the content of the encrypted store file.
+type encryptedStoreContent struct {
+	// Version is the version of the file format.
+	Version int `json:"version"`
+	// KDF is the parameters of the key derivation.
+	KDF encryptedStoreKDFParams `json:"kdf"`
+	// KeyCheck is an encrypted empty value used to verify the key.
+	KeyCheck encryptedValue `json:"keyCheck"`
+	// Auths maps server addresses to the encrypted credentials.
+	Auths map[string]encryptedValue `json:"auths"`
+}
+
+// encryptedStoreKDFParams is the parameters of the key derivation.
+type encryptedStoreKDFParams struct {
+	// Name is the name of the key derivation function.
+	Name string `json:"name"`
+	// Salt is the random salt.
+	Salt []byte `json:"salt"`
+	// Iterations is the number of iterations.
+	Iterations int `json:"iterations"`
+}
+
+// encryptedValue is a value encrypted by AES-GCM.
+type encryptedValue struct {
+	// Nonce is the random nonce.
+	Nonce []byte `json:"nonce"`
+	// Data is the encrypted data with the authentication tag.
+	Data []byte `json:"data"`
+}
+
+// EncryptedFileStoreOptions provides options for NewEncryptedFileStore.
+// The encryption key is read from the first non-empty source of Key, KeyFile
+// and KeyEnv.
+type EncryptedFileStoreOptions struct {
+	// Key is the encryption key.
+	Key []byte
+
+	// KeyFile is the path of the file containing the encryption key.
+	// Trailing newlines are trimmed.
+	KeyFile string
+
+	// KeyEnv is the name of the environment variable containing the
+	// encryption key.
+	KeyEnv string
+}
+
+// key reads the encryption key from the configured source.
+func (opts EncryptedFileStoreOptions) key() ([]byte, error) {
+	var key []byte
+	switch {
+	case len(opts.Key) > 0:
+		key = opts.Key
+	case opts.KeyFile != "":
+		data, err := os.ReadFile(opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+		key = bytes.TrimRight(data, "\r\n")
+	case opts.KeyEnv != "":
+		key = []byte(os.Getenv(opts.KeyEnv))
+	}
+	if len(key) == 0 {
+		return nil, ErrMissingEncryptionKey
+	}
+	return key, nil
+}
+
+// EncryptedFileStore implements a credentials store keeping the credentials
+// encrypted at rest in a file, for hosts without native keychains.
+//
+// The credentials are encrypted by AES-256-GCM with a key derived from the
+// encryption key by PBKDF2-HMAC-SHA256 with a random salt. The file is
+// versioned and only accessible by the current user (permission 0600).
+// The encryption key can be changed by Rotate().
+//
+// Updates are guarded among processes by an advisory lock of the file with
+// the suffix ".lock" next to the store file, and are applied to the latest
+// content of the store file, so that concurrent writers do not lose entries.
+// Once the key is rotated by another process, updates fail with
+// ErrInvalidEncryptionKey.
+type EncryptedFileStore struct {
+	path string
+	key  []byte
+
+	// lock protects content and aead.
+	lock    sync.RWMutex
+	content *encryptedStoreContent
+	aead    cipher.AEAD
+}
+
+// NewEncryptedFileStore creates a new encrypted credentials store based on the
+// file at the given path, which is created on the first Put if not exists.
+// Returns ErrInvalidEncryptionKey if the key does not match the existing file.
+func NewEncryptedFileStore(path string, opts EncryptedFileStoreOptions) (*EncryptedFileStore, error) {
+	key, err := opts.key()
+	if err != nil {
+		return nil, err
+	}
+
+	es := &EncryptedFileStore{
+		path: path,
+		key:  key,
+	}
+	content, err := readEncryptedStoreFile(path)
+	switch {
+	case err == nil:
+		aead, err := newEncryptedStoreAEAD(key, content.KDF)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkEncryptionKey(aead, content, path); err != nil {
+			return nil, err
+		}
+		es.content = content
+		es.aead = aead
+	case errors.Is(err, os.ErrNotExist):
+		es.content, es.aead, err = newEncryptedStoreContent(key)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+	return es, nil
+}
+
+// Get retrieves credentials from the store for the given server address.
+func (es *EncryptedFileStore) Get(_ context.Context, serverAddress string) (auth.Credential, error) {
+	es.lock.RLock()
+	defer es.lock.RUnlock()
+
+	value, ok := es.content.Auths[serverAddress]
+	if !ok {
+		return auth.EmptyCredential, nil
+	}
+	plaintext, err := decrypt(es.aead, value, []byte(serverAddress))
+	if err != nil {
+		return auth.EmptyCredential, fmt.Errorf("failed to decrypt credentials for %s: %w", serverAddress, err)
+	}
+	var authCfg config.AuthConfig
+	if err := json.Unmarshal(plaintext, &authCfg); err != nil {
+		return auth.EmptyCredential, fmt.Errorf("failed to unmarshal credentials for %s: %w: %v", serverAddress, config.ErrInvalidConfigFormat, err)
+	}
+	return authCfg.Credential()
+}
+
+// Put saves credentials into the store for the given server address.
+func (es *EncryptedFileStore) Put(_ context.Context, serverAddress string, cred auth.Credential) error {
+	if err := validateCredentialFormat(cred); err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(config.NewAuthConfig(cred))
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	unlock, err := es.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	value, err := encrypt(es.aead, plaintext, []byte(serverAddress))
+	if err != nil {
+		return err
+	}
+	old, existed := es.content.Auths[serverAddress]
+	es.content.Auths[serverAddress] = value
+	if err := es.save(es.content); err != nil {
+		// roll back
+		if existed {
+			es.content.Auths[serverAddress] = old
+		} else {
+			delete(es.content.Auths, serverAddress)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes credentials from the store for the given server address.
+func (es *EncryptedFileStore) Delete(_ context.Context, serverAddress string) error {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	unlock, err := es.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	old, ok := es.content.Auths[serverAddress]
+	if !ok {
+		// no ops
+		return nil
+	}
+	delete(es.content.Auths, serverAddress)
+	if err := es.save(es.content); err != nil {
+		// roll back
+		es.content.Auths[serverAddress] = old
+		return err
+	}
+	return nil
+}
+
+// Rotate re-encrypts all the credentials with the new encryption key, using a
+// new salt. The file is updated atomically, and the old key can no longer be
+// used once Rotate returns successfully.
+func (es *EncryptedFileStore) Rotate(_ context.Context, newKey []byte) error {
+	if len(newKey) == 0 {
+		return ErrMissingEncryptionKey
+	}
+
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	unlock, err := es.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	content, aead, err := newEncryptedStoreContent(newKey)
+	if err != nil {
+		return err
+	}
+	for serverAddress, value := range es.content.Auths {
+		plaintext, err := decrypt(es.aead, value, []byte(serverAddress))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt credentials for %s: %w", serverAddress, err)
+		}
+		if content.Auths[serverAddress], err = encrypt(aead, plaintext, []byte(serverAddress)); err != nil {
+			return err
+		}
+	}
+	if err := es.save(content); err != nil {
+		return err
+	}
+	es.key = newKey
+	es.content = content
+	es.aead = aead
+	return nil
+}
+
+// lockFile acquires the exclusive lock of the store file among processes, and
+// reloads the content of the store file so that the updates are applied to
+// the latest content.
+// The caller must hold es.lock.
+func (es *EncryptedFileStore) lockFile() (func() error, error) {
+	unlock, err := filelock.Lock(es.path + encryptedStoreLockSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock encrypted store file at %s: %w", es.path, err)
+	}
+	if err := es.reload(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// reload reloads the content of the store file if the file exists.
+// The caller must hold es.lock and the lock of the store file.
+func (es *EncryptedFileStore) reload() error {
+	content, err := readEncryptedStoreFile(es.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// not saved yet
+			return nil
+		}
+		return err
+	}
+	aead := es.aead
+	if !content.KDF.equal(es.content.KDF) {
+		// the key has been rotated by another process
+		if aead, err = newEncryptedStoreAEAD(es.key, content.KDF); err != nil {
+			return err
+		}
+	}
+	if err := checkEncryptionKey(aead, content, es.path); err != nil {
+		return err
+	}
+	es.content = content
+	es.aead = aead
+	return nil
+}
+
+// save atomically writes the content into the file.
+func (es *EncryptedFileStore) save(content *encryptedStoreContent) (returnErr error) {
+	data, err := json.MarshalIndent(content, "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encrypted store: %w", err)
+	}
+
+	dir := filepath.Dir(es.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to make directory %s: %w", dir, err)
+	}
+	ingest, err := ioutil.Ingest(dir, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to save encrypted store file: %w", err)
+	}
+	defer func() {
+		if returnErr != nil {
+			// clean up the ingest file in case of error
+			os.Remove(ingest)
+		}
+	}()
+	if err := os.Rename(ingest, es.path); err != nil {
+		return fmt.Errorf("failed to save encrypted store file: %w", err)
+	}
+	return nil
+}
+
+// readEncryptedStoreFile reads and decodes the encrypted store file at path.
+func readEncryptedStoreFile(path string) (*encryptedStoreContent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open encrypted store file at %s: %w", path, err)
+	}
+	var content encryptedStoreContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted store file at %s: %w: %v", path, config.ErrInvalidConfigFormat, err)
+	}
+	if content.Version != encryptedStoreVersion {
+		return nil, fmt.Errorf("encrypted store file at %s: %w: %d", path, errdef.ErrUnsupportedVersion, content.Version)
+	}
+	if content.Auths == nil {
+		content.Auths = make(map[string]encryptedValue)
+	}
+	return &content, nil
+}
+
+// checkEncryptionKey verifies that aead can decrypt the content by the key
+// check value.
+func checkEncryptionKey(aead cipher.AEAD, content *encryptedStoreContent, path string) error {
+	if _, err := decrypt(aead, content.KeyCheck, []byte(encryptedStoreKeyCheckData)); err != nil {
+		if errors.Is(err, ErrCorruptedEncryptedStore) {
+			return fmt.Errorf("encrypted store file at %s: %w", path, err)
+		}
+		return fmt.Errorf("encrypted store file at %s: %w", path, ErrInvalidEncryptionKey)
+	}
+	return nil
+}
+
+// newEncryptedStoreContent creates an empty content with a new random salt
+// for the given key.
+func newEncryptedStoreContent(key []byte) (*encryptedStoreContent, cipher.AEAD, error) {
+	salt := make([]byte, encryptedStoreSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	content := &encryptedStoreContent{
+		Version: encryptedStoreVersion,
+		KDF: encryptedStoreKDFParams{
+			Name:       encryptedStoreKDF,
+			Salt:       salt,
+			Iterations: encryptedStoreIterations,
+		},
+		Auths: make(map[string]encryptedValue),
+	}
+	aead, err := newEncryptedStoreAEAD(key, content.KDF)
+	if err != nil {
+		return nil, nil, err
+	}
+	if content.KeyCheck, err = encrypt(aead, nil, []byte(encryptedStoreKeyCheckData)); err != nil {
+		return nil, nil, err
+	}
+	return content, aead, nil
+}
+
+// newEncryptedStoreAEAD derives the AES-256 key from the encryption key and
+// returns the AES-GCM cipher.
+func newEncryptedStoreAEAD(key []byte, params encryptedStoreKDFParams) (cipher.AEAD, error) {
+	if params.Name != encryptedStoreKDF {
+		return nil, fmt.Errorf("%w: key derivation function %q", errdef.ErrUnsupported, params.Name)
+	}
+	if params.Iterations <= 0 || len(params.Salt) == 0 {
+		return nil, fmt.Errorf("%w: invalid key derivation parameters", config.ErrInvalidConfigFormat)
+	}
+	if params.Iterations > maxEncryptedStoreIterations {
+		return nil, fmt.Errorf("%w: key derivation iterations %d exceed the limit %d", config.ErrInvalidConfigFormat, params.Iterations, maxEncryptedStoreIterations)
+	}
+	block, err := aes.NewCipher(pbkdf2SHA256(key, params.Salt, params.Iterations, encryptedStoreKeySize))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt encrypts the plaintext with a random nonce.
+func encrypt(aead cipher.AEAD, plaintext, additionalData []byte) (encryptedValue, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return encryptedValue{}, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return encryptedValue{
+		Nonce: nonce,
+		Data:  aead.Seal(nil, nonce, plaintext, additionalData),
+	}, nil
+}
+
+// decrypt decrypts the value. The nonce is validated as AES-GCM panics on
+// nonces of invalid sizes.
+func decrypt(aead cipher.AEAD, value encryptedValue, additionalData []byte) ([]byte, error) {
+	if len(value.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce size %d", ErrCorruptedEncryptedStore, len(value.Nonce))
+	}
+	return aead.Open(nil, value.Nonce, value.Data, additionalData)
+}
+
+// equal reports whether the key derivation parameters are equal.
+func (params encryptedStoreKDFParams) equal(other encryptedStoreKDFParams) bool {
+	return params.Name == other.Name && bytes.Equal(params.Salt, other.Salt) && params.Iterations == other.Iterations
+}
+
+// pbkdf2SHA256 derives a key of the given length from the password by
+// PBKDF2 with HMAC-SHA256.
+// Reference: https://www.rfc-editor.org/rfc/rfc8018#section-5.2
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	derived := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		// U_1 = PRF(password, salt || INT(block))
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:])
+		u = prf.Sum(u[:0])
+		t := make([]byte, hashLen)
+		copy(t, u)
+
+		// U_n = PRF(password, U_{n-1})
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+		derived = append(derived, t...)
+	}
+	return derived[:keyLen]
+}
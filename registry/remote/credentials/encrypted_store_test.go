@@ -0,0 +1,382 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials/internal/config"
+)
+
+func init() {
+	// speed up tests
+	encryptedStoreIterations = 1000
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	key := []byte("secret key")
+	es, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: key})
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("store file should not be created before Put: %v", err)
+	}
+
+	basicCred := auth.Credential{Username: "username", Password: "password"}
+	tokenCred := auth.Credential{RefreshToken: "identity_token"}
+	if err := es.Put(ctx, "registry.example.com", basicCred); err != nil {
+		t.Fatalf("EncryptedFileStore.Put() error = %v", err)
+	}
+	if err := es.Put(ctx, "token.example.com", tokenCred); err != nil {
+		t.Fatalf("EncryptedFileStore.Put() error = %v", err)
+	}
+
+	// verify the file content
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat store file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("store file permission = %v, want %v", perm, os.FileMode(0600))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read store file: %v", err)
+	}
+	for _, secret := range []string{"username", "password", "identity_token"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("store file contains plaintext %q", secret)
+		}
+	}
+	var content encryptedStoreContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		t.Fatalf("failed to decode store file: %v", err)
+	}
+	if content.Version != encryptedStoreVersion {
+		t.Errorf("store file version = %v, want %v", content.Version, encryptedStoreVersion)
+	}
+
+	// verify with a new store
+	es, err = NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: key})
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	for serverAddress, want := range map[string]auth.Credential{
+		"registry.example.com": basicCred,
+		"token.example.com":    tokenCred,
+		"unknown.example.com":  auth.EmptyCredential,
+	} {
+		got, err := es.Get(ctx, serverAddress)
+		if err != nil {
+			t.Fatalf("EncryptedFileStore.Get(%s) error = %v", serverAddress, err)
+		}
+		if got != want {
+			t.Errorf("EncryptedFileStore.Get(%s) = %v, want %v", serverAddress, got, want)
+		}
+	}
+
+	// delete
+	if err := es.Delete(ctx, "registry.example.com"); err != nil {
+		t.Fatalf("EncryptedFileStore.Delete() error = %v", err)
+	}
+	if err := es.Delete(ctx, "unknown.example.com"); err != nil {
+		t.Fatalf("EncryptedFileStore.Delete() error = %v", err)
+	}
+	es, err = NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: key})
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	got, err := es.Get(ctx, "registry.example.com")
+	if err != nil {
+		t.Fatalf("EncryptedFileStore.Get() error = %v", err)
+	}
+	if got != auth.EmptyCredential {
+		t.Errorf("EncryptedFileStore.Get() = %v, want %v", got, auth.EmptyCredential)
+	}
+}
+
+func TestEncryptedFileStore_Rotate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	oldKey := []byte("old key")
+	newKey := []byte("new key")
+	es, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: oldKey})
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	cred := auth.Credential{Username: "username", Password: "password"}
+	if err := es.Put(ctx, "registry.example.com", cred); err != nil {
+		t.Fatalf("EncryptedFileStore.Put() error = %v", err)
+	}
+	if err := es.Rotate(ctx, newKey); err != nil {
+		t.Fatalf("EncryptedFileStore.Rotate() error = %v", err)
+	}
+	if got, err := es.Get(ctx, "registry.example.com"); err != nil || got != cred {
+		t.Errorf("EncryptedFileStore.Get() = %v, %v, want %v, nil", got, err, cred)
+	}
+
+	if _, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: oldKey}); !errors.Is(err, ErrInvalidEncryptionKey) {
+		t.Errorf("NewEncryptedFileStore() error = %v, wantErr %v", err, ErrInvalidEncryptionKey)
+	}
+	es, err = NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: newKey})
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	if got, err := es.Get(ctx, "registry.example.com"); err != nil || got != cred {
+		t.Errorf("EncryptedFileStore.Get() = %v, %v, want %v, nil", got, err, cred)
+	}
+	if err := es.Rotate(ctx, nil); !errors.Is(err, ErrMissingEncryptionKey) {
+		t.Errorf("EncryptedFileStore.Rotate() error = %v, wantErr %v", err, ErrMissingEncryptionKey)
+	}
+}
+
+func TestEncryptedFileStore_KeySource(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials.json")
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte("file key\n"), 0600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+	es, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	cred := auth.Credential{Username: "username", Password: "password"}
+	if err := es.Put(ctx, "registry.example.com", cred); err != nil {
+		t.Fatalf("EncryptedFileStore.Put() error = %v", err)
+	}
+
+	// the same key from the environment variable
+	t.Setenv("TEST_ORAS_CREDENTIALS_KEY", "file key")
+	es, err = NewEncryptedFileStore(path, EncryptedFileStoreOptions{KeyEnv: "TEST_ORAS_CREDENTIALS_KEY"})
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	if got, err := es.Get(ctx, "registry.example.com"); err != nil || got != cred {
+		t.Errorf("EncryptedFileStore.Get() = %v, %v, want %v, nil", got, err, cred)
+	}
+
+	t.Setenv("TEST_ORAS_CREDENTIALS_KEY", "")
+	for _, opts := range []EncryptedFileStoreOptions{
+		{},
+		{KeyEnv: "TEST_ORAS_CREDENTIALS_KEY"},
+	} {
+		if _, err := NewEncryptedFileStore(path, opts); !errors.Is(err, ErrMissingEncryptionKey) {
+			t.Errorf("NewEncryptedFileStore() error = %v, wantErr %v", err, ErrMissingEncryptionKey)
+		}
+	}
+	if _, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{KeyFile: filepath.Join(dir, "missing")}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("NewEncryptedFileStore() error = %v, wantErr %v", err, os.ErrNotExist)
+	}
+}
+
+func TestEncryptedFileStore_InvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"invalid json":    `{`,
+		"unknown version": `{"version":2}`,
+		"unknown kdf":     `{"version":1,"kdf":{"name":"unknown","salt":"c2FsdA==","iterations":1}}`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".json")
+			if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+				t.Fatalf("failed to write store file: %v", err)
+			}
+			if _, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: []byte("key")}); err == nil {
+				t.Errorf("NewEncryptedFileStore() error = nil, wantErr true")
+			}
+		})
+	}
+
+	path := filepath.Join(dir, "iterations.json")
+	content := fmt.Sprintf(`{"version":1,"kdf":{"name":%q,"salt":"c2FsdA==","iterations":2147483647}}`, encryptedStoreKDF)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write store file: %v", err)
+	}
+	if _, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: []byte("key")}); !errors.Is(err, config.ErrInvalidConfigFormat) {
+		t.Errorf("NewEncryptedFileStore() error = %v, wantErr %v", err, config.ErrInvalidConfigFormat)
+	}
+
+	path = filepath.Join(dir, "version.json")
+	if err := os.WriteFile(path, []byte(`{"version":2}`), 0600); err != nil {
+		t.Fatalf("failed to write store file: %v", err)
+	}
+	if _, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: []byte("key")}); !errors.Is(err, errdef.ErrUnsupportedVersion) {
+		t.Errorf("NewEncryptedFileStore() error = %v, wantErr %v", err, errdef.ErrUnsupportedVersion)
+	}
+}
+
+func Test_pbkdf2SHA256(t *testing.T) {
+	// test vectors from RFC 7914 section 11
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		want       string
+	}{
+		{
+			password:   "passwd",
+			salt:       "salt",
+			iterations: 1,
+			want:       "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783",
+		},
+		{
+			password:   "Password",
+			salt:       "NaCl",
+			iterations: 80000,
+			want:       "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			got := hex.EncodeToString(pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, 64))
+			if got != tt.want {
+				t.Errorf("pbkdf2SHA256() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptedFileStore_TruncatedNonce(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("secret key")
+	newStoreFile := func(t *testing.T, corrupt func(content *encryptedStoreContent)) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "credentials.json")
+		es, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: key})
+		if err != nil {
+			t.Fatalf("NewEncryptedFileStore() error = %v", err)
+		}
+		if err := es.Put(ctx, "registry.example.com", auth.Credential{Username: "username", Password: "password"}); err != nil {
+			t.Fatalf("EncryptedFileStore.Put() error = %v", err)
+		}
+		content, err := readEncryptedStoreFile(path)
+		if err != nil {
+			t.Fatalf("readEncryptedStoreFile() error = %v", err)
+		}
+		corrupt(content)
+		data, err := json.Marshal(content)
+		if err != nil {
+			t.Fatalf("failed to encode store file: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("failed to write store file: %v", err)
+		}
+		return path
+	}
+
+	t.Run("key check", func(t *testing.T) {
+		path := newStoreFile(t, func(content *encryptedStoreContent) {
+			content.KeyCheck.Nonce = content.KeyCheck.Nonce[:4]
+		})
+		if _, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: key}); !errors.Is(err, ErrCorruptedEncryptedStore) {
+			t.Errorf("NewEncryptedFileStore() error = %v, wantErr %v", err, ErrCorruptedEncryptedStore)
+		}
+	})
+
+	t.Run("credentials", func(t *testing.T) {
+		path := newStoreFile(t, func(content *encryptedStoreContent) {
+			value := content.Auths["registry.example.com"]
+			value.Nonce = value.Nonce[:4]
+			content.Auths["registry.example.com"] = value
+		})
+		es, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: key})
+		if err != nil {
+			t.Fatalf("NewEncryptedFileStore() error = %v", err)
+		}
+		if _, err := es.Get(ctx, "registry.example.com"); !errors.Is(err, ErrCorruptedEncryptedStore) {
+			t.Errorf("EncryptedFileStore.Get() error = %v, wantErr %v", err, ErrCorruptedEncryptedStore)
+		}
+		if err := es.Rotate(ctx, []byte("new key")); !errors.Is(err, ErrCorruptedEncryptedStore) {
+			t.Errorf("EncryptedFileStore.Rotate() error = %v, wantErr %v", err, ErrCorruptedEncryptedStore)
+		}
+	})
+}
+
+func TestEncryptedFileStore_ConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	key := []byte("secret key")
+	stores := make([]*EncryptedFileStore, 2)
+	for i := range stores {
+		es, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: key})
+		if err != nil {
+			t.Fatalf("NewEncryptedFileStore() error = %v", err)
+		}
+		stores[i] = es
+	}
+
+	// each store puts its own entries, as if in separate processes
+	const count = 5
+	errCh := make(chan error, len(stores))
+	for i, es := range stores {
+		go func(i int, es *EncryptedFileStore) {
+			for j := 0; j < count; j++ {
+				serverAddress := fmt.Sprintf("registry%d-%d.example.com", i, j)
+				if err := es.Put(ctx, serverAddress, auth.Credential{Username: serverAddress, Password: "password"}); err != nil {
+					errCh <- err
+					return
+				}
+			}
+			errCh <- nil
+		}(i, es)
+	}
+	for range stores {
+		if err := <-errCh; err != nil {
+			t.Fatalf("EncryptedFileStore.Put() error = %v", err)
+		}
+	}
+
+	es, err := NewEncryptedFileStore(path, EncryptedFileStoreOptions{Key: key})
+	if err != nil {
+		t.Fatalf("NewEncryptedFileStore() error = %v", err)
+	}
+	for i := range stores {
+		for j := 0; j < count; j++ {
+			serverAddress := fmt.Sprintf("registry%d-%d.example.com", i, j)
+			want := auth.Credential{Username: serverAddress, Password: "password"}
+			if got, err := es.Get(ctx, serverAddress); err != nil || got != want {
+				t.Errorf("EncryptedFileStore.Get(%s) = %v, %v, want %v, nil", serverAddress, got, err, want)
+			}
+		}
+	}
+
+	// updates fail once the key is rotated by another writer
+	if err := stores[0].Rotate(ctx, []byte("new key")); err != nil {
+		t.Fatalf("EncryptedFileStore.Rotate() error = %v", err)
+	}
+	if err := stores[1].Put(ctx, "registry.example.com", auth.Credential{Username: "username", Password: "password"}); !errors.Is(err, ErrInvalidEncryptionKey) {
+		t.Errorf("EncryptedFileStore.Put() error = %v, wantErr %v", err, ErrInvalidEncryptionKey)
+	}
+	if err := stores[0].Delete(ctx, "registry0-0.example.com"); err != nil {
+		t.Errorf("EncryptedFileStore.Delete() error = %v", err)
+	}
+}
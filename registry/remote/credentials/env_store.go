@@ -0,0 +1,122 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials/internal/config"
+)
+
+// DefaultEnvStorePrefix is the default prefix of the environment variables
+// read by the store created by NewEnvStore.
+const DefaultEnvStorePrefix = "ORAS_AUTH_"
+
+// dockerHubHostname is the hostname identifying Docker Hub in the stores
+// keyed by hostnames.
+const dockerHubHostname = "docker.io"
+
+// envStore is a read-only store reading credentials from environment
+// variables.
+type envStore struct {
+	prefix string
+}
+
+// NewEnvStore creates a read-only store reading credentials from environment
+// variables named by the prefix and the registry hostname, such as
+// "ORAS_AUTH_REGISTRY_EXAMPLE_COM_5000" for "registry.example.com:5000" with
+// the default prefix "ORAS_AUTH_". The hostname is upper-cased and all the
+// characters other than letters and digits are replaced by "_". The
+// credentials of Docker Hub are read from "ORAS_AUTH_DOCKER_IO".
+//
+// The value of the environment variable is either "username:password", or
+// an identity token if there is no colon. If prefix is empty,
+// DefaultEnvStorePrefix is used.
+//
+// The server addresses are normalized as those returned by
+// ServerAddressFromHostname and ServerAddressFromRegistry. Put and Delete are
+// not supported, thus the returned store is expected to be used as a fallback
+// of NewStoreWithFallbacks.
+func NewEnvStore(prefix string) Store {
+	if prefix == "" {
+		prefix = DefaultEnvStorePrefix
+	}
+	return &envStore{prefix: prefix}
+}
+
+// Get retrieves credentials from the environment variable for the given
+// server address.
+func (es *envStore) Get(_ context.Context, serverAddress string) (auth.Credential, error) {
+	hostname := storeHostname(serverAddress)
+	if hostname == "" {
+		return auth.EmptyCredential, nil
+	}
+	value := os.Getenv(es.prefix + envVarSuffix(hostname))
+	if value == "" {
+		return auth.EmptyCredential, nil
+	}
+	username, password, ok := strings.Cut(value, ":")
+	if !ok {
+		return auth.Credential{RefreshToken: value}, nil
+	}
+	return auth.Credential{
+		Username: username,
+		Password: password,
+	}, nil
+}
+
+// Put is not supported by the env store.
+func (es *envStore) Put(_ context.Context, _ string, _ auth.Credential) error {
+	return fmt.Errorf("%w: environment variable store is read-only", errdef.ErrUnsupported)
+}
+
+// Delete is not supported by the env store.
+func (es *envStore) Delete(_ context.Context, _ string) error {
+	return fmt.Errorf("%w: environment variable store is read-only", errdef.ErrUnsupported)
+}
+
+// envVarSuffix converts the hostname to the suffix of an environment variable
+// name.
+func envVarSuffix(hostname string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, hostname)
+}
+
+// storeHostname normalizes the server address to the hostname used by the
+// stores keyed by hostnames, which is consistent with
+// ServerAddressFromHostname and ServerAddressFromRegistry: the scheme and the
+// path are removed, and the hosts of Docker Hub are mapped to "docker.io".
+func storeHostname(serverAddress string) string {
+	hostname := config.ToHostname(serverAddress)
+	switch hostname {
+	case "index.docker.io", "registry-1.docker.io":
+		return dockerHubHostname
+	}
+	return hostname
+}
@@ -0,0 +1,123 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+func TestEnvStore_Get(t *testing.T) {
+	t.Setenv("ORAS_AUTH_REGISTRY_EXAMPLE_COM_5000", "username:pass:word")
+	t.Setenv("ORAS_AUTH_TOKEN_EXAMPLE_COM", "identity_token")
+	t.Setenv("ORAS_AUTH_DOCKER_IO", "docker_user:docker_password")
+	t.Setenv("CUSTOM_REGISTRY_EXAMPLE_COM_5000", "custom:password")
+
+	tests := []struct {
+		name          string
+		prefix        string
+		serverAddress string
+		want          auth.Credential
+	}{
+		{
+			name:          "basic credential",
+			serverAddress: "registry.example.com:5000",
+			want:          auth.Credential{Username: "username", Password: "pass:word"},
+		},
+		{
+			name:          "identity token",
+			serverAddress: "token.example.com",
+			want:          auth.Credential{RefreshToken: "identity_token"},
+		},
+		{
+			name:          "docker hub server address",
+			serverAddress: ServerAddressFromHostname("registry-1.docker.io"),
+			want:          auth.Credential{Username: "docker_user", Password: "docker_password"},
+		},
+		{
+			name:          "docker hub registry",
+			serverAddress: ServerAddressFromRegistry("docker.io"),
+			want:          auth.Credential{Username: "docker_user", Password: "docker_password"},
+		},
+		{
+			name:          "custom prefix",
+			prefix:        "CUSTOM_",
+			serverAddress: "registry.example.com:5000",
+			want:          auth.Credential{Username: "custom", Password: "password"},
+		},
+		{
+			name:          "not found",
+			serverAddress: "registry.example.com",
+			want:          auth.EmptyCredential,
+		},
+		{
+			name:          "empty server address",
+			serverAddress: "",
+			want:          auth.EmptyCredential,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewEnvStore(tt.prefix).Get(context.Background(), tt.serverAddress)
+			if err != nil {
+				t.Fatalf("envStore.Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("envStore.Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvStore_ReadOnly(t *testing.T) {
+	es := NewEnvStore("")
+	if err := es.Put(context.Background(), "registry.example.com", auth.Credential{Username: "u", Password: "p"}); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("envStore.Put() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+	if err := es.Delete(context.Background(), "registry.example.com"); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("envStore.Delete() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+}
+
+func TestEnvStore_WithFallbacks(t *testing.T) {
+	t.Setenv("ORAS_AUTH_REGISTRY_EXAMPLE_COM", "env_user:env_password")
+	t.Setenv("NETRC", "testdata/netrc")
+	ns, err := NewNetrcStore("")
+	if err != nil {
+		t.Fatalf("NewNetrcStore() error = %v", err)
+	}
+	store := NewStoreWithFallbacks(NewMemoryStore(), NewEnvStore(""), ns)
+	ctx := context.Background()
+
+	got, err := store.Get(ctx, "registry.example.com")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if want := (auth.Credential{Username: "env_user", Password: "env_password"}); got != want {
+		t.Errorf("Get() = %v, want %v", got, want)
+	}
+	got, err = store.Get(ctx, "netrc.example.com")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if want := (auth.Credential{Username: "netrc_user", Password: "netrc_password"}); got != want {
+		t.Errorf("Get() = %v, want %v", got, want)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"oras.land/oras-go/v2/registry/remote/auth"
@@ -85,6 +86,14 @@ func (fs *FileStore) Delete(_ context.Context, serverAddress string) error {
 	return fs.config.DeleteCredential(serverAddress)
 }
 
+// List returns the server addresses of which the credentials are stored in
+// the config file.
+func (fs *FileStore) List(_ context.Context) ([]string, error) {
+	serverAddresses := fs.config.ServerAddresses()
+	slices.Sort(serverAddresses)
+	return serverAddresses, nil
+}
+
 // validateCredentialFormat validates the format of cred.
 func validateCredentialFormat(cred auth.Credential) error {
 	if strings.ContainsRune(cred.Username, ':') {
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
@@ -184,6 +185,38 @@ func (cfg *Config) GetCredential(serverAddress string) (auth.Credential, error)
 	return authCfg.Credential()
 }
 
+// LookupCredential returns an auth.Credential for the exact key and reports
+// whether the key is found. Keys may be scoped to repositories in the form of
+// "registry/namespace/repository". For keys without any path, keys stored with
+// a http/https prefix are also matched.
+func (cfg *Config) LookupCredential(key string) (auth.Credential, bool, error) {
+	cfg.rwLock.RLock()
+	defer cfg.rwLock.RUnlock()
+
+	authCfgBytes, ok := cfg.authsCache[key]
+	if !ok && !strings.Contains(key, "/") {
+		for addr, auth := range cfg.authsCache {
+			if strings.Contains(addr, "://") && ToHostname(addr) == key {
+				ok = true
+				authCfgBytes = auth
+				break
+			}
+		}
+	}
+	if !ok {
+		return auth.EmptyCredential, false, nil
+	}
+	var authCfg AuthConfig
+	if err := json.Unmarshal(authCfgBytes, &authCfg); err != nil {
+		return auth.EmptyCredential, false, fmt.Errorf("failed to unmarshal auth field: %w: %v", ErrInvalidConfigFormat, err)
+	}
+	cred, err := authCfg.Credential()
+	if err != nil {
+		return auth.EmptyCredential, false, err
+	}
+	return cred, true, nil
+}
+
 // PutAuthConfig puts cred for serverAddress.
 func (cfg *Config) PutCredential(serverAddress string, cred auth.Credential) error {
 	cfg.rwLock.Lock()
@@ -211,6 +244,24 @@ func (cfg *Config) DeleteCredential(serverAddress string) error {
 	return cfg.saveFile()
 }
 
+// ServerAddresses returns the server addresses in the auths field.
+func (cfg *Config) ServerAddresses() []string {
+	cfg.rwLock.RLock()
+	defer cfg.rwLock.RUnlock()
+
+	serverAddresses := make([]string, 0, len(cfg.authsCache))
+	for serverAddress := range cfg.authsCache {
+		serverAddresses = append(serverAddresses, serverAddress)
+	}
+	return serverAddresses
+}
+
+// CredentialHelpers returns a copy of the credHelpers field, mapping server
+// addresses to the credential helpers.
+func (cfg *Config) CredentialHelpers() map[string]string {
+	return maps.Clone(cfg.credentialHelpers)
+}
+
 // GetCredentialHelper returns the credential helpers for serverAddress.
 func (cfg *Config) GetCredentialHelper(serverAddress string) string {
 	return cfg.credentialHelpers[serverAddress]
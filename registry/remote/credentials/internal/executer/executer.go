@@ -52,15 +52,53 @@ func New(name string) Executer {
 // Execute operates on an executable binary and supports context.
 func (c *executable) Execute(ctx context.Context, input io.Reader, action string) ([]byte, error) {
 	cmd := exec.CommandContext(ctx, c.name, action)
+	return run(ctx, cmd, c.name, input, action)
+}
+
+// command implements the Executer interface for binaries invoked with fixed
+// arguments and additional environment variables.
+type command struct {
+	name string
+	args []string
+	env  []string
+}
+
+// NewCommand returns a new Executer instance invoking the binary with the
+// given arguments and additional environment variables in the form of
+// "key=value". The action passed to Execute is appended to the arguments if
+// not empty.
+func NewCommand(name string, args []string, env []string) Executer {
+	return &command{
+		name: name,
+		args: args,
+		env:  env,
+	}
+}
+
+// Execute operates on an executable binary and supports context.
+func (c *command) Execute(ctx context.Context, input io.Reader, action string) ([]byte, error) {
+	args := c.args
+	if action != "" {
+		args = append(args[:len(args):len(args)], action)
+	}
+	cmd := exec.CommandContext(ctx, c.name, args...)
+	if len(c.env) > 0 {
+		cmd.Env = append(os.Environ(), c.env...)
+	}
+	return run(ctx, cmd, c.name, input, action)
+}
+
+// run runs the command with the given input and returns its output.
+func run(ctx context.Context, cmd *exec.Cmd, name string, input io.Reader, action string) ([]byte, error) {
 	cmd.Stdin = input
 	cmd.Stderr = os.Stderr
 	trace := trace.ContextExecutableTrace(ctx)
 	if trace != nil && trace.ExecuteStart != nil {
-		trace.ExecuteStart(c.name, action)
+		trace.ExecuteStart(name, action)
 	}
 	output, err := cmd.Output()
 	if trace != nil && trace.ExecuteDone != nil {
-		trace.ExecuteDone(c.name, action, err)
+		trace.ExecuteDone(name, action, err)
 	}
 	if err != nil {
 		switch execErr := err.(type) {
@@ -70,7 +108,7 @@ func (c *executable) Execute(ctx context.Context, input io.Reader, action string
 			}
 		case *exec.Error:
 			// check if the error is caused by Docker Desktop not running
-			if execErr.Err == exec.ErrNotFound && c.name == dockerDesktopHelperName {
+			if execErr.Err == exec.ErrNotFound && name == dockerDesktopHelperName {
 				return nil, errors.New("credentials store is configured to `desktop.exe` but Docker Desktop seems not running")
 			}
 		}
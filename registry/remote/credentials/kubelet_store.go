@@ -0,0 +1,448 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials/internal/config"
+	"oras.land/oras-go/v2/registry/remote/credentials/internal/executer"
+)
+
+const (
+	kubeletConfigKind   = "CredentialProviderConfig"
+	kubeletRequestKind  = "CredentialProviderRequest"
+	kubeletResponseKind = "CredentialProviderResponse"
+
+	kubeletCacheKeyTypeImage    = "Image"
+	kubeletCacheKeyTypeRegistry = "Registry"
+	kubeletCacheKeyTypeGlobal   = "Global"
+)
+
+// kubeletAPIVersions is the set of supported API versions of the kubelet
+// credential provider exec protocol.
+var kubeletAPIVersions = map[string]struct{}{
+	"credentialprovider.kubelet.k8s.io/v1":       {},
+	"credentialprovider.kubelet.k8s.io/v1beta1":  {},
+	"credentialprovider.kubelet.k8s.io/v1alpha1": {},
+}
+
+// kubeletConfigAPIVersions is the set of supported API versions of the kubelet
+// CredentialProviderConfig.
+var kubeletConfigAPIVersions = map[string]struct{}{
+	"kubelet.config.k8s.io/v1":       {},
+	"kubelet.config.k8s.io/v1beta1":  {},
+	"kubelet.config.k8s.io/v1alpha1": {},
+}
+
+// ErrInvalidKubeletConfig is returned when the kubelet credential provider
+// config is invalid.
+var ErrInvalidKubeletConfig = errors.New("invalid kubelet credential provider config")
+
+// kubeletConfig is the kubelet CredentialProviderConfig.
+// Reference: https://kubernetes.io/docs/reference/config-api/kubelet-config.v1/#kubelet-config-k8s-io-v1-CredentialProviderConfig
+type kubeletConfig struct {
+	Kind       string                  `json:"kind"`
+	APIVersion string                  `json:"apiVersion"`
+	Providers  []kubeletProviderConfig `json:"providers"`
+}
+
+// kubeletProviderConfig is the kubelet CredentialProvider config of a single
+// exec plugin.
+type kubeletProviderConfig struct {
+	Name                 string       `json:"name"`
+	MatchImages          []string     `json:"matchImages"`
+	DefaultCacheDuration string       `json:"defaultCacheDuration"`
+	APIVersion           string       `json:"apiVersion"`
+	Args                 []string     `json:"args"`
+	Env                  []kubeletEnv `json:"env"`
+}
+
+// kubeletEnv is an environment variable passed to an exec plugin.
+type kubeletEnv struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// kubeletRequest is the CredentialProviderRequest sent to an exec plugin.
+type kubeletRequest struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Image      string `json:"image"`
+}
+
+// kubeletResponse is the CredentialProviderResponse returned by an exec
+// plugin.
+type kubeletResponse struct {
+	Kind          string                     `json:"kind"`
+	APIVersion    string                     `json:"apiVersion"`
+	CacheKeyType  string                     `json:"cacheKeyType"`
+	CacheDuration *string                    `json:"cacheDuration,omitempty"`
+	Auth          map[string]kubeletAuthConf `json:"auth"`
+}
+
+// kubeletAuthConf is the credential of an image pattern returned by an exec
+// plugin.
+type kubeletAuthConf struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// kubeletCacheEntry is the response of an exec plugin cached until expiry.
+type kubeletCacheEntry struct {
+	auth      map[string]kubeletAuthConf
+	expiresAt time.Time
+}
+
+// kubeletProvider is an exec plugin configured by kubeletProviderConfig.
+type kubeletProvider struct {
+	name                 string
+	apiVersion           string
+	matchImages          []string
+	defaultCacheDuration time.Duration
+	exec                 executer.Executer
+	cache                sync.Map // map[string]kubeletCacheEntry
+}
+
+// kubeletStore is a read-only store retrieving credentials from kubelet
+// credential provider exec plugins.
+type kubeletStore struct {
+	providers []*kubeletProvider
+}
+
+// NewKubeletStore creates a read-only store retrieving credentials from the
+// kubelet credential provider exec plugins configured by the
+// CredentialProviderConfig file at configPath, so that the plugins can be
+// shared between kubelet and other tools. The plugin binaries are looked up
+// in binDir, as specified by the kubelet flag
+// "--image-credential-provider-bin-dir".
+//
+// Limitations:
+//   - The config file must be in the JSON format. YAML config files, which
+//     are common on nodes, are not supported and must be converted to JSON.
+//   - As the store is queried by server addresses, the image in the request
+//     is the registry host, which is matched against "matchImages" of the
+//     plugins. Therefore, only host patterns (e.g. "*.dkr.ecr.*.amazonaws.com")
+//     are supported, and configs with path-scoped patterns
+//     (e.g. "registry.example.com/team/*") are rejected.
+//
+// The config must have the kind "CredentialProviderConfig" and a supported
+// apiVersion of "kubelet.config.k8s.io". The plugins are invoked with the
+// configured arguments and environment variables, reading a
+// CredentialProviderRequest from stdin and writing a
+// CredentialProviderResponse to stdout.
+//
+// The plugins are tried in order and the first non-empty credential is
+// returned. Responses are cached according to their "cacheKeyType" for
+// "cacheDuration", or "defaultCacheDuration" of the plugin if absent.
+//
+// Put and Delete are not supported by the returned store.
+//
+// Reference: https://kubernetes.io/docs/tasks/administer-cluster/kubelet-credential-provider/
+func NewKubeletStore(configPath, binDir string) (Store, error) {
+	return newKubeletStore(configPath, func(cfg kubeletProviderConfig) executer.Executer {
+		env := make([]string, 0, len(cfg.Env))
+		for _, e := range cfg.Env {
+			env = append(env, e.Name+"="+e.Value)
+		}
+		return executer.NewCommand(filepath.Join(binDir, cfg.Name), cfg.Args, env)
+	})
+}
+
+// newKubeletStore creates a kubelet store from the config file at configPath
+// using the given function to create the executers of the plugins.
+func newKubeletStore(configPath string, newExecuter func(cfg kubeletProviderConfig) executer.Executer) (*kubeletStore, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubelet credential provider config %s: %w", configPath, err)
+	}
+	var cfg kubeletConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidKubeletConfig, configPath, err)
+	}
+	if cfg.Kind != kubeletConfigKind {
+		return nil, fmt.Errorf("%w: %s: unexpected kind %q", ErrInvalidKubeletConfig, configPath, cfg.Kind)
+	}
+	if _, ok := kubeletConfigAPIVersions[cfg.APIVersion]; !ok {
+		return nil, fmt.Errorf("%w: %s: unsupported apiVersion %q", ErrInvalidKubeletConfig, configPath, cfg.APIVersion)
+	}
+
+	ks := &kubeletStore{}
+	names := make(map[string]struct{}, len(cfg.Providers))
+	for _, pc := range cfg.Providers {
+		if err := validateKubeletProviderConfig(pc); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidKubeletConfig, configPath, err)
+		}
+		if _, ok := names[pc.Name]; ok {
+			return nil, fmt.Errorf("%w: %s: duplicate provider name %q", ErrInvalidKubeletConfig, configPath, pc.Name)
+		}
+		names[pc.Name] = struct{}{}
+
+		var defaultCacheDuration time.Duration
+		if pc.DefaultCacheDuration != "" {
+			defaultCacheDuration, err = time.ParseDuration(pc.DefaultCacheDuration)
+			if err != nil || defaultCacheDuration < 0 {
+				return nil, fmt.Errorf("%w: %s: provider %q: invalid defaultCacheDuration %q", ErrInvalidKubeletConfig, configPath, pc.Name, pc.DefaultCacheDuration)
+			}
+		}
+		ks.providers = append(ks.providers, &kubeletProvider{
+			name:                 pc.Name,
+			apiVersion:           pc.APIVersion,
+			matchImages:          pc.MatchImages,
+			defaultCacheDuration: defaultCacheDuration,
+			exec:                 newExecuter(pc),
+		})
+	}
+	return ks, nil
+}
+
+// validateKubeletProviderConfig validates the config of an exec plugin.
+func validateKubeletProviderConfig(pc kubeletProviderConfig) error {
+	if pc.Name == "" || pc.Name == "." || pc.Name == ".." || strings.ContainsAny(pc.Name, `/\`) {
+		return fmt.Errorf("invalid provider name %q", pc.Name)
+	}
+	if _, ok := kubeletAPIVersions[pc.APIVersion]; !ok {
+		return fmt.Errorf("provider %q: unsupported apiVersion %q", pc.Name, pc.APIVersion)
+	}
+	if len(pc.MatchImages) == 0 {
+		return fmt.Errorf("provider %q: empty matchImages", pc.Name)
+	}
+	for _, pattern := range pc.MatchImages {
+		u, err := parseKubeletImage(pattern)
+		if err != nil {
+			return fmt.Errorf("provider %q: invalid matchImages %q: %v", pc.Name, pattern, err)
+		}
+		if u.Path != "" && u.Path != "/" {
+			// the store is queried by registry hosts only
+			return fmt.Errorf("provider %q: path-scoped matchImages %q is not supported", pc.Name, pattern)
+		}
+	}
+	for _, e := range pc.Env {
+		if e.Name == "" || strings.Contains(e.Name, "=") {
+			return fmt.Errorf("provider %q: invalid env name %q", pc.Name, e.Name)
+		}
+	}
+	return nil
+}
+
+// Get retrieves credentials from the exec plugins for the given server
+// address.
+func (ks *kubeletStore) Get(ctx context.Context, serverAddress string) (auth.Credential, error) {
+	image := config.ToHostname(serverAddress)
+	for _, p := range ks.providers {
+		if !p.matches(image) {
+			continue
+		}
+		cred, err := p.get(ctx, image)
+		if err != nil {
+			return auth.EmptyCredential, fmt.Errorf("kubelet credential provider %q: %w", p.name, err)
+		}
+		if cred != auth.EmptyCredential {
+			return cred, nil
+		}
+	}
+	return auth.EmptyCredential, nil
+}
+
+// Put is not supported by the kubelet store.
+func (ks *kubeletStore) Put(_ context.Context, _ string, _ auth.Credential) error {
+	return fmt.Errorf("%w: kubelet credential provider store is read-only", errdef.ErrUnsupported)
+}
+
+// Delete is not supported by the kubelet store.
+func (ks *kubeletStore) Delete(_ context.Context, _ string) error {
+	return fmt.Errorf("%w: kubelet credential provider store is read-only", errdef.ErrUnsupported)
+}
+
+// matches reports whether the image matches any of the matchImages of the
+// plugin.
+func (p *kubeletProvider) matches(image string) bool {
+	for _, pattern := range p.matchImages {
+		if matchKubeletImage(pattern, image) {
+			return true
+		}
+	}
+	return false
+}
+
+// get retrieves the credential for the image from the cache, or by invoking
+// the plugin.
+func (p *kubeletProvider) get(ctx context.Context, image string) (auth.Credential, error) {
+	registry := image
+	if u, err := parseKubeletImage(image); err == nil {
+		registry = u.Host
+	}
+	cacheKeys := map[string]string{
+		kubeletCacheKeyTypeImage:    kubeletCacheKeyTypeImage + ":" + image,
+		kubeletCacheKeyTypeRegistry: kubeletCacheKeyTypeRegistry + ":" + registry,
+		kubeletCacheKeyTypeGlobal:   kubeletCacheKeyTypeGlobal,
+	}
+
+	now := time.Now()
+	for _, keyType := range []string{kubeletCacheKeyTypeImage, kubeletCacheKeyTypeRegistry, kubeletCacheKeyTypeGlobal} {
+		key := cacheKeys[keyType]
+		value, ok := p.cache.Load(key)
+		if !ok {
+			continue
+		}
+		entry := value.(kubeletCacheEntry)
+		if !now.Before(entry.expiresAt) {
+			p.cache.CompareAndDelete(key, value)
+			continue
+		}
+		return selectKubeletCredential(entry.auth, image), nil
+	}
+
+	resp, err := p.invoke(ctx, image)
+	if err != nil {
+		return auth.EmptyCredential, err
+	}
+	cacheDuration := p.defaultCacheDuration
+	if resp.CacheDuration != nil {
+		cacheDuration, err = time.ParseDuration(*resp.CacheDuration)
+		if err != nil {
+			return auth.EmptyCredential, fmt.Errorf("invalid cacheDuration %q: %w", *resp.CacheDuration, err)
+		}
+	}
+	if cacheDuration > 0 {
+		p.cache.Store(cacheKeys[resp.CacheKeyType], kubeletCacheEntry{
+			auth:      resp.Auth,
+			expiresAt: now.Add(cacheDuration),
+		})
+	}
+	return selectKubeletCredential(resp.Auth, image), nil
+}
+
+// invoke invokes the plugin for the image and validates its response.
+func (p *kubeletProvider) invoke(ctx context.Context, image string) (*kubeletResponse, error) {
+	req, err := json.Marshal(kubeletRequest{
+		Kind:       kubeletRequestKind,
+		APIVersion: p.apiVersion,
+		Image:      image,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out, err := p.exec.Execute(ctx, bytes.NewReader(req), "")
+	if err != nil {
+		return nil, err
+	}
+	var resp kubeletResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.Kind != kubeletResponseKind {
+		return nil, fmt.Errorf("unexpected response kind %q", resp.Kind)
+	}
+	if resp.APIVersion != p.apiVersion {
+		return nil, fmt.Errorf("unexpected response apiVersion %q, want %q", resp.APIVersion, p.apiVersion)
+	}
+	switch resp.CacheKeyType {
+	case kubeletCacheKeyTypeImage, kubeletCacheKeyTypeRegistry, kubeletCacheKeyTypeGlobal:
+	default:
+		return nil, fmt.Errorf("invalid cacheKeyType %q", resp.CacheKeyType)
+	}
+	return &resp, nil
+}
+
+// selectKubeletCredential returns the credential of the most specific
+// pattern in auth matching the image.
+func selectKubeletCredential(auths map[string]kubeletAuthConf, image string) auth.Credential {
+	var selected string
+	found := false
+	for pattern := range auths {
+		if !matchKubeletImage(pattern, image) {
+			continue
+		}
+		// prefer longer patterns and break ties deterministically
+		if found && (len(pattern) < len(selected) || len(pattern) == len(selected) && pattern > selected) {
+			continue
+		}
+		selected = pattern
+		found = true
+	}
+	if !found {
+		return auth.EmptyCredential
+	}
+	conf := auths[selected]
+	return auth.Credential{
+		Username: conf.Username,
+		Password: conf.Password,
+	}
+}
+
+// parseKubeletImage parses an image or an image pattern as a URL.
+func parseKubeletImage(image string) (*url.URL, error) {
+	if !strings.Contains(image, "://") {
+		image = "https://" + image
+	}
+	u, err := url.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing host")
+	}
+	return u, nil
+}
+
+// matchKubeletImage reports whether the image matches the pattern, following
+// the semantics of "matchImages" of kubelet:
+//   - Each dot-separated part of the host is matched by a glob of the
+//     corresponding part of the pattern, and the numbers of parts must be
+//     equal. For example, "*.example.com" matches "registry.example.com" but
+//     not "a.registry.example.com".
+//   - The ports must be equal.
+//   - The path of the pattern must be a prefix of the path of the image.
+//
+// Reference: https://kubernetes.io/docs/reference/config-api/kubelet-config.v1/#kubelet-config-k8s-io-v1-CredentialProvider
+func matchKubeletImage(pattern, image string) bool {
+	p, err := parseKubeletImage(pattern)
+	if err != nil {
+		return false
+	}
+	i, err := parseKubeletImage(image)
+	if err != nil {
+		return false
+	}
+	if p.Scheme != i.Scheme || p.Port() != i.Port() {
+		return false
+	}
+	patternParts := strings.Split(p.Hostname(), ".")
+	imageParts := strings.Split(i.Hostname(), ".")
+	if len(patternParts) != len(imageParts) {
+		return false
+	}
+	for j, part := range patternParts {
+		if matched, err := path.Match(part, imageParts[j]); err != nil || !matched {
+			return false
+		}
+	}
+	return strings.HasPrefix(i.Path, p.Path)
+}
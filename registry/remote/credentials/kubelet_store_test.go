@@ -0,0 +1,343 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials/internal/executer"
+)
+
+// testKubeletExecuter implements the Executer interface for testing purpose.
+// It simulates a kubelet credential provider exec plugin.
+type testKubeletExecuter struct {
+	t        *testing.T
+	config   kubeletProviderConfig
+	response func(image string) string
+	requests []kubeletRequest
+}
+
+// Execute records the request and returns the response for the image.
+func (e *testKubeletExecuter) Execute(ctx context.Context, input io.Reader, action string) ([]byte, error) {
+	if action != "" {
+		e.t.Errorf("unexpected action: %q", action)
+	}
+	var req kubeletRequest
+	if err := json.NewDecoder(input).Decode(&req); err != nil {
+		return nil, err
+	}
+	if req.Kind != kubeletRequestKind || req.APIVersion != e.config.APIVersion {
+		e.t.Errorf("unexpected request: %+v", req)
+	}
+	e.requests = append(e.requests, req)
+	resp := e.response(req.Image)
+	if resp == "" {
+		return nil, errors.New("plugin failure")
+	}
+	return []byte(resp), nil
+}
+
+// newTestKubeletStore creates a kubelet store with the given config and test
+// executers.
+func newTestKubeletStore(t *testing.T, config string, responses map[string]func(image string) string) (*kubeletStore, map[string]*testKubeletExecuter) {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	executers := make(map[string]*testKubeletExecuter)
+	ks, err := newKubeletStore(configPath, func(cfg kubeletProviderConfig) executer.Executer {
+		e := &testKubeletExecuter{
+			t:        t,
+			config:   cfg,
+			response: responses[cfg.Name],
+		}
+		executers[cfg.Name] = e
+		return e
+	})
+	if err != nil {
+		t.Fatalf("newKubeletStore() error = %v", err)
+	}
+	return ks, executers
+}
+
+func kubeletResponseJSON(cacheKeyType, cacheDuration string, auths map[string]kubeletAuthConf) string {
+	resp := kubeletResponse{
+		Kind:         kubeletResponseKind,
+		APIVersion:   "credentialprovider.kubelet.k8s.io/v1",
+		CacheKeyType: cacheKeyType,
+		Auth:         auths,
+	}
+	if cacheDuration != "" {
+		resp.CacheDuration = &cacheDuration
+	}
+	data, _ := json.Marshal(resp)
+	return string(data)
+}
+
+const testKubeletConfig = `{
+	"kind": "CredentialProviderConfig",
+	"apiVersion": "kubelet.config.k8s.io/v1",
+	"providers": [
+		{
+			"name": "ecr-credential-provider",
+			"matchImages": ["*.dkr.ecr.*.amazonaws.com"],
+			"defaultCacheDuration": "12h",
+			"apiVersion": "credentialprovider.kubelet.k8s.io/v1",
+			"args": ["get-credentials"],
+			"env": [{"name": "AWS_PROFILE", "value": "temp"}]
+		},
+		{
+			"name": "test-provider",
+			"matchImages": ["registry.example.com", "*.example.com:5000"],
+			"defaultCacheDuration": "0s",
+			"apiVersion": "credentialprovider.kubelet.k8s.io/v1"
+		}
+	]
+}`
+
+func TestKubeletStore_Get(t *testing.T) {
+	ecrAuth := map[string]kubeletAuthConf{
+		"*.dkr.ecr.*.amazonaws.com": {Username: "AWS", Password: "ecr-password"},
+	}
+	ks, executers := newTestKubeletStore(t, testKubeletConfig, map[string]func(string) string{
+		"ecr-credential-provider": func(image string) string {
+			return kubeletResponseJSON(kubeletCacheKeyTypeRegistry, "", ecrAuth)
+		},
+		"test-provider": func(image string) string {
+			return kubeletResponseJSON(kubeletCacheKeyTypeImage, "", map[string]kubeletAuthConf{
+				"*.example.com:5000":          {Username: "generic", Password: "generic-password"},
+				"registry.example.com:5000":   {Username: "specific", Password: "specific-password"},
+				"registry.example.com:5000/a": {Username: "repository", Password: "repository-password"},
+				"registry.example.com":        {Username: "default", Password: "default-password"},
+			})
+		},
+	})
+
+	tests := []struct {
+		serverAddress string
+		want          auth.Credential
+	}{
+		{"123456789012.dkr.ecr.us-east-1.amazonaws.com", auth.Credential{Username: "AWS", Password: "ecr-password"}},
+		{"https://123456789012.dkr.ecr.us-west-2.amazonaws.com", auth.Credential{Username: "AWS", Password: "ecr-password"}},
+		{"registry.example.com:5000", auth.Credential{Username: "specific", Password: "specific-password"}},
+		{"mirror.example.com:5000", auth.Credential{Username: "generic", Password: "generic-password"}},
+		{"registry.example.com", auth.Credential{Username: "default", Password: "default-password"}},
+		{"a.registry.example.com:5000", auth.EmptyCredential},
+		{"registry.example.com:6000", auth.EmptyCredential},
+		{"docker.io", auth.EmptyCredential},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serverAddress, func(t *testing.T) {
+			got, err := ks.Get(context.Background(), tt.serverAddress)
+			if err != nil {
+				t.Fatalf("kubeletStore.Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("kubeletStore.Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the registry cache key type is used with the default cache duration
+	ecr := executers["ecr-credential-provider"]
+	wantImages := []string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com",
+		"123456789012.dkr.ecr.us-west-2.amazonaws.com",
+	}
+	if len(ecr.requests) != len(wantImages) {
+		t.Fatalf("ecr-credential-provider requests = %v, want %v", ecr.requests, wantImages)
+	}
+	for i, req := range ecr.requests {
+		if req.Image != wantImages[i] {
+			t.Errorf("ecr-credential-provider request image = %v, want %v", req.Image, wantImages[i])
+		}
+	}
+	if _, err := ks.Get(context.Background(), wantImages[0]); err != nil {
+		t.Fatalf("kubeletStore.Get() error = %v", err)
+	}
+	if len(ecr.requests) != len(wantImages) {
+		t.Errorf("ecr-credential-provider invoked %d times, want %d", len(ecr.requests), len(wantImages))
+	}
+	if want := []string{"get-credentials"}; !reflect.DeepEqual(ecr.config.Args, want) {
+		t.Errorf("ecr-credential-provider args = %v, want %v", ecr.config.Args, want)
+	}
+
+	// zero default cache duration disables caching
+	test := executers["test-provider"]
+	before := len(test.requests)
+	if _, err := ks.Get(context.Background(), "registry.example.com"); err != nil {
+		t.Fatalf("kubeletStore.Get() error = %v", err)
+	}
+	if got := len(test.requests) - before; got != 1 {
+		t.Errorf("test-provider invoked %d times, want 1", got)
+	}
+}
+
+func TestKubeletStore_Get_CacheKeyType(t *testing.T) {
+	config := `{
+		"kind": "CredentialProviderConfig",
+		"apiVersion": "kubelet.config.k8s.io/v1",
+		"providers": [{
+			"name": "provider",
+			"matchImages": ["*.example.com"],
+			"apiVersion": "credentialprovider.kubelet.k8s.io/v1"
+		}]
+	}`
+	tests := []struct {
+		cacheKeyType string
+		wantCount    int
+	}{
+		{kubeletCacheKeyTypeImage, 2},
+		{kubeletCacheKeyTypeRegistry, 2},
+		{kubeletCacheKeyTypeGlobal, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cacheKeyType, func(t *testing.T) {
+			ks, executers := newTestKubeletStore(t, config, map[string]func(string) string{
+				"provider": func(image string) string {
+					return kubeletResponseJSON(tt.cacheKeyType, "1h", map[string]kubeletAuthConf{
+						"*.example.com": {Username: "username", Password: "password"},
+					})
+				},
+			})
+			for _, serverAddress := range []string{"a.example.com", "b.example.com", "a.example.com", "b.example.com"} {
+				if _, err := ks.Get(context.Background(), serverAddress); err != nil {
+					t.Fatalf("kubeletStore.Get() error = %v", err)
+				}
+			}
+			if got := len(executers["provider"].requests); got != tt.wantCount {
+				t.Errorf("provider invoked %d times, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestKubeletStore_Get_InvalidResponse(t *testing.T) {
+	config := `{
+		"kind": "CredentialProviderConfig",
+		"apiVersion": "kubelet.config.k8s.io/v1",
+		"providers": [{
+			"name": "provider",
+			"matchImages": ["*.example.com"],
+			"apiVersion": "credentialprovider.kubelet.k8s.io/v1"
+		}]
+	}`
+	tests := map[string]string{
+		"failure.example.com":      "",
+		"json.example.com":         "not json",
+		"kind.example.com":         `{"kind":"Unknown","apiVersion":"credentialprovider.kubelet.k8s.io/v1","cacheKeyType":"Image"}`,
+		"apiversion.example.com":   `{"kind":"CredentialProviderResponse","apiVersion":"credentialprovider.kubelet.k8s.io/v1beta1","cacheKeyType":"Image"}`,
+		"cachekeytype.example.com": `{"kind":"CredentialProviderResponse","apiVersion":"credentialprovider.kubelet.k8s.io/v1"}`,
+		"duration.example.com":     `{"kind":"CredentialProviderResponse","apiVersion":"credentialprovider.kubelet.k8s.io/v1","cacheKeyType":"Image","cacheDuration":"forever"}`,
+	}
+	ks, _ := newTestKubeletStore(t, config, map[string]func(string) string{
+		"provider": func(image string) string {
+			return tests[image]
+		},
+	})
+	for serverAddress := range tests {
+		t.Run(serverAddress, func(t *testing.T) {
+			if _, err := ks.Get(context.Background(), serverAddress); err == nil {
+				t.Errorf("kubeletStore.Get() error = nil, wantErr true")
+			}
+		})
+	}
+}
+
+func TestNewKubeletStore_InvalidConfig(t *testing.T) {
+	provider := func(name, matchImages, apiVersion, defaultCacheDuration string) string {
+		return fmt.Sprintf(`{"name":%q,"matchImages":[%s],"apiVersion":%q,"defaultCacheDuration":%q}`, name, matchImages, apiVersion, defaultCacheDuration)
+	}
+	config := func(providers ...string) string {
+		return `{"kind":"CredentialProviderConfig","apiVersion":"kubelet.config.k8s.io/v1","providers":[` + strings.Join(providers, ",") + `]}`
+	}
+	const apiVersion = "credentialprovider.kubelet.k8s.io/v1"
+	tests := map[string]string{
+		"invalid json":            `{`,
+		"empty name":              config(provider("", `"*.example.com"`, apiVersion, "")),
+		"path name":               config(provider("../provider", `"*.example.com"`, apiVersion, "")),
+		"empty matchImages":       config(provider("provider", ``, apiVersion, "")),
+		"bad apiVersion":          config(provider("provider", `"*.example.com"`, "v1", "")),
+		"bad cache duration":      config(provider("provider", `"*.example.com"`, apiVersion, "forever")),
+		"path-scoped matchImages": config(provider("provider", `"*.example.com/team/*"`, apiVersion, "")),
+		"missing kind":            `{"apiVersion":"kubelet.config.k8s.io/v1","providers":[` + provider("provider", `"*.example.com"`, apiVersion, "") + `]}`,
+		"bad config apiVersion":   `{"kind":"CredentialProviderConfig","apiVersion":"v1","providers":[` + provider("provider", `"*.example.com"`, apiVersion, "") + `]}`,
+		"yaml":                    "kind: CredentialProviderConfig\napiVersion: kubelet.config.k8s.io/v1\nproviders: []\n",
+		"duplicate name":          config(provider("provider", `"*.example.com"`, apiVersion, ""), provider("provider", `"*.example.com"`, apiVersion, "")),
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			if _, err := NewKubeletStore(configPath, t.TempDir()); !errors.Is(err, ErrInvalidKubeletConfig) {
+				t.Errorf("NewKubeletStore() error = %v, wantErr %v", err, ErrInvalidKubeletConfig)
+			}
+		})
+	}
+
+	if _, err := NewKubeletStore(filepath.Join(t.TempDir(), "missing.json"), t.TempDir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("NewKubeletStore() error = %v, wantErr %v", err, os.ErrNotExist)
+	}
+}
+
+func TestKubeletStore_ReadOnly(t *testing.T) {
+	ks, _ := newTestKubeletStore(t, testKubeletConfig, nil)
+	if err := ks.Put(context.Background(), "registry.example.com", auth.Credential{Username: "u", Password: "p"}); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("kubeletStore.Put() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+	if err := ks.Delete(context.Background(), "registry.example.com"); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("kubeletStore.Delete() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+}
+
+func Test_matchKubeletImage(t *testing.T) {
+	tests := []struct {
+		pattern string
+		image   string
+		want    bool
+	}{
+		{"*.kubernetes.io", "registry.kubernetes.io", true},
+		{"*.kubernetes.io", "a.registry.kubernetes.io", false},
+		{"*.*.kubernetes.io", "a.registry.kubernetes.io", true},
+		{"prefix.*.io", "prefix.kubernetes.io", true},
+		{"*-good.kubernetes.io", "prefix-good.kubernetes.io", true},
+		{"registry.io:8080/path", "registry.io:8080/path/image", true},
+		{"registry.io:8080/path", "registry.io/path", false},
+		{"registry.io/path", "registry.io/other", false},
+		{"registry.io", "registry.io/any/path", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.image, func(t *testing.T) {
+			if got := matchKubeletImage(tt.pattern, tt.image); got != tt.want {
+				t.Errorf("matchKubeletImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
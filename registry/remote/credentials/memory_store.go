@@ -19,6 +19,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sync"
 
 	"oras.land/oras-go/v2/registry/remote/auth"
@@ -79,3 +80,15 @@ func (ms *memoryStore) Delete(_ context.Context, serverAddress string) error {
 	ms.store.Delete(serverAddress)
 	return nil
 }
+
+// List returns the server addresses of which the credentials are stored in
+// the memory store.
+func (ms *memoryStore) List(_ context.Context) ([]string, error) {
+	var serverAddresses []string
+	ms.store.Range(func(key, _ any) bool {
+		serverAddresses = append(serverAddresses, key.(string))
+		return true
+	})
+	slices.Sort(serverAddresses)
+	return serverAddresses, nil
+}
@@ -227,3 +227,28 @@ func TestMemoryStore_Delete_notExistRecord(t *testing.T) {
 		return
 	}
 }
+
+func TestMemoryStore_List(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemoryStore()
+	got, err := ms.(Lister).List(ctx)
+	if err != nil {
+		t.Fatalf("memoryStore.List() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("memoryStore.List() = %v, want empty", got)
+	}
+
+	for _, serverAddress := range []string{"registry2.example.com", "registry1.example.com"} {
+		if err := ms.Put(ctx, serverAddress, auth.Credential{Username: "username", Password: "password"}); err != nil {
+			t.Fatalf("memoryStore.Put() error = %v", err)
+		}
+	}
+	got, err = ms.(Lister).List(ctx)
+	if err != nil {
+		t.Fatalf("memoryStore.List() error = %v", err)
+	}
+	if want := []string{"registry1.example.com", "registry2.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("memoryStore.List() = %v, want %v", got, want)
+	}
+}
@@ -19,7 +19,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"maps"
 	"os/exec"
+	"slices"
 	"strings"
 
 	"oras.land/oras-go/v2/registry/remote/auth"
@@ -129,6 +131,21 @@ func (ns *nativeStore) Delete(ctx context.Context, serverAddress string) error {
 	return err
 }
 
+// List returns the server addresses of which the credentials are stored in
+// the native keychain, using the "list" action of the credential helper.
+func (ns *nativeStore) List(ctx context.Context) ([]string, error) {
+	out, err := ns.exec.Execute(ctx, strings.NewReader(""), "list")
+	if err != nil {
+		return nil, err
+	}
+	// the output maps server addresses to usernames
+	var listed map[string]string
+	if err := json.Unmarshal(out, &listed); err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(listed)), nil
+}
+
 // getDefaultHelperSuffix returns the default credential helper suffix.
 func getDefaultHelperSuffix() string {
 	platformDefault := getPlatformDefaultHelperSuffix()
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -109,6 +110,8 @@ func (e *testExecuter) Execute(ctx context.Context, input io.Reader, action stri
 		default:
 			return []byte("program failed"), errCommandExited
 		}
+	case "list":
+		return []byte(`{"localhost:2333": "test_username", "localhost:666": "<token>"}`), nil
 	case "erase":
 		switch inS {
 		case basicAuthHost, bearerAuthHost:
@@ -383,3 +386,14 @@ func TestNativeStore_multipleTrace(t *testing.T) {
 		t.Fatalf("incorrect buffer content: %s", bufferContent)
 	}
 }
+
+func TestNativeStore_List(t *testing.T) {
+	ns := &nativeStore{&testExecuter{}}
+	got, err := ns.List(context.Background())
+	if err != nil {
+		t.Fatalf("nativeStore.List() error = %v", err)
+	}
+	if want := []string{basicAuthHost, bearerAuthHost}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nativeStore.List() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,259 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+const (
+	netrcEnv = "NETRC"
+
+	netrcTokenMachine  = "machine"
+	netrcTokenDefault  = "default"
+	netrcTokenLogin    = "login"
+	netrcTokenPassword = "password"
+	netrcTokenAccount  = "account"
+	netrcTokenMacdef   = "macdef"
+)
+
+// ErrInvalidNetrcFormat is returned when the netrc file format is invalid.
+var ErrInvalidNetrcFormat = errors.New("invalid netrc format")
+
+// netrcStore is a read-only store reading credentials from a netrc file.
+type netrcStore struct {
+	// machines maps the normalized machine names to the credentials.
+	machines map[string]auth.Credential
+	// defaultCred is the credential of the "default" entry.
+	defaultCred auth.Credential
+	// useDefault controls if defaultCred is used for unmatched hosts.
+	useDefault bool
+}
+
+// NetrcStoreOptions provides options for NewNetrcStoreWithOptions.
+type NetrcStoreOptions struct {
+	// UseDefault controls if the credential of the "default" entry is
+	// returned for the registries not matching any "machine" entry.
+	// As the "default" entry matches any registry, enabling it sends the
+	// credential to every registry the client accesses.
+	//   - Default value: false.
+	UseDefault bool
+}
+
+// NewNetrcStore creates a read-only store reading credentials from the netrc
+// file at the given path. If path is empty, the file specified by the
+// environment variable "NETRC" is used, falling back to "~/.netrc" ("~/_netrc"
+// on Windows). A missing file results in an empty store.
+//
+// The "login" and "password" of the entry whose "machine" matches the
+// registry hostname are used, where the hostname with port is preferred over
+// the hostname without port. The "default" entry is ignored, see
+// NewNetrcStoreWithOptions for using it. Machine names are normalized as the
+// server addresses returned by ServerAddressFromHostname and
+// ServerAddressFromRegistry, so that, for example, both "docker.io" and
+// "index.docker.io" match Docker Hub.
+//
+// Put and Delete are not supported, thus the returned store is expected to be
+// used as a fallback of NewStoreWithFallbacks.
+//
+// Reference: https://www.gnu.org/software/inetutils/manual/html_node/The-_002enetrc-file.html
+func NewNetrcStore(path string) (Store, error) {
+	return NewNetrcStoreWithOptions(path, NetrcStoreOptions{})
+}
+
+// NewNetrcStoreWithOptions creates a read-only store reading credentials from
+// the netrc file at the given path with the given options.
+// See also NewNetrcStore.
+func NewNetrcStoreWithOptions(path string, opts NetrcStoreOptions) (Store, error) {
+	if path == "" {
+		var err error
+		if path, err = getNetrcPath(); err != nil {
+			return nil, err
+		}
+	}
+	ns := &netrcStore{
+		machines:   make(map[string]auth.Credential),
+		useDefault: opts.UseDefault,
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ns, nil
+		}
+		return nil, fmt.Errorf("failed to read netrc file at %s: %w", path, err)
+	}
+	if err := ns.parse(string(data)); err != nil {
+		return nil, fmt.Errorf("failed to parse netrc file at %s: %w", path, err)
+	}
+	return ns, nil
+}
+
+// Get retrieves credentials from the netrc file for the given server address.
+func (ns *netrcStore) Get(_ context.Context, serverAddress string) (auth.Credential, error) {
+	hostname := storeHostname(serverAddress)
+	if hostname == "" {
+		return auth.EmptyCredential, nil
+	}
+	if cred, ok := ns.machines[hostname]; ok {
+		return cred, nil
+	}
+	if host, _, err := net.SplitHostPort(hostname); err == nil {
+		if cred, ok := ns.machines[host]; ok {
+			return cred, nil
+		}
+	}
+	if ns.useDefault {
+		return ns.defaultCred, nil
+	}
+	return auth.EmptyCredential, nil
+}
+
+// Put is not supported by the netrc store.
+func (ns *netrcStore) Put(_ context.Context, _ string, _ auth.Credential) error {
+	return fmt.Errorf("%w: netrc store is read-only", errdef.ErrUnsupported)
+}
+
+// Delete is not supported by the netrc store.
+func (ns *netrcStore) Delete(_ context.Context, _ string) error {
+	return fmt.Errorf("%w: netrc store is read-only", errdef.ErrUnsupported)
+}
+
+// parse parses the content of a netrc file. Macro definitions are skipped.
+// For duplicated machines, the first entry wins.
+func (ns *netrcStore) parse(content string) error {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	var tokens []string
+	inMacro := false
+	// isValue reports whether the next token is the value of a keyword, such
+	// as a password starting with "#", rather than a keyword or a comment
+	isValue := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			// a macro definition ends with an empty line
+			if strings.TrimSpace(line) == "" {
+				inMacro = false
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if isValue {
+				tokens = append(tokens, field)
+				isValue = false
+				continue
+			}
+			if strings.HasPrefix(field, "#") {
+				// skip comments
+				break
+			}
+			tokens = append(tokens, field)
+			switch field {
+			case netrcTokenMachine, netrcTokenLogin, netrcTokenPassword, netrcTokenAccount:
+				isValue = true
+			}
+			if field == netrcTokenMacdef {
+				// skip the macro name and the macro definition
+				if i+1 < len(fields) {
+					tokens = append(tokens, fields[i+1])
+				}
+				inMacro = true
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	var machine string
+	var cred *auth.Credential
+	isDefault := false
+	commit := func() {
+		if cred == nil {
+			return
+		}
+		if isDefault {
+			ns.defaultCred = *cred
+		} else if _, ok := ns.machines[machine]; !ok {
+			ns.machines[machine] = *cred
+		}
+	}
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch token {
+		case netrcTokenMachine, netrcTokenDefault:
+			commit()
+			cred = &auth.Credential{}
+			isDefault = token == netrcTokenDefault
+			if !isDefault {
+				if i+1 >= len(tokens) {
+					return fmt.Errorf("%w: missing machine name", ErrInvalidNetrcFormat)
+				}
+				i++
+				machine = storeHostname(tokens[i])
+			}
+		case netrcTokenLogin, netrcTokenPassword, netrcTokenAccount, netrcTokenMacdef:
+			if i+1 >= len(tokens) {
+				return fmt.Errorf("%w: missing value of %q", ErrInvalidNetrcFormat, token)
+			}
+			i++
+			if cred == nil {
+				// ignore tokens not belonging to any machine
+				continue
+			}
+			switch token {
+			case netrcTokenLogin:
+				cred.Username = tokens[i]
+			case netrcTokenPassword:
+				cred.Password = tokens[i]
+			}
+		default:
+			return fmt.Errorf("%w: unexpected token %q", ErrInvalidNetrcFormat, token)
+		}
+	}
+	commit()
+	return nil
+}
+
+// getNetrcPath returns the path to the default netrc file.
+func getNetrcPath() (string, error) {
+	// first try the environment variable
+	if path := os.Getenv(netrcEnv); path != "" {
+		return path, nil
+	}
+	// then try home directory
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+	return filepath.Join(homeDir, name), nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+func TestNetrcStore_Get(t *testing.T) {
+	ns, err := NewNetrcStore("testdata/netrc")
+	if err != nil {
+		t.Fatalf("NewNetrcStore() error = %v", err)
+	}
+	tests := []struct {
+		serverAddress string
+		want          auth.Credential
+	}{
+		{"netrc.example.com", auth.Credential{Username: "netrc_user", Password: "netrc_password"}},
+		{"registry.example.com:5000", auth.Credential{Username: "port_user", Password: "port_password"}},
+		{"registry.example.com:6000", auth.Credential{Username: "host_user", Password: "host_password"}},
+		{"registry.example.com", auth.Credential{Username: "host_user", Password: "host_password"}},
+		{ServerAddressFromHostname("registry-1.docker.io"), auth.Credential{Username: "docker_user", Password: "docker_password"}},
+		{"macro.example.com", auth.EmptyCredential},
+		{"unknown.example.com", auth.EmptyCredential},
+		{"", auth.EmptyCredential},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serverAddress, func(t *testing.T) {
+			got, err := ns.Get(context.Background(), tt.serverAddress)
+			if err != nil {
+				t.Fatalf("netrcStore.Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("netrcStore.Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetrcStore_Get_UseDefault(t *testing.T) {
+	ns, err := NewNetrcStoreWithOptions("testdata/netrc", NetrcStoreOptions{UseDefault: true})
+	if err != nil {
+		t.Fatalf("NewNetrcStoreWithOptions() error = %v", err)
+	}
+	tests := []struct {
+		serverAddress string
+		want          auth.Credential
+	}{
+		{"netrc.example.com", auth.Credential{Username: "netrc_user", Password: "netrc_password"}},
+		{"macro.example.com", auth.Credential{Username: "default_user", Password: "default_password"}},
+		{"unknown.example.com", auth.Credential{Username: "default_user", Password: "default_password"}},
+		{"", auth.EmptyCredential},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serverAddress, func(t *testing.T) {
+			got, err := ns.Get(context.Background(), tt.serverAddress)
+			if err != nil {
+				t.Fatalf("netrcStore.Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("netrcStore.Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewNetrcStore_NotExist(t *testing.T) {
+	ns, err := NewNetrcStore(filepath.Join(t.TempDir(), "netrc"))
+	if err != nil {
+		t.Fatalf("NewNetrcStore() error = %v", err)
+	}
+	got, err := ns.Get(context.Background(), "registry.example.com")
+	if err != nil {
+		t.Fatalf("netrcStore.Get() error = %v", err)
+	}
+	if got != auth.EmptyCredential {
+		t.Errorf("netrcStore.Get() = %v, want %v", got, auth.EmptyCredential)
+	}
+	if err := ns.Put(context.Background(), "registry.example.com", auth.Credential{Username: "u", Password: "p"}); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("netrcStore.Put() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+	if err := ns.Delete(context.Background(), "registry.example.com"); !errors.Is(err, errdef.ErrUnsupported) {
+		t.Errorf("netrcStore.Delete() error = %v, wantErr %v", err, errdef.ErrUnsupported)
+	}
+}
+
+func TestNewNetrcStore_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"missing machine name": "machine",
+		"missing login value":  "machine registry.example.com login",
+		"unexpected token":     "machine registry.example.com user username",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "netrc")
+			if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+				t.Fatalf("failed to write netrc file: %v", err)
+			}
+			if _, err := NewNetrcStore(path); !errors.Is(err, ErrInvalidNetrcFormat) {
+				t.Errorf("NewNetrcStore() error = %v, wantErr %v", err, ErrInvalidNetrcFormat)
+			}
+		})
+	}
+}
+
+func TestNetrcStore_Get_HashValues(t *testing.T) {
+	content := `# comment
+machine registry.example.com login #user password #pass # trailing comment
+machine split.example.com
+  login
+    #split_user
+  password #split_pass
+`
+	path := filepath.Join(t.TempDir(), "netrc")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write netrc file: %v", err)
+	}
+	ns, err := NewNetrcStore(path)
+	if err != nil {
+		t.Fatalf("NewNetrcStore() error = %v", err)
+	}
+	tests := []struct {
+		serverAddress string
+		want          auth.Credential
+	}{
+		{"registry.example.com", auth.Credential{Username: "#user", Password: "#pass"}},
+		{"split.example.com", auth.Credential{Username: "#split_user", Password: "#split_pass"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serverAddress, func(t *testing.T) {
+			got, err := ns.Get(context.Background(), tt.serverAddress)
+			if err != nil {
+				t.Fatalf("netrcStore.Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("netrcStore.Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
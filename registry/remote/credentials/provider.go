@@ -0,0 +1,282 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"oras.land/oras-go/v2/internal/syncutil"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/internal/errutil"
+	"oras.land/oras-go/v2/registry/remote/internal/jwtutil"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+// providerExpiryLeeway is the duration before the expiry of a provided
+// credential in which the credential is considered as expired.
+const providerExpiryLeeway = 30 * time.Second
+
+const (
+	// defaultExchangeGrantType is the default grant type of the token
+	// exchange request.
+	defaultExchangeGrantType = "access_token"
+
+	// defaultExchangeTokenField is the default field of the token exchange
+	// response containing the registry token.
+	defaultExchangeTokenField = "refresh_token"
+
+	// defaultExchangeUsername is the default username of the exchanged
+	// credential, which is the well-known username expected by registries
+	// for identity tokens.
+	defaultExchangeUsername = "00000000-0000-0000-0000-000000000000"
+
+	// maxExchangeResponseBytes is the limit on how many response bytes are
+	// allowed in the token exchange response.
+	maxExchangeResponseBytes int64 = 128 * 1024 // 128 KiB
+)
+
+// Provider provides credentials for registries, typically by exchanging
+// identities of cloud platforms for registry credentials.
+type Provider interface {
+	// Provide returns the credential for the given registry (i.e. host:port)
+	// and the time when the credential expires.
+	// The zero time indicates that the expiry is unknown.
+	// EmptyCredential is a valid return value for unsupported registries and
+	// should not be considered as an error.
+	Provide(ctx context.Context, hostport string) (auth.Credential, time.Time, error)
+}
+
+// providerCacheEntry is a credential provided with its expiry.
+type providerCacheEntry struct {
+	cred      auth.Credential
+	expiresAt time.Time
+}
+
+// ProviderCredential returns a Credential() function that can be used by
+// auth.Client to resolve credentials from the provider.
+// Credentials with known expiry are cached per registry until shortly before
+// they expire. Credentials without known expiry are not cached.
+// Concurrent calls for the same registry on a cache miss share a single call
+// to the provider.
+func ProviderCredential(provider Provider) auth.CredentialFunc {
+	var cache sync.Map  // map[string]providerCacheEntry
+	var status sync.Map // map[string]*syncutil.Once
+	return func(ctx context.Context, hostport string) (auth.Credential, error) {
+		now := time.Now()
+		if value, ok := cache.Load(hostport); ok {
+			entry := value.(providerCacheEntry)
+			if now.Before(entry.expiresAt.Add(-providerExpiryLeeway)) {
+				return entry.cred, nil
+			}
+			cache.CompareAndDelete(hostport, value)
+		}
+
+		statusValue, _ := status.LoadOrStore(hostport, syncutil.NewOnce())
+		fetchOnce := statusValue.(*syncutil.Once)
+		fetchedFirst, result, err := fetchOnce.Do(ctx, func() (interface{}, error) {
+			cred, expiresAt, err := provider.Provide(ctx, hostport)
+			if err != nil {
+				return nil, err
+			}
+			entry := providerCacheEntry{
+				cred:      cred,
+				expiresAt: expiresAt,
+			}
+			if cred != auth.EmptyCredential && !expiresAt.IsZero() {
+				cache.Store(hostport, entry)
+			}
+			return entry, nil
+		})
+		if fetchedFirst {
+			status.Delete(hostport)
+		}
+		if err != nil {
+			return auth.EmptyCredential, err
+		}
+		return result.(providerCacheEntry).cred, nil
+	}
+}
+
+// TokenExchangeProvider is a Provider exchanging an identity token of a cloud
+// platform for a registry refresh token, by sending a form-encoded POST request
+// to the exchange endpoint of the registry.
+//
+// The request contains the form values "grant_type", "service" (the registry),
+// "access_token" (the identity token), and "tenant" if specified. The response
+// is a JSON object containing the registry token and optionally "expires_in"
+// in seconds. If "expires_in" is absent, the expiry is taken from the "exp"
+// claim of the registry token if it is a JWT.
+//
+// The default configuration follows the exchange protocol of Azure Container
+// Registry.
+// Reference: https://github.com/Azure/acr/blob/main/docs/AAD-OAuth.md
+type TokenExchangeProvider struct {
+	// Client is the underlying HTTP client used to access the exchange
+	// endpoint.
+	// If nil, retry.DefaultClient is used.
+	Client *http.Client
+
+	// IdentityToken returns the identity token of the cloud platform to be
+	// exchanged for the given registry (i.e. host:port).
+	// If an empty token is returned, auth.EmptyCredential is provided.
+	// IdentityToken is required.
+	IdentityToken func(ctx context.Context, hostport string) (string, error)
+
+	// Endpoint returns the URL of the exchange endpoint for the given registry.
+	// If nil, "https://<hostport>/oauth2/exchange" is used.
+	Endpoint func(hostport string) string
+
+	// Hosts is the list of host globs, using the syntax of path.Match, of the
+	// registries supported by the provider. For example, "*.azurecr.io".
+	// auth.EmptyCredential is provided for unsupported registries, and the
+	// identity token is never sent to them.
+	// If empty, no registry is supported. Hosts is required.
+	Hosts []string
+
+	// GrantType is the grant type of the exchange request.
+	// If empty, "access_token" is used.
+	GrantType string
+
+	// Tenant is the optional tenant of the exchange request.
+	Tenant string
+
+	// TokenField is the field of the exchange response containing the
+	// registry token.
+	// If empty, "refresh_token" is used.
+	TokenField string
+
+	// Username is the username of the provided credential.
+	// If empty, "00000000-0000-0000-0000-000000000000" is used.
+	Username string
+
+	// TokenLifetime is the lifetime of the registry token if the exchange
+	// response does not contain "expires_in" and the registry token carries
+	// no "exp" claim.
+	// If zero, the expiry of the registry token is unknown and the provided
+	// credential is not cached by ProviderCredential.
+	TokenLifetime time.Duration
+}
+
+// Provide exchanges the identity token for a registry refresh token of the
+// given registry and returns the credential with the refresh token.
+func (p *TokenExchangeProvider) Provide(ctx context.Context, hostport string) (auth.Credential, time.Time, error) {
+	if !p.supports(hostport) {
+		return auth.EmptyCredential, time.Time{}, nil
+	}
+	if p.IdentityToken == nil {
+		return auth.EmptyCredential, time.Time{}, errors.New("missing identity token function")
+	}
+	identityToken, err := p.IdentityToken(ctx, hostport)
+	if err != nil {
+		return auth.EmptyCredential, time.Time{}, fmt.Errorf("failed to get identity token for %s: %w", hostport, err)
+	}
+	if identityToken == "" {
+		return auth.EmptyCredential, time.Time{}, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", valueOrDefault(p.GrantType, defaultExchangeGrantType))
+	form.Set("service", hostport)
+	form.Set("access_token", identityToken)
+	if p.Tenant != "" {
+		form.Set("tenant", p.Tenant)
+	}
+	endpoint := "https://" + hostport + "/oauth2/exchange"
+	if p.Endpoint != nil {
+		endpoint = p.Endpoint(hostport)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return auth.EmptyCredential, time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := p.Client
+	if client == nil {
+		client = retry.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return auth.EmptyCredential, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return auth.EmptyCredential, time.Time{}, errutil.ParseErrorResponse(resp)
+	}
+	received := time.Now()
+
+	var result map[string]json.RawMessage
+	lr := io.LimitReader(resp.Body, maxExchangeResponseBytes)
+	if err := json.NewDecoder(lr).Decode(&result); err != nil {
+		return auth.EmptyCredential, time.Time{}, fmt.Errorf("%s %q: failed to decode response: %w", resp.Request.Method, resp.Request.URL, err)
+	}
+	tokenField := valueOrDefault(p.TokenField, defaultExchangeTokenField)
+	var token string
+	if raw, ok := result[tokenField]; ok {
+		if err := json.Unmarshal(raw, &token); err != nil {
+			return auth.EmptyCredential, time.Time{}, fmt.Errorf("%s %q: invalid %s: %w", resp.Request.Method, resp.Request.URL, tokenField, err)
+		}
+	}
+	if token == "" {
+		return auth.EmptyCredential, time.Time{}, fmt.Errorf("%s %q: empty token returned", resp.Request.Method, resp.Request.URL)
+	}
+
+	var expiresAt time.Time
+	if raw, ok := result["expires_in"]; ok {
+		var expiresIn int64
+		if err := json.Unmarshal(raw, &expiresIn); err == nil && expiresIn > 0 {
+			expiresAt = received.Add(time.Duration(expiresIn) * time.Second)
+		}
+	}
+	if expiresAt.IsZero() {
+		expiresAt = jwtutil.Expiry(token)
+	}
+	if expiresAt.IsZero() && p.TokenLifetime > 0 {
+		expiresAt = received.Add(p.TokenLifetime)
+	}
+	return auth.Credential{
+		Username:     valueOrDefault(p.Username, defaultExchangeUsername),
+		RefreshToken: token,
+	}, expiresAt, nil
+}
+
+// supports reports whether the registry is supported by the provider.
+func (p *TokenExchangeProvider) supports(hostport string) bool {
+	for _, pattern := range p.Hosts {
+		if matched, err := path.Match(pattern, hostport); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// valueOrDefault returns the value if not empty, or the default value.
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
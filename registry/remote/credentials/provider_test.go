@@ -0,0 +1,386 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+)
+
+// providerFunc is a Provider backed by a function.
+type providerFunc func(ctx context.Context, hostport string) (auth.Credential, time.Time, error)
+
+func (fn providerFunc) Provide(ctx context.Context, hostport string) (auth.Credential, time.Time, error) {
+	return fn(ctx, hostport)
+}
+
+func TestProviderCredential(t *testing.T) {
+	var count int64
+	expiresAt := time.Now().Add(time.Hour)
+	credFunc := ProviderCredential(providerFunc(func(ctx context.Context, hostport string) (auth.Credential, time.Time, error) {
+		atomic.AddInt64(&count, 1)
+		switch hostport {
+		case "cached.example.com":
+			return auth.Credential{RefreshToken: "cached"}, expiresAt, nil
+		case "uncached.example.com":
+			return auth.Credential{RefreshToken: "uncached"}, time.Time{}, nil
+		case "expiring.example.com":
+			return auth.Credential{RefreshToken: "expiring"}, time.Now().Add(providerExpiryLeeway / 2), nil
+		default:
+			return auth.EmptyCredential, time.Time{}, nil
+		}
+	}))
+
+	tests := []struct {
+		hostport  string
+		want      auth.Credential
+		wantCount int64
+	}{
+		{"cached.example.com", auth.Credential{RefreshToken: "cached"}, 1},
+		{"uncached.example.com", auth.Credential{RefreshToken: "uncached"}, 2},
+		{"expiring.example.com", auth.Credential{RefreshToken: "expiring"}, 2},
+		{"unknown.example.com", auth.EmptyCredential, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostport, func(t *testing.T) {
+			atomic.StoreInt64(&count, 0)
+			for i := 0; i < 2; i++ {
+				got, err := credFunc(context.Background(), tt.hostport)
+				if err != nil {
+					t.Fatalf("CredentialFunc() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("CredentialFunc() = %v, want %v", got, tt.want)
+				}
+			}
+			if got := atomic.LoadInt64(&count); got != tt.wantCount {
+				t.Errorf("Provider.Provide() called %d times, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestProviderCredential_Error(t *testing.T) {
+	wantErr := errors.New("provider failure")
+	credFunc := ProviderCredential(providerFunc(func(ctx context.Context, hostport string) (auth.Credential, time.Time, error) {
+		return auth.Credential{RefreshToken: "token"}, time.Now().Add(time.Hour), wantErr
+	}))
+	got, err := credFunc(context.Background(), "registry.example.com")
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("CredentialFunc() error = %v, wantErr %v", err, wantErr)
+	}
+	if got != auth.EmptyCredential {
+		t.Errorf("CredentialFunc() = %v, want %v", got, auth.EmptyCredential)
+	}
+}
+
+func TestProviderCredential_Concurrent(t *testing.T) {
+	var count int64
+	release := make(chan struct{})
+	credFunc := ProviderCredential(providerFunc(func(ctx context.Context, hostport string) (auth.Credential, time.Time, error) {
+		atomic.AddInt64(&count, 1)
+		<-release
+		return auth.Credential{RefreshToken: "token"}, time.Now().Add(time.Hour), nil
+	}))
+
+	const concurrency = 8
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	want := auth.Credential{RefreshToken: "token"}
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			got, err := credFunc(context.Background(), "registry.example.com")
+			if err != nil {
+				t.Errorf("CredentialFunc() error = %v", err)
+				return
+			}
+			if got != want {
+				t.Errorf("CredentialFunc() = %v, want %v", got, want)
+			}
+		}()
+	}
+	// give the goroutines a chance to join the in-flight call
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if got := atomic.LoadInt64(&count); got != 1 {
+		t.Errorf("Provider.Provide() called %d times, want 1", got)
+	}
+}
+
+func TestTokenExchangeProvider_Provide(t *testing.T) {
+	const (
+		identityToken = "aad_token"
+		registryToken = "acr_refresh_token"
+		tenant        = "tenant_id"
+	)
+	var count int64
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+		if r.Method != http.MethodPost || r.URL.Path != "/oauth2/exchange" {
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		wantForm := url.Values{
+			"grant_type":   []string{"access_token"},
+			"service":      []string{r.Host},
+			"access_token": []string{identityToken},
+			"tenant":       []string{tenant},
+		}
+		for key, want := range wantForm {
+			if got := r.PostForm.Get(key); got != want[0] {
+				t.Errorf("form %s = %v, want %v", key, got, want[0])
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"refresh_token":"` + registryToken + `"}`))
+	}))
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	provider := &TokenExchangeProvider{
+		Client: ts.Client(),
+		IdentityToken: func(ctx context.Context, hostport string) (string, error) {
+			return identityToken, nil
+		},
+		Hosts:         []string{"127.0.0.1:*"},
+		Tenant:        tenant,
+		TokenLifetime: time.Hour,
+	}
+	credFunc := ProviderCredential(provider)
+	want := auth.Credential{
+		Username:     defaultExchangeUsername,
+		RefreshToken: registryToken,
+	}
+	for i := 0; i < 2; i++ {
+		got, err := credFunc(context.Background(), uri.Host)
+		if err != nil {
+			t.Fatalf("CredentialFunc() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("CredentialFunc() = %v, want %v", got, want)
+		}
+	}
+	if got := atomic.LoadInt64(&count); got != 1 {
+		t.Errorf("exchange endpoint accessed %d times, want 1", got)
+	}
+
+	// unsupported host
+	got, expiresAt, err := provider.Provide(context.Background(), "registry.example.com")
+	if err != nil {
+		t.Fatalf("TokenExchangeProvider.Provide() error = %v", err)
+	}
+	if got != auth.EmptyCredential || !expiresAt.IsZero() {
+		t.Errorf("TokenExchangeProvider.Provide() = %v, %v, want %v, zero time", got, expiresAt, auth.EmptyCredential)
+	}
+}
+
+func TestTokenExchangeProvider_Provide_Custom(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/exchange" {
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if got := r.PostFormValue("grant_type"); got != "urn:custom" {
+			t.Errorf("form grant_type = %v, want %v", got, "urn:custom")
+		}
+		if r.PostForm.Has("tenant") {
+			t.Errorf("unexpected tenant: %v", r.PostForm.Get("tenant"))
+		}
+		w.Write([]byte(`{"token":"custom_token","expires_in":600}`))
+	}))
+	defer ts.Close()
+
+	provider := &TokenExchangeProvider{
+		Client: ts.Client(),
+		IdentityToken: func(ctx context.Context, hostport string) (string, error) {
+			return "identity", nil
+		},
+		Endpoint: func(hostport string) string {
+			return ts.URL + "/exchange"
+		},
+		Hosts:      []string{"registry.example.com"},
+		GrantType:  "urn:custom",
+		TokenField: "token",
+		Username:   "user",
+	}
+	before := time.Now()
+	got, expiresAt, err := provider.Provide(context.Background(), "registry.example.com")
+	if err != nil {
+		t.Fatalf("TokenExchangeProvider.Provide() error = %v", err)
+	}
+	want := auth.Credential{Username: "user", RefreshToken: "custom_token"}
+	if got != want {
+		t.Errorf("TokenExchangeProvider.Provide() = %v, want %v", got, want)
+	}
+	if expiresAt.Before(before.Add(10*time.Minute)) || expiresAt.After(time.Now().Add(10*time.Minute)) {
+		t.Errorf("TokenExchangeProvider.Provide() expiry = %v, want about 10 minutes later", expiresAt)
+	}
+}
+
+func TestTokenExchangeProvider_Provide_UnlistedHost(t *testing.T) {
+	var count int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+		w.Write([]byte(`{"refresh_token":"token"}`))
+	}))
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	for _, hosts := range [][]string{nil, {"*.azurecr.io"}} {
+		var identityCount int64
+		provider := &TokenExchangeProvider{
+			Client: ts.Client(),
+			IdentityToken: func(ctx context.Context, hostport string) (string, error) {
+				atomic.AddInt64(&identityCount, 1)
+				return "identity", nil
+			},
+			Endpoint: func(hostport string) string {
+				return ts.URL + "/oauth2/exchange"
+			},
+			Hosts: hosts,
+		}
+		got, expiresAt, err := provider.Provide(context.Background(), uri.Host)
+		if err != nil {
+			t.Fatalf("TokenExchangeProvider.Provide() error = %v", err)
+		}
+		if got != auth.EmptyCredential || !expiresAt.IsZero() {
+			t.Errorf("TokenExchangeProvider.Provide() = %v, %v, want %v, zero time", got, expiresAt, auth.EmptyCredential)
+		}
+		if got := atomic.LoadInt64(&identityCount); got != 0 {
+			t.Errorf("IdentityToken() called %d times with hosts %v, want 0", got, hosts)
+		}
+	}
+	if got := atomic.LoadInt64(&count); got != 0 {
+		t.Errorf("exchange endpoint accessed %d times, want 0", got)
+	}
+}
+
+func TestTokenExchangeProvider_Provide_JWTExpiry(t *testing.T) {
+	exp := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	token := "e30." + payload + ".sig"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"refresh_token":"` + token + `"}`))
+	}))
+	defer ts.Close()
+
+	provider := &TokenExchangeProvider{
+		Client: ts.Client(),
+		IdentityToken: func(ctx context.Context, hostport string) (string, error) {
+			return "identity", nil
+		},
+		Endpoint: func(hostport string) string {
+			return ts.URL + "/oauth2/exchange"
+		},
+		Hosts:         []string{"registry.example.com"},
+		TokenLifetime: time.Minute,
+	}
+	got, expiresAt, err := provider.Provide(context.Background(), "registry.example.com")
+	if err != nil {
+		t.Fatalf("TokenExchangeProvider.Provide() error = %v", err)
+	}
+	if got.RefreshToken != token {
+		t.Errorf("TokenExchangeProvider.Provide() = %v, want refresh token %v", got, token)
+	}
+	if !expiresAt.Equal(exp) {
+		t.Errorf("TokenExchangeProvider.Provide() expiry = %v, want %v", expiresAt, exp)
+	}
+}
+
+func TestTokenExchangeProvider_Provide_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/unauthorized":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors":[{"code":"UNAUTHORIZED","message":"invalid identity token"}]}`))
+		case "/empty":
+			w.Write([]byte(`{"access_token":"wrong_field"}`))
+		default:
+			w.Write([]byte(`not json`))
+		}
+	}))
+	defer ts.Close()
+
+	newProvider := func(path string) *TokenExchangeProvider {
+		return &TokenExchangeProvider{
+			Client: ts.Client(),
+			IdentityToken: func(ctx context.Context, hostport string) (string, error) {
+				return "identity", nil
+			},
+			Endpoint: func(hostport string) string {
+				return ts.URL + path
+			},
+			Hosts: []string{"registry.example.com"},
+		}
+	}
+
+	_, _, err := newProvider("/unauthorized").Provide(context.Background(), "registry.example.com")
+	var errResp *errcode.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("TokenExchangeProvider.Provide() error = %v, want %v", err, http.StatusUnauthorized)
+	}
+	for _, path := range []string{"/empty", "/invalid"} {
+		if _, _, err := newProvider(path).Provide(context.Background(), "registry.example.com"); err == nil {
+			t.Errorf("TokenExchangeProvider.Provide() %s error = nil, wantErr true", path)
+		}
+	}
+
+	wantErr := errors.New("identity failure")
+	provider := newProvider("/")
+	provider.IdentityToken = func(ctx context.Context, hostport string) (string, error) {
+		return "", wantErr
+	}
+	if _, _, err := provider.Provide(context.Background(), "registry.example.com"); !errors.Is(err, wantErr) {
+		t.Errorf("TokenExchangeProvider.Provide() error = %v, wantErr %v", err, wantErr)
+	}
+
+	// empty identity token
+	provider.IdentityToken = func(ctx context.Context, hostport string) (string, error) {
+		return "", nil
+	}
+	got, _, err := provider.Provide(context.Background(), "registry.example.com")
+	if err != nil || got != auth.EmptyCredential {
+		t.Errorf("TokenExchangeProvider.Provide() = %v, %v, want %v, nil", got, err, auth.EmptyCredential)
+	}
+}
@@ -32,6 +32,10 @@ var ErrClientTypeUnsupported = errors.New("client type not supported")
 // registry's client should be nil or of type *auth.Client. Login uses
 // a client local to the function and will not modify the original client of
 // the registry.
+//
+// If the client requests offline access (see auth.Client.OfflineAccess) and
+// the authorization service returns a refresh token, the refresh token is
+// stored as the identity token instead of the password.
 func Login(ctx context.Context, store Store, reg *remote.Registry, cred auth.Credential) error {
 	// create a clone of the original registry for login purpose
 	regClone := *reg
@@ -48,10 +52,22 @@ func Login(ctx context.Context, store Store, reg *remote.Registry, cred auth.Cre
 	regClone.Client = &authClient
 	// update credentials with the client
 	authClient.Credential = auth.StaticCredential(reg.Reference.Registry, cred)
+	authClient.ScopedCredential = nil
+	var refreshToken string
+	authClient.HandleRefreshToken = func(_ context.Context, _ string, token string) error {
+		refreshToken = token
+		return nil
+	}
 	// validate and store the credential
 	if err := regClone.Ping(ctx); err != nil {
 		return fmt.Errorf("failed to validate the credentials for %s: %w", regClone.Reference.Registry, err)
 	}
+	if refreshToken != "" {
+		cred = auth.Credential{
+			Username:     cred.Username,
+			RefreshToken: refreshToken,
+		}
+	}
 	hostname := ServerAddressFromRegistry(regClone.Reference.Registry)
 	if err := store.Put(ctx, hostname, cred); err != nil {
 		return fmt.Errorf("failed to store the credentials for %s: %w", hostname, err)
@@ -79,6 +95,32 @@ func Credential(store Store) auth.CredentialFunc {
 	}
 }
 
+// RefreshTokenHandler returns a HandleRefreshToken() function that can be used
+// by auth.Client to save the refresh tokens returned by the authorization
+// service into the store.
+// The refresh token is saved as the identity token of the credential of the
+// registry, replacing the password so that subsequent authentications do not
+// send the password.
+func RefreshTokenHandler(store Store) func(ctx context.Context, registry string, refreshToken string) error {
+	return func(ctx context.Context, registry string, refreshToken string) error {
+		serverAddress := ServerAddressFromHostname(registry)
+		if serverAddress == "" {
+			return nil
+		}
+		cred, err := store.Get(ctx, serverAddress)
+		if err != nil {
+			return err
+		}
+		cred.RefreshToken = refreshToken
+		cred.Password = ""
+		cred.AccessToken = ""
+		if err := store.Put(ctx, serverAddress, cred); err != nil {
+			return fmt.Errorf("failed to store the refresh token for %s: %w", serverAddress, err)
+		}
+		return nil
+	}
+}
+
 // ServerAddressFromRegistry maps a registry to a server address, which is used as
 // a key for credentials store. The Docker CLI expects that the credentials of
 // the registry 'docker.io' will be added under the key "https://index.docker.io/v1/".
@@ -114,6 +114,57 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+func TestLogin_refreshToken(t *testing.T) {
+	refreshToken := "test/refresh/token"
+	accessToken := "test/access/token"
+	as := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if got := r.PostForm.Get("access_type"); got != "offline" {
+			t.Errorf("unexpected access type: %v, want %v", got, "offline")
+		}
+		if r.PostForm.Get("username") != testUsername || r.PostForm.Get("password") != testPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"access_token":"` + accessToken + `","refresh_token":"` + refreshToken + `"}`))
+	}))
+	defer as.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+accessToken {
+			w.Header().Set("Www-Authenticate", `Bearer realm="`+as.URL+`",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+	uri, _ := url.Parse(ts.URL)
+	reg, err := remote.NewRegistry(uri.Host)
+	if err != nil {
+		t.Fatalf("cannot create test registry: %v", err)
+	}
+	reg.PlainHTTP = true
+	reg.Client = &auth.Client{
+		ForceAttemptOAuth2: true,
+		OfflineAccess:      true,
+	}
+	s := &testStore{}
+	ctx := context.Background()
+
+	if err := Login(ctx, s, reg, auth.Credential{Username: testUsername, Password: testPassword}); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	want := auth.Credential{Username: testUsername, RefreshToken: refreshToken}
+	if got := s.storage[reg.Reference.Registry]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stored credential = %v, want %v", got, want)
+	}
+	if reg.Client.(*auth.Client).HandleRefreshToken != nil {
+		t.Error("Login() modifies the original client")
+	}
+}
+
 func TestLogin_unsupportedClient(t *testing.T) {
 	var testClient http.Client
 	reg, err := remote.NewRegistry("whatever")
@@ -245,3 +296,48 @@ func TestCredential(t *testing.T) {
 		})
 	}
 }
+
+func TestRefreshTokenHandler(t *testing.T) {
+	s := &testStore{}
+	s.storage = map[string]auth.Credential{
+		"localhost:2333":              {Username: "test_user", Password: "test_word"},
+		"https://index.docker.io/v1/": {Username: "user", Password: "word"},
+	}
+	handle := RefreshTokenHandler(s)
+	ctx := context.Background()
+	tests := []struct {
+		name          string
+		registry      string
+		serverAddress string
+		want          auth.Credential
+	}{
+		{
+			name:          "replace password",
+			registry:      "localhost:2333",
+			serverAddress: "localhost:2333",
+			want:          auth.Credential{Username: "test_user", RefreshToken: "token1"},
+		},
+		{
+			name:          "docker hub",
+			registry:      "registry-1.docker.io",
+			serverAddress: "https://index.docker.io/v1/",
+			want:          auth.Credential{Username: "user", RefreshToken: "token2"},
+		},
+		{
+			name:          "registry not stored",
+			registry:      "localhost:6666",
+			serverAddress: "localhost:6666",
+			want:          auth.Credential{RefreshToken: "token3"},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := handle(ctx, tt.registry, tt.want.RefreshToken); err != nil {
+				t.Fatalf("RefreshTokenHandler() error = %v", err)
+			}
+			if got := s.storage[tt.serverAddress]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("test %d: Stored credential = %v, want %v", i, got, tt.want)
+			}
+		})
+	}
+}
@@ -22,9 +22,12 @@ package credentials
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"oras.land/oras-go/v2/internal/syncutil"
 	"oras.land/oras-go/v2/registry/remote/auth"
@@ -47,6 +50,17 @@ type Store interface {
 	Delete(ctx context.Context, serverAddress string) error
 }
 
+// Lister is an optional interface that a credentials store can implement to
+// enumerate the server addresses of which the credentials are stored, for
+// example, to show which registries the user is logged into.
+type Lister interface {
+	// List returns the server addresses of which the credentials are stored
+	// in the store, sorted in ascending order. If some of the underlying
+	// stores fail, the server addresses found may be returned along with a
+	// non-nil error.
+	List(ctx context.Context) ([]string, error)
+}
+
 // DynamicStore dynamically determines which store to use based on the settings
 // in the config file.
 type DynamicStore struct {
@@ -156,6 +170,54 @@ func (ds *DynamicStore) Delete(ctx context.Context, serverAddress string) error
 	return ds.getStore(serverAddress).Delete(ctx, serverAddress)
 }
 
+// List returns the server addresses of which the credentials are stored,
+// merging the entries of the "auths" field, the credentials listed by the
+// "credsStore" (or the detected default native store), and the server
+// addresses in the "credHelpers" field for which the credential helpers have
+// credentials.
+//
+// Failures of the "credsStore" or of individual credential helpers, such as
+// missing binaries, do not hide the server addresses found in the other
+// stores: the server addresses found are returned along with the failures
+// joined by errors.Join.
+func (ds *DynamicStore) List(ctx context.Context) ([]string, error) {
+	var errs []error
+	serverAddresses := make(map[string]struct{})
+	for _, serverAddress := range ds.config.ServerAddresses() {
+		serverAddresses[serverAddress] = struct{}{}
+	}
+
+	credsStore := ds.config.CredentialsStore()
+	if credsStore == "" {
+		credsStore = ds.detectedCredsStore
+	}
+	if credsStore != "" {
+		listed, err := NewNativeStore(credsStore).(Lister).List(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list credentials from credsStore %q: %w", credsStore, err))
+		}
+		for _, serverAddress := range listed {
+			serverAddresses[serverAddress] = struct{}{}
+		}
+	}
+
+	for serverAddress, helper := range ds.config.CredentialHelpers() {
+		if helper == "" {
+			continue
+		}
+		cred, err := NewNativeStore(helper).Get(ctx, serverAddress)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get credentials for %s from credential helper %q: %w", serverAddress, helper, err))
+			continue
+		}
+		if cred != auth.EmptyCredential {
+			serverAddresses[serverAddress] = struct{}{}
+		}
+	}
+
+	return slices.Sorted(maps.Keys(serverAddresses)), errors.Join(errs...)
+}
+
 // IsAuthConfigured returns whether there is authentication configured in the
 // config file or not.
 //
@@ -22,6 +22,8 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 
 	"oras.land/oras-go/v2/registry/remote/auth"
@@ -993,3 +995,139 @@ func TestNewStoreFromDocker(t *testing.T) {
 		t.Errorf("DynamicStore.Get() = %v, want %v", got, want)
 	}
 }
+
+// writeTestHelper writes a shell script as the credential helper with the
+// given suffix into dir.
+func writeTestHelper(t *testing.T, dir, suffix, script string) {
+	t.Helper()
+	path := filepath.Join(dir, remoteCredentialsPrefix+suffix)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700); err != nil {
+		t.Fatalf("failed to write credential helper: %v", err)
+	}
+}
+
+func Test_DynamicStore_List(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test on Windows as the credential helpers are shell scripts")
+	}
+	binDir := t.TempDir()
+	writeTestHelper(t, binDir, "teststore", `
+case "$1" in
+list) echo '{"store1.example.com":"user","registry1.example.com":"user"}' ;;
+*) exit 1 ;;
+esac
+`)
+	writeTestHelper(t, binDir, "test-helper", `
+read server
+case "$1:$server" in
+get:test.example.com) echo '{"Username":"user","Secret":"secret"}' ;;
+get:*) echo "credentials not found in native keychain"; exit 1 ;;
+*) exit 1 ;;
+esac
+`)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+	"auths": {
+		"registry1.example.com": {},
+		"registry2.example.com": {"auth": "dXNlcm5hbWU6cGFzc3dvcmQ="}
+	},
+	"credsStore": "teststore",
+	"credHelpers": {
+		"test.example.com": "test-helper",
+		"missing.example.com": "test-helper",
+		"empty.example.com": ""
+	}
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	ds, err := NewStore(configPath, StoreOptions{})
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	got, err := ds.List(context.Background())
+	if err != nil {
+		t.Fatalf("DynamicStore.List() error = %v", err)
+	}
+	want := []string{
+		"registry1.example.com",
+		"registry2.example.com",
+		"store1.example.com",
+		"test.example.com",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DynamicStore.List() = %v, want %v", got, want)
+	}
+}
+
+func Test_DynamicStore_List_BrokenHelpers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test on Windows as the credential helpers are shell scripts")
+	}
+	binDir := t.TempDir()
+	writeTestHelper(t, binDir, "test-helper", `
+read server
+case "$1:$server" in
+get:test.example.com) echo '{"Username":"user","Secret":"secret"}' ;;
+*) exit 1 ;;
+esac
+`)
+	writeTestHelper(t, binDir, "broken-helper", `
+echo "internal failure"
+exit 1
+`)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+	"auths": {
+		"registry1.example.com": {"auth": "dXNlcm5hbWU6cGFzc3dvcmQ="}
+	},
+	"credHelpers": {
+		"test.example.com": "test-helper",
+		"broken.example.com": "broken-helper",
+		"absent.example.com": "absent-helper"
+	}
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	ds, err := NewStore(configPath, StoreOptions{})
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	got, err := ds.List(context.Background())
+	if err == nil {
+		t.Error("DynamicStore.List() error = nil, wantErr true")
+	} else {
+		for _, helper := range []string{"broken-helper", "absent-helper"} {
+			if !strings.Contains(err.Error(), helper) {
+				t.Errorf("DynamicStore.List() error = %v, want error of %s", err, helper)
+			}
+		}
+	}
+	if want := []string{"registry1.example.com", "test.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DynamicStore.List() = %v, want %v", got, want)
+	}
+}
+
+func Test_DynamicStore_List_fileStore(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{"auths":{"registry1.example.com":{},"registry3.example.com":{}},"credHelpers":{"registry3.example.com":""}}`
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	ds, err := NewStore(configPath, StoreOptions{})
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	got, err := ds.List(context.Background())
+	if err != nil {
+		t.Fatalf("DynamicStore.List() error = %v", err)
+	}
+	if want := []string{"registry1.example.com", "registry3.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DynamicStore.List() = %v, want %v", got, want)
+	}
+}
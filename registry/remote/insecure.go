@@ -0,0 +1,165 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"path"
+	"strings"
+	"sync"
+)
+
+// InsecurePolicy describes the registries which are allowed to be accessed
+// via plain HTTP when they cannot be accessed via HTTPS.
+//
+// When an InsecurePolicy is configured on a Repository or a Registry, the
+// client first tries HTTPS and falls back to plain HTTP only if the HTTPS
+// request fails at the transport level and the host is allowed by the policy.
+// The scheme in use is then cached per host so that subsequent requests do
+// not attempt HTTPS again.
+//
+// An InsecurePolicy is safe for concurrent use and may be shared by multiple
+// repositories and registries, in which case the cache is shared as well.
+//
+// Reference: https://docs.docker.com/reference/cli/dockerd/#insecure-registries
+type InsecurePolicy struct {
+	// Hosts is a list of glob patterns matched against the registry host.
+	// A pattern is matched against both the host with port (e.g.
+	// "myregistry.local:5000") and the host without port (e.g.
+	// "myregistry.local"). The pattern syntax is the same as path.Match.
+	// Example: "*.internal.example.com"
+	Hosts []string
+
+	// CIDRs is a list of IP prefixes matched against registry hosts that are
+	// IP literals. Host names are not resolved.
+	// Example: netip.MustParsePrefix("10.0.0.0/8")
+	CIDRs []netip.Prefix
+
+	// AllowLocalhost allows "localhost" and loopback addresses to fall back
+	// to plain HTTP.
+	AllowLocalhost bool
+
+	// schemes caches the scheme to access hosts: map[string]string
+	schemes sync.Map
+}
+
+// Allows reports whether the given registry host, with or without port, is
+// allowed to be accessed via plain HTTP.
+func (p *InsecurePolicy) Allows(host string) bool {
+	if p == nil || host == "" {
+		return false
+	}
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	hostname = strings.TrimSuffix(strings.TrimPrefix(hostname, "["), "]")
+
+	for _, pattern := range p.Hosts {
+		if matched, _ := path.Match(pattern, host); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, hostname); matched {
+			return true
+		}
+	}
+
+	addr, err := netip.ParseAddr(hostname)
+	if err != nil {
+		return p.AllowLocalhost && strings.EqualFold(hostname, "localhost")
+	}
+	addr = addr.Unmap()
+	if p.AllowLocalhost && addr.IsLoopback() {
+		return true
+	}
+	for _, prefix := range p.CIDRs {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// cachedScheme returns the cached scheme for the host, if any.
+func (p *InsecurePolicy) cachedScheme(host string) (string, bool) {
+	scheme, ok := p.schemes.Load(host)
+	if !ok {
+		return "", false
+	}
+	return scheme.(string), true
+}
+
+// doWithInsecurePolicy sends the request using the client. If the request is
+// to be sent via HTTPS and the host is allowed by the policy, the request is
+// retried via plain HTTP on transport failures.
+//
+// On fallback, the URL of req is updated in place so that follow-up requests
+// derived from req, such as range requests, use the same scheme.
+func doWithInsecurePolicy(client Client, policy *InsecurePolicy, req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" || !policy.Allows(req.URL.Host) {
+		return client.Do(req)
+	}
+	host := req.URL.Host
+	if scheme, ok := policy.cachedScheme(host); ok {
+		if scheme == "http" {
+			setScheme(req, "http")
+		}
+		return client.Do(req)
+	}
+
+	resp, err := client.Do(req)
+	if err == nil {
+		policy.schemes.Store(host, "https")
+		return resp, nil
+	}
+	if req.Context().Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		// the request body has been consumed and closed by the first attempt
+		if req.GetBody == nil {
+			// the body cannot be rewound
+			return nil, err
+		}
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+
+	setScheme(req, "http")
+	resp, httpErr := client.Do(req)
+	if httpErr != nil {
+		// restore the original scheme so that the error reported is the
+		// HTTPS one, which is the expected protocol
+		setScheme(req, "https")
+		return nil, err
+	}
+	policy.schemes.Store(host, "http")
+	return resp, nil
+}
+
+// setScheme replaces the URL of req with a copy using the given scheme.
+func setScheme(req *http.Request, scheme string) {
+	u := *req.URL
+	u.Scheme = scheme
+	req.URL = &u
+}
@@ -0,0 +1,228 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// schemeRecorder records the schemes of the requests sent.
+type schemeRecorder struct {
+	client  Client
+	lock    sync.Mutex
+	schemes []string
+}
+
+func (c *schemeRecorder) Do(req *http.Request) (*http.Response, error) {
+	c.lock.Lock()
+	c.schemes = append(c.schemes, req.URL.Scheme)
+	c.lock.Unlock()
+	return c.client.Do(req)
+}
+
+func TestInsecurePolicy_Allows(t *testing.T) {
+	policy := &InsecurePolicy{
+		Hosts: []string{"*.internal.example.com", "registry.local:5000"},
+		CIDRs: []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+			netip.MustParsePrefix("fd00::/8"),
+		},
+		AllowLocalhost: true,
+	}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"foo.internal.example.com", true},
+		{"foo.internal.example.com:5000", true},
+		{"registry.local:5000", true},
+		{"registry.local", false},
+		{"registry.local:443", false},
+		{"example.com", false},
+		{"10.1.2.3", true},
+		{"10.1.2.3:5000", true},
+		{"11.1.2.3:5000", false},
+		{"[fd00::1]:5000", true},
+		{"[fe80::1]:5000", false},
+		{"localhost", true},
+		{"localhost:5000", true},
+		{"127.0.0.1:5000", true},
+		{"[::1]:5000", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := policy.Allows(tt.host); got != tt.want {
+				t.Errorf("InsecurePolicy.Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var nilPolicy *InsecurePolicy
+	if nilPolicy.Allows("localhost") {
+		t.Error("nil InsecurePolicy.Allows() = true, want false")
+	}
+	if (&InsecurePolicy{}).Allows("localhost:5000") {
+		t.Error("InsecurePolicy{}.Allows() = true, want false")
+	}
+}
+
+func TestRepository_InsecurePolicy_Fallback(t *testing.T) {
+	blob := []byte("hello world")
+	blobDesc := ocispec.Descriptor{
+		MediaType: "test",
+		Digest:    digest.FromBytes(blob),
+		Size:      int64(len(blob)),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v2/test/blobs/"+blobDesc.Digest.String():
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Docker-Content-Digest", blobDesc.Digest.String())
+			if _, err := w.Write(blob); err != nil {
+				t.Errorf("failed to write %q: %v", r.URL, err)
+			}
+		default:
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	recorder := &schemeRecorder{client: http.DefaultClient}
+	repo, err := NewRepository(uri.Host + "/test")
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	repo.Client = recorder
+	repo.InsecurePolicy = &InsecurePolicy{AllowLocalhost: true}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		rc, err := repo.Fetch(ctx, blobDesc)
+		if err != nil {
+			t.Fatalf("Repository.Fetch() error = %v", err)
+		}
+		buf := bytes.NewBuffer(nil)
+		if _, err := buf.ReadFrom(rc); err != nil {
+			t.Errorf("fail to read: %v", err)
+		}
+		if err := rc.Close(); err != nil {
+			t.Errorf("fail to close: %v", err)
+		}
+		if got := buf.Bytes(); !bytes.Equal(got, blob) {
+			t.Errorf("Repository.Fetch() = %v, want %v", got, blob)
+		}
+	}
+
+	// HTTPS should only be attempted once as the scheme is cached
+	want := []string{"https", "http", "http"}
+	if got := recorder.schemes; !slices.Equal(got, want) {
+		t.Errorf("schemes = %v, want %v", got, want)
+	}
+	if scheme, ok := repo.InsecurePolicy.cachedScheme(uri.Host); !ok || scheme != "http" {
+		t.Errorf("cached scheme = %q, %v, want %q, true", scheme, ok, "http")
+	}
+}
+
+func TestRepository_InsecurePolicy_NotAllowed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	recorder := &schemeRecorder{client: http.DefaultClient}
+	repo, err := NewRepository(uri.Host + "/test")
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	repo.Client = recorder
+	repo.InsecurePolicy = &InsecurePolicy{
+		Hosts: []string{"registry.example.com"},
+	}
+	ctx := context.Background()
+
+	if _, err := repo.Resolve(ctx, "latest"); err == nil {
+		t.Fatal("Repository.Resolve() error = nil, wantErr true")
+	}
+	want := []string{"https"}
+	if got := recorder.schemes; !slices.Equal(got, want) {
+		t.Errorf("schemes = %v, want %v", got, want)
+	}
+}
+
+func TestRegistry_InsecurePolicy_HTTPS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v2/" {
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}))
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	recorder := &schemeRecorder{client: ts.Client()}
+	reg, err := NewRegistry(uri.Host)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	reg.Client = recorder
+	reg.InsecurePolicy = &InsecurePolicy{AllowLocalhost: true}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := reg.Ping(ctx); err != nil {
+			t.Fatalf("Registry.Ping() error = %v", err)
+		}
+	}
+	want := []string{"https", "https"}
+	if got := recorder.schemes; !slices.Equal(got, want) {
+		t.Errorf("schemes = %v, want %v", got, want)
+	}
+
+	// the policy is inherited by derived repositories
+	repo, err := reg.Repository(ctx, "test")
+	if err != nil {
+		t.Fatalf("Registry.Repository() error = %v", err)
+	}
+	if got := repo.(*Repository).InsecurePolicy; got != reg.InsecurePolicy {
+		t.Errorf("Repository.InsecurePolicy = %v, want %v", got, reg.InsecurePolicy)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jwtutil
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestExpiry(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1700000000}`))
+	tests := []struct {
+		name  string
+		token string
+		want  time.Time
+	}{
+		{
+			name:  "jwt",
+			token: "e30." + payload + ".sig",
+			want:  time.Unix(1700000000, 0),
+		},
+		{
+			name:  "opaque token",
+			token: "foo",
+		},
+		{
+			name:  "invalid payload",
+			token: "a.!!!.c",
+		},
+		{
+			name:  "no exp claim",
+			token: "e30.e30.sig",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Expiry(tt.token); !got.Equal(tt.want) {
+				t.Errorf("Expiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -77,10 +77,10 @@ func (r *Registry) client() Client {
 // returned by r.client().
 func (r *Registry) do(req *http.Request) (*http.Response, error) {
 	if r.HandleWarning == nil {
-		return r.client().Do(req)
+		return doWithInsecurePolicy(r.client(), r.InsecurePolicy, req)
 	}
 
-	resp, err := r.client().Do(req)
+	resp, err := doWithInsecurePolicy(r.client(), r.InsecurePolicy, req)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +104,12 @@ type Repository struct {
 	// instead of HTTPS.
 	PlainHTTP bool
 
+	// InsecurePolicy specifies the hosts which are allowed to be accessed via
+	// plain HTTP if they cannot be accessed via HTTPS.
+	// If nil, no fallback is performed. It has no effect if PlainHTTP is true.
+	// See also InsecurePolicy.
+	InsecurePolicy *InsecurePolicy
+
 	// ManifestMediaTypes is used in `Accept` header for resolving manifests
 	// from references. It is also used in identifying manifests and blobs from
 	// descriptors. If an empty list is present, default manifest media types
@@ -201,6 +207,7 @@ func (r *Repository) clone() *Repository {
 		Client:               r.Client,
 		Reference:            r.Reference,
 		PlainHTTP:            r.PlainHTTP,
+		InsecurePolicy:       r.InsecurePolicy,
 		ManifestMediaTypes:   slices.Clone(r.ManifestMediaTypes),
 		TagListPageSize:      r.TagListPageSize,
 		ReferrerListPageSize: r.ReferrerListPageSize,
@@ -258,10 +265,10 @@ func (r *Repository) client() Client {
 // returned by r.client().
 func (r *Repository) do(req *http.Request) (*http.Response, error) {
 	if r.HandleWarning == nil {
-		return r.client().Do(req)
+		return doWithInsecurePolicy(r.client(), r.InsecurePolicy, req)
 	}
 
-	resp, err := r.client().Do(req)
+	resp, err := doWithInsecurePolicy(r.client(), r.InsecurePolicy, req)
 	if err != nil {
 		return nil, err
 	}
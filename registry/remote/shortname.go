@@ -0,0 +1,71 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry"
+)
+
+// ShortNameResolver resolves short names, such as "ubuntu:22.04", by probing
+// the candidate references expanded by registry.ShortNameConfig in order.
+type ShortNameResolver struct {
+	// Config configures the expansion of short names.
+	Config registry.ShortNameConfig
+
+	// RepositoryOptions is used as the template of the repositories created
+	// for probing the candidates.
+	// If nil, the default options are used.
+	RepositoryOptions *RepositoryOptions
+}
+
+// Resolve resolves name against the candidate references and returns the
+// repository and the descriptor of the first candidate found.
+// A candidate without a tag or a digest is resolved with the tag "latest".
+//
+// The errors of all candidates are returned if none is resolved. Context
+// errors abort the probing immediately.
+func (r *ShortNameResolver) Resolve(ctx context.Context, name string) (*Repository, ocispec.Descriptor, error) {
+	candidates, err := r.Config.Candidates(name)
+	if err != nil {
+		return nil, ocispec.Descriptor{}, err
+	}
+
+	opts := r.RepositoryOptions
+	if opts == nil {
+		opts = &RepositoryOptions{}
+	}
+	var errs []error
+	for _, ref := range candidates {
+		repo, err := newRepositoryWithOptions(ref, opts)
+		if err != nil {
+			return nil, ocispec.Descriptor{}, err
+		}
+		desc, err := repo.Resolve(ctx, ref.ReferenceOrDefault())
+		if err == nil {
+			return repo, desc, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ocispec.Descriptor{}, ctxErr
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", ref, err))
+	}
+	return nil, ocispec.Descriptor{}, fmt.Errorf("failed to resolve short name %q: %w", name, errors.Join(errs...))
+}
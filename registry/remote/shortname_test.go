@@ -0,0 +1,113 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
+)
+
+func TestShortNameResolver_Resolve(t *testing.T) {
+	manifest := []byte(`{"layers":[]}`)
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifest),
+		Size:      int64(len(manifest)),
+	}
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/v2/team/app/manifests/latest" {
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer notFound.Close()
+	found := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/v2/team/app/manifests/latest" {
+			t.Errorf("unexpected access: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", manifestDesc.MediaType)
+		w.Header().Set("Docker-Content-Digest", manifestDesc.Digest.String())
+		w.Header().Set("Content-Length", "13")
+	}))
+	defer found.Close()
+	notFoundURL, err := url.Parse(notFound.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+	foundURL, err := url.Parse(found.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	resolver := &ShortNameResolver{
+		Config: registry.ShortNameConfig{
+			SearchRegistries: []string{notFoundURL.Host, foundURL.Host},
+		},
+		RepositoryOptions: &RepositoryOptions{
+			PlainHTTP: true,
+		},
+	}
+	ctx := context.Background()
+
+	repo, desc, err := resolver.Resolve(ctx, "team/app")
+	if err != nil {
+		t.Fatalf("ShortNameResolver.Resolve() error = %v", err)
+	}
+	if !reflect.DeepEqual(desc, manifestDesc) {
+		t.Errorf("ShortNameResolver.Resolve() = %v, want %v", desc, manifestDesc)
+	}
+	wantRef := registry.Reference{
+		Registry:   foundURL.Host,
+		Repository: "team/app",
+	}
+	if repo.Reference != wantRef {
+		t.Errorf("Repository.Reference = %v, want %v", repo.Reference, wantRef)
+	}
+	if !repo.PlainHTTP {
+		t.Error("Repository.PlainHTTP = false, want true")
+	}
+
+	// none of the candidates are found
+	resolver.Config.SearchRegistries = []string{notFoundURL.Host}
+	if _, _, err := resolver.Resolve(ctx, "team/app"); !errors.Is(err, errdef.ErrNotFound) {
+		t.Errorf("ShortNameResolver.Resolve() error = %v, wantErr %v", err, errdef.ErrNotFound)
+	}
+}
+
+func TestShortNameResolver_Resolve_ContextCanceled(t *testing.T) {
+	resolver := &ShortNameResolver{
+		Config: registry.ShortNameConfig{
+			SearchRegistries: []string{"registry.example.com", "docker.io"},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := resolver.Resolve(ctx, "ubuntu"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ShortNameResolver.Resolve() error = %v, wantErr %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,346 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tlsconfig provides per-host TLS configurations, including private
+// CA bundles and client certificates for mutual TLS, for clients accessing
+// remote registries.
+//
+// Reference: https://docs.docker.com/engine/security/certificates/
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+const (
+	// extCACert is the file extension of CA certificates in a host directory.
+	extCACert = ".crt"
+	// extClientCert is the file extension of client certificates in a host
+	// directory.
+	extClientCert = ".cert"
+	// extClientKey is the file extension of client keys in a host directory.
+	extClientKey = ".key"
+)
+
+// ErrMissingKeyPair is returned when a client certificate or a client key in
+// a host directory does not have its counterpart.
+var ErrMissingKeyPair = errors.New("missing client certificate or key")
+
+// Store stores TLS configurations keyed by registry host.
+// A Store is safe for concurrent use.
+type Store struct {
+	// Base is the TLS configuration used for hosts without a specific
+	// configuration, and the template of the host-specific configurations
+	// loaded from directories.
+	// If nil, the default TLS configuration is used.
+	Base *tls.Config
+
+	lock    sync.RWMutex
+	configs map[string]*tls.Config
+}
+
+// NewStore creates a Store with the given base TLS configuration.
+func NewStore(base *tls.Config) *Store {
+	return &Store{
+		Base: base,
+	}
+}
+
+// NewStoreFromDir creates a Store and loads host-specific TLS configurations
+// from the given directory. See also Store.LoadDir.
+func NewStoreFromDir(dir string, base *tls.Config) (*Store, error) {
+	s := NewStore(base)
+	if err := s.LoadDir(dir); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Set sets the TLS configuration for the given host (i.e. host:port).
+// If host does not contain a port, the configuration applies to all ports of
+// the host unless a configuration for the host with the port is set.
+func (s *Store) Set(host string, config *tls.Config) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.configs == nil {
+		s.configs = make(map[string]*tls.Config)
+	}
+	s.configs[host] = config
+}
+
+// Get returns the TLS configuration for the given host (i.e. host:port).
+// The configuration for the host with port takes precedence over the one for
+// the host without port. If none is found, Base is returned.
+func (s *Store) Get(host string) *tls.Config {
+	if config, ok := s.lookup(host); ok {
+		return config
+	}
+	return s.Base
+}
+
+// lookup returns the host-specific TLS configuration for the given host.
+func (s *Store) lookup(host string) (*tls.Config, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if config, ok := s.configs[host]; ok {
+		return config, true
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		if config, ok := s.configs[hostname]; ok {
+			return config, true
+		}
+	}
+	return nil, false
+}
+
+// LoadDir loads host-specific TLS configurations from the given directory,
+// which follows the layout of "certs.d" directories:
+//
+//	<dir>/
+//	├── localhost:5000/
+//	│   ├── ca.crt
+//	│   ├── client.cert
+//	│   └── client.key
+//	└── registry.example.com/
+//	    └── ca.crt
+//
+// Each sub-directory is named after the host (i.e. host:port) and is loaded
+// by LoadHostDir. Existing configurations of the same hosts are replaced.
+func (s *Store) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		host := entry.Name()
+		config, err := LoadHostDir(filepath.Join(dir, host), s.Base)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS configuration for %s: %w", host, err)
+		}
+		s.Set(host, config)
+	}
+	return nil
+}
+
+// LoadHostDir loads a TLS configuration from a host directory, using base as
+// the template. If base is nil, the default TLS configuration is used.
+//
+//   - Files with the extension ".crt" are loaded as CA certificates, which are
+//     added to the root CAs of base, or to the system cert pool if base has
+//     no root CAs.
+//   - Files with the extension ".cert" are loaded as client certificates,
+//     each of which must be accompanied by a key file with the same name and
+//     the extension ".key".
+func LoadHostDir(dir string, base *tls.Config) (*tls.Config, error) {
+	var config *tls.Config
+	if base != nil {
+		config = base.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		switch ext := filepath.Ext(name); ext {
+		case extCACert:
+			if config.RootCAs == nil {
+				pool, err := x509.SystemCertPool()
+				if err != nil {
+					pool = x509.NewCertPool()
+				}
+				config.RootCAs = pool
+			} else if base != nil && config.RootCAs == base.RootCAs {
+				// avoid modifying the cert pool of base
+				config.RootCAs = base.RootCAs.Clone()
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if !config.RootCAs.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("%s: no valid CA certificate found", path)
+			}
+		case extClientCert:
+			keyPath := strings.TrimSuffix(path, ext) + extClientKey
+			if _, err := os.Stat(keyPath); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil, fmt.Errorf("%s: %w", path, ErrMissingKeyPair)
+				}
+				return nil, err
+			}
+			cert, err := tls.LoadX509KeyPair(path, keyPath)
+			if err != nil {
+				return nil, err
+			}
+			config.Certificates = append(config.Certificates, cert)
+		case extClientKey:
+			certPath := strings.TrimSuffix(path, ext) + extClientCert
+			if _, err := os.Stat(certPath); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil, fmt.Errorf("%s: %w", path, ErrMissingKeyPair)
+				}
+				return nil, err
+			}
+		}
+	}
+	return config, nil
+}
+
+// Transport is an HTTP transport which selects the TLS configuration by the
+// host of the request.
+type Transport struct {
+	// Store provides the TLS configurations.
+	Store *Store
+
+	// Base is the template of the underlying HTTP transports, which is cloned
+	// for each distinct TLS configuration.
+	// If nil, http.DefaultTransport is used as the template.
+	Base *http.Transport
+
+	lock       sync.Mutex
+	transports map[*tls.Config]*http.Transport
+	// hosts records the host-specific TLS configuration last used by each
+	// host, so that the transports of replaced configurations can be
+	// released. Hosts without host-specific configurations are not recorded.
+	hosts map[string]*tls.Config
+	// base records the default TLS configuration last used by the hosts
+	// without host-specific configurations.
+	base *tls.Config
+}
+
+// NewTransport creates an HTTP transport using the TLS configurations in the
+// store.
+func NewTransport(store *Store) *Transport {
+	return &Transport{
+		Store: store,
+	}
+}
+
+// RoundTrip executes a single HTTP transaction using the TLS configuration
+// of the request host.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.transport(req.URL.Host).RoundTrip(req)
+}
+
+// transport returns the underlying HTTP transport for the given host.
+// Transports are cached per TLS configuration so that connections are reused.
+// When the configuration of the host is replaced in the store, the transport
+// of the previous configuration is evicted and its idle connections are
+// closed, unless the configuration is still used by other hosts.
+func (t *Transport) transport(host string) *http.Transport {
+	var config *tls.Config
+	var specific bool
+	if t.Store != nil {
+		config, specific = t.Store.lookup(host)
+		if !specific {
+			config = t.Store.Base
+		}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	old, recorded := t.hosts[host]
+	if specific {
+		if t.hosts == nil {
+			t.hosts = make(map[string]*tls.Config)
+		}
+		t.hosts[host] = config
+	} else {
+		delete(t.hosts, host)
+		if config != t.base {
+			prevBase := t.base
+			t.base = config
+			t.evict(prevBase)
+		}
+	}
+	if recorded && old != config {
+		t.evict(old)
+	}
+	if tr, ok := t.transports[config]; ok {
+		return tr
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	tr := base.Clone()
+	if config != nil {
+		tr.TLSClientConfig = config.Clone()
+	}
+	if t.transports == nil {
+		t.transports = make(map[*tls.Config]*http.Transport)
+	}
+	t.transports[config] = tr
+	return tr
+}
+
+// evict removes the transport of the given TLS configuration and closes its
+// idle connections if the configuration is no longer used by any host.
+// The caller must hold t.lock.
+func (t *Transport) evict(config *tls.Config) {
+	if config == t.base {
+		return
+	}
+	for _, c := range t.hosts {
+		if c == config {
+			return
+		}
+	}
+	if tr, ok := t.transports[config]; ok {
+		delete(t.transports, config)
+		tr.CloseIdleConnections()
+	}
+}
+
+// CloseIdleConnections closes the idle connections of all the underlying
+// HTTP transports.
+func (t *Transport) CloseIdleConnections() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, tr := range t.transports {
+		tr.CloseIdleConnections()
+	}
+}
+
+// NewClient creates an HTTP client with the default retry policy using the
+// TLS configurations in the store. The returned client can be used as the
+// underlying client of auth.Client.
+func NewClient(store *Store) *http.Client {
+	return &http.Client{
+		Transport: retry.NewTransport(NewTransport(store)),
+	}
+}
@@ -0,0 +1,300 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and its private key in PEM.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert creates a certificate signed by parent, or a self-signed CA
+// certificate if parent is nil.
+func newTestCert(t *testing.T, parent *testCert, serial int64, ips ...net.IP) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "oras-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  ips,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestStore_Get(t *testing.T) {
+	base := &tls.Config{ServerName: "base"}
+	hostConfig := &tls.Config{ServerName: "host"}
+	portConfig := &tls.Config{ServerName: "port"}
+	s := NewStore(base)
+	s.Set("registry.example.com", hostConfig)
+	s.Set("registry.example.com:5000", portConfig)
+
+	tests := []struct {
+		host string
+		want *tls.Config
+	}{
+		{"registry.example.com", hostConfig},
+		{"registry.example.com:443", hostConfig},
+		{"registry.example.com:5000", portConfig},
+		{"example.com", base},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := s.Get(tt.host); got != tt.want {
+				t.Errorf("Store.Get() = %v, want %v", got.ServerName, tt.want.ServerName)
+			}
+		})
+	}
+}
+
+func TestLoadHostDir_MissingKeyPair(t *testing.T) {
+	ca := newTestCert(t, nil, 1)
+	client := newTestCert(t, ca, 2)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "client.cert"), client.certPEM)
+	if _, err := LoadHostDir(dir, nil); !errors.Is(err, ErrMissingKeyPair) {
+		t.Errorf("LoadHostDir() error = %v, wantErr %v", err, ErrMissingKeyPair)
+	}
+
+	dir = t.TempDir()
+	writeFile(t, filepath.Join(dir, "client.key"), client.keyPEM)
+	if _, err := LoadHostDir(dir, nil); !errors.Is(err, ErrMissingKeyPair) {
+		t.Errorf("LoadHostDir() error = %v, wantErr %v", err, ErrMissingKeyPair)
+	}
+}
+
+func TestLoadHostDir_InvalidCA(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ca.crt"), []byte("not a certificate"))
+	if _, err := LoadHostDir(dir, nil); err == nil {
+		t.Error("LoadHostDir() error = nil, wantErr true")
+	}
+}
+
+func TestLoadHostDir_BaseNotModified(t *testing.T) {
+	ca := newTestCert(t, nil, 1)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.certPEM)
+
+	pool := x509.NewCertPool()
+	base := &tls.Config{RootCAs: pool}
+	config, err := LoadHostDir(dir, base)
+	if err != nil {
+		t.Fatalf("LoadHostDir() error = %v", err)
+	}
+	if config.RootCAs == pool {
+		t.Error("LoadHostDir() modifies the root CAs of base")
+	}
+	if !pool.Equal(x509.NewCertPool()) {
+		t.Error("LoadHostDir() modifies the root CAs of base")
+	}
+}
+
+func TestClient_MutualTLS(t *testing.T) {
+	ca := newTestCert(t, nil, 1)
+	server := newTestCert(t, ca, 2, net.IPv4(127, 0, 0, 1))
+	client := newTestCert(t, ca, 3)
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load server certificate: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			t.Error("missing client certificate")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+	uri, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("invalid test http server: %v", err)
+	}
+
+	certsDir := t.TempDir()
+	hostDir := filepath.Join(certsDir, uri.Host)
+	writeFile(t, filepath.Join(hostDir, "ca.crt"), ca.certPEM)
+	writeFile(t, filepath.Join(hostDir, "client.cert"), client.certPEM)
+	writeFile(t, filepath.Join(hostDir, "client.key"), client.keyPEM)
+	// unrelated files should be ignored
+	writeFile(t, filepath.Join(hostDir, "README"), []byte("hello"))
+	writeFile(t, filepath.Join(certsDir, "README"), []byte("hello"))
+
+	// without the TLS configuration
+	if _, err := NewClient(NewStore(nil)).Get(ts.URL); err == nil {
+		t.Error("Client.Get() without TLS configuration error = nil, wantErr true")
+	}
+
+	// with the TLS configuration
+	store, err := NewStoreFromDir(certsDir, nil)
+	if err != nil {
+		t.Fatalf("NewStoreFromDir() error = %v", err)
+	}
+	resp, err := NewClient(store).Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Client.Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Client.Get() status = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestTransport_ConfigReplaced(t *testing.T) {
+	oldConfig := &tls.Config{ServerName: "old"}
+	newConfig := &tls.Config{ServerName: "new"}
+	s := NewStore(nil)
+	s.Set("registry.example.com", oldConfig)
+	s.Set("shared.example.com", oldConfig)
+	tr := NewTransport(s)
+
+	oldTransport := tr.transport("registry.example.com")
+	if got := tr.transport("shared.example.com"); got != oldTransport {
+		t.Errorf("Transport.transport() = %p, want %p", got, oldTransport)
+	}
+
+	// the old transport is kept while used by other hosts
+	s.Set("registry.example.com", newConfig)
+	newTransport := tr.transport("registry.example.com")
+	if newTransport == oldTransport {
+		t.Fatal("Transport.transport() returned the transport of the replaced configuration")
+	}
+	if got := newTransport.TLSClientConfig.ServerName; got != newConfig.ServerName {
+		t.Errorf("Transport.transport().TLSClientConfig.ServerName = %v, want %v", got, newConfig.ServerName)
+	}
+	if _, ok := tr.transports[oldConfig]; !ok {
+		t.Error("Transport.transports evicted the configuration in use")
+	}
+
+	// the old transport is evicted once unused
+	s.Set("shared.example.com", newConfig)
+	if got := tr.transport("shared.example.com"); got != newTransport {
+		t.Errorf("Transport.transport() = %p, want %p", got, newTransport)
+	}
+	if _, ok := tr.transports[oldConfig]; ok {
+		t.Error("Transport.transports kept the replaced configuration")
+	}
+	if got, want := len(tr.transports), 1; got != want {
+		t.Errorf("len(Transport.transports) = %v, want %v", got, want)
+	}
+}
+
+func TestTransport_HostsWithoutConfig(t *testing.T) {
+	base := &tls.Config{ServerName: "base"}
+	s := NewStore(base)
+	s.Set("registry.example.com", &tls.Config{ServerName: "specific"})
+	tr := NewTransport(s)
+
+	specificTransport := tr.transport("registry.example.com:5000")
+	for i := 0; i < 100; i++ {
+		if got := tr.transport(fmt.Sprintf("host%d.example.com", i)); got.TLSClientConfig.ServerName != base.ServerName {
+			t.Fatalf("Transport.transport().TLSClientConfig.ServerName = %v, want %v", got.TLSClientConfig.ServerName, base.ServerName)
+		}
+	}
+	// only the hosts with host-specific configurations are recorded
+	if got, want := len(tr.hosts), 1; got != want {
+		t.Errorf("len(Transport.hosts) = %v, want %v", got, want)
+	}
+	if got, want := len(tr.transports), 2; got != want {
+		t.Errorf("len(Transport.transports) = %v, want %v", got, want)
+	}
+	if specificTransport.TLSClientConfig.ServerName != "specific" {
+		t.Errorf("Transport.transport().TLSClientConfig.ServerName = %v, want %v", specificTransport.TLSClientConfig.ServerName, "specific")
+	}
+
+	// the transport of the replaced base configuration is evicted
+	s.Base = &tls.Config{ServerName: "new base"}
+	if got := tr.transport("other.example.com"); got.TLSClientConfig.ServerName != "new base" {
+		t.Errorf("Transport.transport().TLSClientConfig.ServerName = %v, want %v", got.TLSClientConfig.ServerName, "new base")
+	}
+	if _, ok := tr.transports[base]; ok {
+		t.Error("Transport.transports kept the replaced base configuration")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"oras.land/oras-go/v2/errdef"
+)
+
+// ShortNameConfig configures the resolution of short names, which are
+// references without a registry, such as "ubuntu:22.04" or "team/app".
+//
+// Reference: https://github.com/containers/image/blob/main/docs/containers-registries.conf.5.md
+type ShortNameConfig struct {
+	// SearchRegistries is the ordered list of registries used to qualify
+	// short names which are not aliased.
+	// It is equivalent to `unqualified-search-registries` in
+	// containers-registries.conf.
+	// Example: []string{"registry.example.com", "docker.io"}
+	SearchRegistries []string
+
+	// Aliases maps short names to fully qualified repositories, optionally
+	// with a default tag or digest.
+	// The keys are short names without tags or digests, and take precedence
+	// over SearchRegistries.
+	// It is equivalent to the `[aliases]` table in containers-registries.conf.
+	// Example: map[string]string{"fedora": "registry.fedoraproject.org/fedora"}
+	Aliases map[string]string
+}
+
+// IsShortName reports whether the name is a short name, that is, its first
+// component does not look like a registry.
+// A component looks like a registry if it contains a "." or a ":", or if it is
+// "localhost".
+func IsShortName(name string) bool {
+	registry, _, found := strings.Cut(name, "/")
+	return !found || (!strings.ContainsAny(registry, ".:") && registry != "localhost")
+}
+
+// Candidates expands name into the list of fully qualified references to be
+// tried in order.
+//   - If name is not a short name, it is parsed by ParseReference and returned
+//     as the only candidate.
+//   - If name, without the tag or the digest, has an alias, the alias is
+//     returned as the only candidate. The tag or the digest of name, if any,
+//     replaces the one of the alias.
+//   - Otherwise, name is qualified with each of SearchRegistries. Docker Hub
+//     candidates are normalized as ParseNormalizedReference does.
+func (c *ShortNameConfig) Candidates(name string) ([]Reference, error) {
+	if !IsShortName(name) {
+		ref, err := ParseReference(name)
+		if err != nil {
+			return nil, err
+		}
+		return []Reference{ref}, nil
+	}
+
+	repository := repositoryPart(name)
+	suffix := name[len(repository):]
+	if alias, ok := c.Aliases[repository]; ok {
+		ref, err := ParseReference(alias)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alias for %q: %w", repository, err)
+		}
+		if suffix != "" {
+			if ref, err = ParseReference(ref.Registry + "/" + ref.Repository + suffix); err != nil {
+				return nil, err
+			}
+		}
+		return []Reference{ref}, nil
+	}
+
+	if len(c.SearchRegistries) == 0 {
+		return nil, fmt.Errorf("%w: %q is a short name but no search registries are configured", errdef.ErrInvalidReference, name)
+	}
+	candidates := make([]Reference, 0, len(c.SearchRegistries))
+	for _, registry := range c.SearchRegistries {
+		parse := ParseReference
+		if registry == dockerHubRegistry || registry == dockerHubLegacyRegistry {
+			parse = ParseNormalizedReference
+		}
+		ref, err := parse(registry + "/" + name)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, ref)
+	}
+	return candidates, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright The ORAS Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"oras.land/oras-go/v2/errdef"
+)
+
+func TestIsShortName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"ubuntu", true},
+		{"ubuntu:22.04", true},
+		{"team/app", true},
+		{"localhost/app", false},
+		{"localhost:5000/app", false},
+		{"registry.example.com/app", false},
+		{"registry:5000/app", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsShortName(tt.name); got != tt.want {
+				t.Errorf("IsShortName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortNameConfig_Candidates(t *testing.T) {
+	config := &ShortNameConfig{
+		SearchRegistries: []string{"registry.example.com", "docker.io"},
+		Aliases: map[string]string{
+			"fedora":  "registry.fedoraproject.org/fedora",
+			"pinned":  "registry.example.com/team/pinned:v1",
+			"invalid": "invalid",
+		},
+	}
+	tests := []struct {
+		name    string
+		input   string
+		want    []Reference
+		wantErr bool
+	}{
+		{
+			name:  "fully qualified",
+			input: "ghcr.io/oras-project/oras:v1",
+			want: []Reference{
+				{Registry: "ghcr.io", Repository: "oras-project/oras", Reference: "v1"},
+			},
+		},
+		{
+			name:  "search registries",
+			input: "ubuntu:22.04",
+			want: []Reference{
+				{Registry: "registry.example.com", Repository: "ubuntu", Reference: "22.04"},
+				{Registry: "docker.io", Repository: "library/ubuntu", Reference: "22.04"},
+			},
+		},
+		{
+			name:  "search registries with namespace",
+			input: "team/app@" + ValidDigest,
+			want: []Reference{
+				{Registry: "registry.example.com", Repository: "team/app", Reference: ValidDigest},
+				{Registry: "docker.io", Repository: "team/app", Reference: ValidDigest},
+			},
+		},
+		{
+			name:  "alias",
+			input: "fedora",
+			want: []Reference{
+				{Registry: "registry.fedoraproject.org", Repository: "fedora"},
+			},
+		},
+		{
+			name:  "alias with tag",
+			input: "fedora:40",
+			want: []Reference{
+				{Registry: "registry.fedoraproject.org", Repository: "fedora", Reference: "40"},
+			},
+		},
+		{
+			name:  "alias with default tag",
+			input: "pinned",
+			want: []Reference{
+				{Registry: "registry.example.com", Repository: "team/pinned", Reference: "v1"},
+			},
+		},
+		{
+			name:  "alias with default tag overridden",
+			input: "pinned:v2",
+			want: []Reference{
+				{Registry: "registry.example.com", Repository: "team/pinned", Reference: "v2"},
+			},
+		},
+		{
+			name:  "alias with default tag overridden by digest",
+			input: "pinned@" + ValidDigest,
+			want: []Reference{
+				{Registry: "registry.example.com", Repository: "team/pinned", Reference: ValidDigest},
+			},
+		},
+		{
+			name:    "invalid alias",
+			input:   "invalid",
+			wantErr: true,
+		},
+		{
+			name:    "invalid short name",
+			input:   "Ubuntu",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := config.Candidates(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ShortNameConfig.Candidates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ShortNameConfig.Candidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortNameConfig_Candidates_NoSearchRegistries(t *testing.T) {
+	config := &ShortNameConfig{}
+	if _, err := config.Candidates("ubuntu"); !errors.Is(err, errdef.ErrInvalidReference) {
+		t.Errorf("ShortNameConfig.Candidates() error = %v, wantErr %v", err, errdef.ErrInvalidReference)
+	}
+}